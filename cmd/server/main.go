@@ -15,6 +15,7 @@ import (
 	"github.com/FlutterDizaster/ya-metrics/pkg/appinfoprinter"
 	configloader "github.com/FlutterDizaster/ya-metrics/pkg/config-loader"
 	"github.com/FlutterDizaster/ya-metrics/pkg/logger"
+	flag "github.com/spf13/pflag"
 )
 
 //nolint:gochecknoglobals // build info
@@ -56,6 +57,12 @@ func main() {
 		return
 	}
 
+	// Выполнение команды управления миграциями: server migrate up|down|status
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		migrate(settings, args[1:])
+		return
+	}
+
 	// Создание сервера
 	srv, err := server.New(settings)
 	if err != nil {
@@ -79,3 +86,16 @@ func main() {
 		return
 	}
 }
+
+// Функция выполнения команды управления миграциями.
+// При ошибке завершает процесс с ненулевым кодом, чтобы ее могли обнаружить скрипты развертывания.
+func migrate(settings server.Settings, args []string) {
+	command := server.MigrateUp
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if err := server.Migrate(context.Background(), settings, command, os.Stdout); err != nil {
+		slog.Error("Migrate error", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
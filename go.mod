@@ -1,12 +1,18 @@
 module github.com/FlutterDizaster/ya-metrics
 
-go 1.21.0
+go 1.22
 
 require (
 	github.com/go-resty/resty/v2 v2.11.0
+	github.com/jackc/pgerrcode v0.0.0-20240316143900-6e2875d9b438
+	github.com/klauspost/compress v1.18.0
 	github.com/mailru/easyjson v0.7.7
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/swaggo/swag v1.16.3
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/tools v0.23.0
+	google.golang.org/grpc v1.66.0
+	google.golang.org/protobuf v1.34.2
 	honnef.co/go/tools v0.4.7
 )
 
@@ -37,8 +43,6 @@ require (
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
-	google.golang.org/grpc v1.66.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
 
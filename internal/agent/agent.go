@@ -29,6 +29,13 @@ type Settings struct {
 	// Использование gRPC сервера вместо HTTP
 	UseGRPC bool `name:"grpc" short:"g" default:"false" usage:"use grpc" env:"USE_GRPC"`
 
+	// Отправка метрик через поток gRPC вместо отдельных запросов
+	GRPCStream bool `name:"grpc-stream" default:"false" usage:"use grpc stream" env:"GRPC_STREAM"`
+
+	// Максимальное количество неподтвержденных сервером пакетов потока gRPC
+	//nolint:lll // tags too long. idk how to fix that
+	GRPCStreamWindow int `name:"grpc-stream-window" default:"4" usage:"max unacknowledged grpc stream batches" env:"GRPC_STREAM_WINDOW"`
+
 	// Ключ для вычисления Hash суммы
 	HashKey string `name:"key" short:"k" default:"" usage:"hash key" env:"KEY"`
 
@@ -110,10 +117,15 @@ func setupSender(
 
 	if settings.UseGRPC {
 		senderSettings := grpcsender.Settings{
-			Addr:           settings.ServerAddr,
-			ReportInterval: time.Duration(settings.ReportInterval) * time.Second,
-			Buf:            buf,
-			RateLimit:      settings.RateLimit,
+			Addr:             settings.ServerAddr,
+			ReportInterval:   time.Duration(settings.ReportInterval) * time.Second,
+			Buf:              buf,
+			RateLimit:        settings.RateLimit,
+			Stream:           settings.GRPCStream,
+			StreamWindow:     settings.GRPCStreamWindow,
+			RetryInterval:    time.Duration(settings.RetryInterval) * time.Second,
+			RetryMaxWaitTime: time.Duration(settings.RetryMaxWaitTime) * time.Second,
+			HashKey:          settings.HashKey,
 		}
 		s = grpcsender.New(senderSettings)
 	} else {
@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"errors"
+	"log/slog"
 	"sync"
 	"sync/atomic"
 
@@ -36,6 +37,8 @@ func (b *Buffer) Close() {
 }
 
 // Метод добавления метрик в буфер.
+// Значения счетчиков и гистограмм суммируются с накопленными.
+// Гистограмма, которую нельзя объединить с накопленной, отбрасывается.
 func (b *Buffer) Put(metrics []view.Metric) error {
 	if b.closed.Load() {
 		return errBufferClosed
@@ -45,11 +48,11 @@ func (b *Buffer) Put(metrics []view.Metric) error {
 	defer b.cond.L.Unlock()
 
 	for i := range metrics {
-		id := metrics[i].ID
+		id := metrics[i].Key()
 		switch metrics[i].MType {
 		case view.KindCounter:
 			old, ok := b.metrics[id]
-			if !ok {
+			if !ok || old.Delta == nil || metrics[i].Delta == nil {
 				b.metrics[id] = metrics[i]
 				continue
 			}
@@ -58,6 +61,26 @@ func (b *Buffer) Put(metrics []view.Metric) error {
 			b.metrics[id] = metrics[i]
 		case view.KindGauge:
 			b.metrics[id] = metrics[i]
+		case view.KindHistogram:
+			old, ok := b.metrics[id]
+			if !ok || old.Histogram == nil {
+				b.metrics[id] = metrics[i]
+				continue
+			}
+			histogram, err := old.Histogram.Merge(metrics[i].Histogram)
+			if err != nil {
+				// Границы корзин изменились или гистограмма некорректна.
+				// Накопленное значение сохраняется, новое отбрасывается, как и на сервере.
+				slog.Warn(
+					"histogram dropped",
+					slog.String("metric", id),
+					slog.Uint64("dropped_count", histogramCount(metrics[i].Histogram)),
+					slog.Any("error", err),
+				)
+				continue
+			}
+			metrics[i].Histogram = histogram
+			b.metrics[id] = metrics[i]
 		}
 	}
 
@@ -66,6 +89,15 @@ func (b *Buffer) Put(metrics []view.Metric) error {
 	return nil
 }
 
+// Хелпер функция получения количества наблюдений гистограммы h.
+// Для nil возвращает 0.
+func histogramCount(h *view.Histogram) uint64 {
+	if h == nil {
+		return 0
+	}
+	return h.Count
+}
+
 // Метод вытягивания метрик из буфера.
 // После вытягивания буфер очищается.
 func (b *Buffer) Pull() ([]view.Metric, error) {
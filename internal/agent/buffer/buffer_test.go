@@ -15,6 +15,7 @@ func TestBuffer_Put(t *testing.T) {
 		values    []view.Metric
 		wantDelta int64
 		wantValue float64
+		wantHist  *view.Histogram
 		wantErr   bool
 	}
 
@@ -55,6 +56,135 @@ func TestBuffer_Put(t *testing.T) {
 			wantDelta: 99,
 			wantErr:   false,
 		},
+		{
+			name:     "histogram test",
+			testKind: view.KindHistogram,
+			values: []view.Metric{
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{1, 0, 1},
+						Count:   2,
+						Sum:     10.5,
+					},
+				},
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{0, 2, 0},
+						Count:   2,
+						Sum:     6,
+					},
+				},
+			},
+			wantHist: &view.Histogram{
+				Bounds:  []float64{1, 5},
+				Buckets: []uint64{1, 2, 1},
+				Count:   4,
+				Sum:     16.5,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "histogram without payload",
+			testKind: view.KindHistogram,
+			values: []view.Metric{
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{1, 0, 1},
+						Count:   2,
+						Sum:     10.5,
+					},
+				},
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+				},
+			},
+			// Гистограмма без значения отбрасывается, накопленное значение сохраняется
+			wantHist: &view.Histogram{
+				Bounds:  []float64{1, 5},
+				Buckets: []uint64{1, 0, 1},
+				Count:   2,
+				Sum:     10.5,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "histogram bounds mismatch",
+			testKind: view.KindHistogram,
+			values: []view.Metric{
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{1, 0, 1},
+						Count:   2,
+						Sum:     10.5,
+					},
+				},
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{2},
+						Buckets: []uint64{1, 1},
+						Count:   2,
+						Sum:     4,
+					},
+				},
+			},
+			// Гистограмма с другими границами корзин отбрасывается, накопленное значение сохраняется
+			wantHist: &view.Histogram{
+				Bounds:  []float64{1, 5},
+				Buckets: []uint64{1, 0, 1},
+				Count:   2,
+				Sum:     10.5,
+			},
+			wantErr: false,
+		},
+		{
+			name:     "histogram bucket count mismatch",
+			testKind: view.KindHistogram,
+			values: []view.Metric{
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{1, 0, 1},
+						Count:   2,
+						Sum:     10.5,
+					},
+				},
+				{
+					ID:    view.KindHistogram,
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1, 5},
+						Buckets: []uint64{2},
+						Count:   2,
+						Sum:     3,
+					},
+				},
+			},
+			// Некорректная гистограмма отбрасывается, накопленное значение сохраняется
+			wantHist: &view.Histogram{
+				Bounds:  []float64{1, 5},
+				Buckets: []uint64{1, 0, 1},
+				Count:   2,
+				Sum:     10.5,
+			},
+			wantErr: false,
+		},
 		{
 			name:     "error test",
 			testKind: "error",
@@ -85,6 +215,8 @@ func TestBuffer_Put(t *testing.T) {
 				assert.InDelta(t, tt.wantValue, *buffer.metrics[view.KindGauge].Value, 0.001)
 			} else if tt.testKind == view.KindCounter {
 				assert.Equal(t, tt.wantDelta, *buffer.metrics[view.KindCounter].Delta)
+			} else if tt.testKind == view.KindHistogram {
+				assert.Equal(t, tt.wantHist, buffer.metrics[view.KindHistogram].Histogram)
 			}
 			buffer.Close()
 		})
@@ -140,7 +272,7 @@ func TestBuffer_Pull(t *testing.T) {
 			}
 
 			require.NoError(t, err)
-			assert.Equal(t, tt.values, metrics)
+			assert.ElementsMatch(t, tt.values, metrics)
 
 			buffer.Close()
 		})
@@ -7,6 +7,7 @@ import (
 
 	"github.com/FlutterDizaster/ya-metrics/internal/agent/sender"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
 	"github.com/FlutterDizaster/ya-metrics/pkg/workerpool"
 	pb "github.com/FlutterDizaster/ya-metrics/proto"
 	"google.golang.org/grpc"
@@ -14,36 +15,59 @@ import (
 )
 
 type Settings struct {
-	Addr           string        // Адрес сервера агрегации метрик
-	ReportInterval time.Duration // Интервал между отправками метрик
-	Buf            sender.Buffer // Буфер метрик
-	RateLimit      int           // Максимальное кол-во запросов в секунду
+	Addr             string        // Адрес сервера агрегации метрик
+	ReportInterval   time.Duration // Интервал между отправками метрик
+	Buf              sender.Buffer // Буфер метрик
+	RateLimit        int           // Максимальное кол-во запросов в секунду
+	Stream           bool          // Отправка метрик через поток StreamMetrics вместо отдельных запросов
+	StreamWindow     int           // Максимальное кол-во неподтвержденных пакетов потока
+	RetryInterval    time.Duration // Интервал перед первым переподключением потока
+	RetryMaxWaitTime time.Duration // Максимальный интервал между переподключениями потока
+	HashKey          string        // Ключ HMAC запросов. Пустая строка - запросы не подписываются
 }
 
 type Sender struct {
-	endpointAddr   string
-	client         pb.MetricsServiceClient
-	reportInterval time.Duration
-	buf            sender.Buffer
-	wpool          workerpool.WorkerPool
+	endpointAddr     string
+	client           pb.MetricsServiceClient
+	stream           *metricsStream
+	reportInterval   time.Duration
+	buf              sender.Buffer
+	wpool            workerpool.WorkerPool
+	useStream        bool
+	streamWindow     int
+	retryInterval    time.Duration
+	retryMaxWaitTime time.Duration
+	hashKey          string
 }
 
 func New(settings Settings) *Sender {
 	return &Sender{
-		endpointAddr:   settings.Addr,
-		reportInterval: settings.ReportInterval,
-		buf:            settings.Buf,
-		wpool:          *workerpool.New(settings.RateLimit),
+		endpointAddr:     settings.Addr,
+		reportInterval:   settings.ReportInterval,
+		buf:              settings.Buf,
+		wpool:            *workerpool.New(settings.RateLimit),
+		useStream:        settings.Stream,
+		streamWindow:     settings.StreamWindow,
+		retryInterval:    settings.RetryInterval,
+		retryMaxWaitTime: settings.RetryMaxWaitTime,
+		hashKey:          settings.HashKey,
 	}
 }
 
 func (s *Sender) Start(ctx context.Context) error {
 	slog.Debug("Sender", slog.String("status", "start"))
 	// Создание подключения
-	conn, err := grpc.NewClient(
-		s.endpointAddr,
+	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	}
+	// Подпись запросов и сообщений потока
+	if s.hashKey != "" {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(validation.UnaryClientInterceptor([]byte(s.hashKey))),
+			grpc.WithStreamInterceptor(validation.StreamClientInterceptor([]byte(s.hashKey))),
+		)
+	}
+	conn, err := grpc.NewClient(s.endpointAddr, opts...)
 	if err != nil {
 		slog.Error("failed create connection", "error", err)
 		return err
@@ -53,6 +77,14 @@ func (s *Sender) Start(ctx context.Context) error {
 	client := pb.NewMetricsServiceClient(conn)
 	s.client = client
 
+	// Запуск потока отправки метрик. Поток живет до отправки последнего пакета при завершении работы
+	streamCtx, stopStream := context.WithCancel(context.Background())
+	defer stopStream()
+	if s.useStream {
+		s.stream = newMetricsStream(client, s.streamWindow, s.retryInterval, s.retryMaxWaitTime)
+		go s.stream.run(streamCtx)
+	}
+
 	ticker := time.NewTicker(s.reportInterval)
 	slog.Info("Sender started", "report interval", s.reportInterval)
 
@@ -67,6 +99,11 @@ func (s *Sender) Start(ctx context.Context) error {
 			lastCtx, lastCancleCtx := context.WithTimeout(context.Background(), 3*time.Second)
 			defer lastCancleCtx()
 			s.send(lastCtx)
+			if s.stream != nil {
+				if err = s.stream.flush(lastCtx); err != nil {
+					slog.Error("Sender", "error", err)
+				}
+			}
 			s.wpool.Close()
 			slog.Debug("Sender", slog.String("status", "stop"))
 			return nil
@@ -78,6 +115,11 @@ func (s *Sender) Start(ctx context.Context) error {
 
 func (s *Sender) send(ctx context.Context) {
 	slog.Debug("Sender", slog.String("status", "sending..."))
+	if s.stream != nil {
+		s.sendStream(ctx)
+		return
+	}
+
 	// ПОлучение метрик из буфера агента
 	metrics, err := s.buf.Pull()
 	if err != nil {
@@ -98,3 +140,27 @@ func (s *Sender) send(ctx context.Context) {
 		slog.Error("Sender", "error", err)
 	}
 }
+
+// Метод отправки метрик через поток StreamMetrics.
+// Место в окне потока занимается до получения метрик из буфера,
+// поэтому при закрытии контекста ctx метрики остаются в буфере.
+func (s *Sender) sendStream(ctx context.Context) {
+	if err := s.stream.acquire(ctx); err != nil {
+		slog.Error("Sender", "error", err)
+		return
+	}
+
+	metrics, err := s.buf.Pull()
+	if err != nil {
+		s.stream.release()
+		slog.Error("Sender", "error", err)
+		return
+	}
+
+	pbMetrics := view.MarshalGRPCMetrics(metrics)
+	if len(pbMetrics) == 0 {
+		s.stream.release()
+		return
+	}
+	s.stream.send(pbMetrics)
+}
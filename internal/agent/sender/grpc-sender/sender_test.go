@@ -0,0 +1,156 @@
+package grpcsender
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/memory"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc/interceptors"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Хелпер для создания хранилища метрик в памяти.
+func newMemoryStorage(t *testing.T) *memory.MetricStorage {
+	t.Helper()
+
+	storage, err := memory.New(&memory.Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+	})
+	require.NoError(t, err)
+	return storage
+}
+
+// Хелпер для запуска gRPC сервера с хранилищем storage и интерцепторами intrcpts.
+// Возвращает адрес сервера.
+func startServer(t *testing.T, storage rpc.MetricsStorage, intrcpts ...interceptors.Interceptor) string {
+	t.Helper()
+
+	// Свободный порт для сервера
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	service := rpc.New(rpc.Settings{
+		Storage:      storage,
+		Addr:         addr,
+		Interceptors: intrcpts,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- service.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return addr
+}
+
+func TestSender_HashKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    bool
+		clientKey string
+		wantSaved bool
+	}{
+		{name: "unary", stream: false, clientKey: "secret", wantSaved: true},
+		{name: "stream", stream: true, clientKey: "secret", wantSaved: true},
+		{name: "unary wrong key", stream: false, clientKey: "wrong", wantSaved: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage(t)
+			addr := startServer(t, storage, &interceptors.HashInterceptor{Key: []byte("secret")})
+
+			s := New(Settings{
+				Addr:           addr,
+				ReportInterval: 10 * time.Millisecond,
+				Buf:            &countingBuffer{},
+				RateLimit:      1,
+				Stream:         tt.stream,
+				RetryInterval:  10 * time.Millisecond,
+				HashKey:        tt.clientKey,
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- s.Start(ctx)
+			}()
+
+			saved := func() bool {
+				_, err := storage.GetMetric(context.Background(), view.KindGauge, "cpu", nil)
+				return err == nil
+			}
+			if tt.wantSaved {
+				assert.Eventually(t, saved, 5*time.Second, 10*time.Millisecond)
+			} else {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			cancel()
+			require.NoError(t, <-done)
+			assert.Equal(t, tt.wantSaved, saved())
+		})
+	}
+}
+
+// Хранилище, первая запись в которое завершается временной ошибкой.
+type unavailableOnceStorage struct {
+	*memory.MetricStorage
+
+	mu     sync.Mutex
+	failed bool
+}
+
+func (s *unavailableOnceStorage) AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	s.mu.Lock()
+	failed := s.failed
+	s.failed = true
+	s.mu.Unlock()
+	if !failed {
+		return nil, repository.ErrUnavailable
+	}
+	return s.MetricStorage.AddMetrics(ctx, metrics...)
+}
+
+func TestMetricsStream_RetryUnavailable(t *testing.T) {
+	storage := &unavailableOnceStorage{MetricStorage: newMemoryStorage(t)}
+	addr := startServer(t, storage)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ms := newMetricsStream(pb.NewMetricsServiceClient(conn), 2, 10*time.Millisecond, 50*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ms.run(ctx)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer sendCancel()
+	for _, delta := range []int64{1, 2} {
+		require.NoError(t, ms.acquire(sendCtx))
+		ms.send([]*pb.Metric{{Id: "requests", Kind: view.KindCounter, Delta: delta}})
+	}
+	require.NoError(t, ms.flush(sendCtx))
+
+	// Пакет с временной ошибкой записи отправляется повторно и записывается один раз
+	metric, err := storage.GetMetric(context.Background(), view.KindCounter, "requests", nil)
+	require.NoError(t, err)
+	require.NotNil(t, metric.Delta)
+	assert.Equal(t, int64(3), *metric.Delta)
+}
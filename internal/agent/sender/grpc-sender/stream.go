@@ -0,0 +1,239 @@
+package grpcsender
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"google.golang.org/grpc/codes"
+)
+
+// Кол-во неподтвержденных пакетов потока по умолчанию.
+const defaultStreamWindow = 4
+
+// Интервал перед первым переподключением потока по умолчанию.
+const defaultReconnectWait = time.Second
+
+// Хелпер структура для отправки пакетов метрик через поток StreamMetrics.
+// Пакеты хранятся до получения подтверждения от сервера. Если поток обрывается,
+// он открывается заново с экспоненциально растущим интервалом, и неподтвержденные
+// пакеты отправляются повторно в исходном порядке.
+// Кол-во неподтвержденных пакетов ограничено размером окна: место в окне занимается
+// до подготовки пакета и при заполненном окне ожидает подтверждения одного из предыдущих.
+// Пакеты передаются с идентификатором клиента, поэтому сервер не записывает повторно
+// пакет, подтверждение которого потеряно при обрыве потока.
+type metricsStream struct {
+	client           pb.MetricsServiceClient
+	clientID         string
+	window           chan struct{}
+	acked            chan struct{}
+	reconnectWait    time.Duration
+	reconnectMaxWait time.Duration
+
+	mu      sync.Mutex
+	stream  pb.MetricsService_StreamMetricsClient
+	seq     uint64
+	pending map[uint64]*pb.StreamMetricsRequest
+}
+
+// Функция создания потока отправки метрик.
+func newMetricsStream(
+	client pb.MetricsServiceClient,
+	window int,
+	reconnectWait time.Duration,
+	reconnectMaxWait time.Duration,
+) *metricsStream {
+	if window <= 0 {
+		window = defaultStreamWindow
+	}
+	if reconnectWait <= 0 {
+		reconnectWait = defaultReconnectWait
+	}
+	return &metricsStream{
+		client:           client,
+		clientID:         newClientID(),
+		window:           make(chan struct{}, window),
+		acked:            make(chan struct{}, 1),
+		reconnectWait:    reconnectWait,
+		reconnectMaxWait: max(reconnectWait, reconnectMaxWait),
+		pending:          make(map[uint64]*pb.StreamMetricsRequest),
+	}
+}
+
+// Метод поддержания открытого потока.
+// Блокирует поток исполнения до закрытия контекста ctx.
+func (ms *metricsStream) run(ctx context.Context) {
+	wait := ms.reconnectWait
+	for {
+		acked, err := ms.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Интервал переподключения сбрасывается, если поток успел подтвердить хотя бы один пакет
+		if acked {
+			wait = ms.reconnectWait
+		}
+		slog.Error("metrics stream broken", slog.String("error", err.Error()), slog.Duration("reconnect in", wait))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, ms.reconnectMaxWait)
+	}
+}
+
+// Метод открытия потока и чтения подтверждений до его обрыва.
+// Возвращает признак получения хотя бы одного подтверждения и ошибку обрыва потока.
+func (ms *metricsStream) serve(ctx context.Context) (bool, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := ms.client.StreamMetrics(streamCtx)
+	if err != nil {
+		return false, err
+	}
+
+	// Повторная отправка неподтвержденных пакетов в порядке номеров
+	ms.mu.Lock()
+	seqs := make([]uint64, 0, len(ms.pending))
+	for seq := range ms.pending {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	for _, seq := range seqs {
+		if err = stream.Send(ms.pending[seq]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		ms.stream = stream
+	}
+	ms.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	var acked bool
+	for {
+		ack, recvErr := stream.Recv()
+		if recvErr != nil {
+			ms.mu.Lock()
+			ms.stream = nil
+			ms.mu.Unlock()
+			return acked, recvErr
+		}
+		acked = true
+		ms.ack(ack)
+	}
+}
+
+// Метод обработки подтверждения пакета.
+// Пакет с временной ошибкой записи (Unavailable, DeadlineExceeded) остается неподтвержденным
+// и отправляется повторно после переподключения: сервер закрывает поток после такой ошибки.
+// Пакет, отклоненный сервером с другой ошибкой, не отправляется повторно.
+func (ms *metricsStream) ack(ack *pb.StreamMetricsResponse) {
+	code := codes.Code(ack.GetCode())
+	if code == codes.Unavailable || code == codes.DeadlineExceeded {
+		slog.Warn(
+			"metrics batch failed, will be resent",
+			slog.Uint64("seq", ack.GetSeq()),
+			slog.String("code", code.String()),
+			slog.String("error", ack.GetError()),
+		)
+		return
+	}
+	if code != codes.OK {
+		slog.Error(
+			"metrics batch rejected",
+			slog.Uint64("seq", ack.GetSeq()),
+			slog.String("code", code.String()),
+			slog.String("error", ack.GetError()),
+		)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.pending[ack.GetSeq()]; ok {
+		delete(ms.pending, ack.GetSeq())
+		<-ms.window
+		select {
+		case ms.acked <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Метод занятия места в окне неподтвержденных пакетов.
+// Ожидает освобождения окна или закрытия контекста ctx.
+// Занятое место освобождается подтверждением пакета, отправленного методом send,
+// или методом release, если пакет не был отправлен.
+func (ms *metricsStream) acquire(ctx context.Context) error {
+	select {
+	case ms.window <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Метод освобождения места в окне, занятого методом acquire без отправки пакета.
+func (ms *metricsStream) release() {
+	<-ms.window
+}
+
+// Метод отправки пакета метрик.
+// Перед вызовом необходимо занять место в окне методом acquire.
+// Если поток оборван, пакет будет отправлен после его восстановления.
+func (ms *metricsStream) send(metrics []*pb.Metric) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.seq++
+	req := &pb.StreamMetricsRequest{Seq: ms.seq, Metrics: metrics, ClientId: ms.clientID}
+	ms.pending[req.GetSeq()] = req
+
+	if ms.stream == nil {
+		return
+	}
+	// Ошибка отправки обрывает поток, пакет будет отправлен повторно
+	if err := ms.stream.Send(req); err != nil {
+		slog.Error("metrics stream send error", slog.String("error", err.Error()))
+	}
+}
+
+// Метод ожидания подтверждения всех отправленных пакетов.
+// Завершается сразу, если неподтвержденных пакетов нет. Прерывается при закрытии контекста ctx.
+func (ms *metricsStream) flush(ctx context.Context) error {
+	for {
+		ms.mu.Lock()
+		pending := len(ms.pending)
+		ms.mu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ms.acked:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Функция генерации случайного идентификатора клиента потока.
+func newClientID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
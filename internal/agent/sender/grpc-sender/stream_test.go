@@ -0,0 +1,160 @@
+package grpcsender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Сервер, обрывающий первый поток после получения первого пакета без подтверждения.
+type flakyServer struct {
+	pb.UnimplementedMetricsServiceServer
+
+	mu        sync.Mutex
+	streams   int
+	received  []uint64
+	clientIDs map[string]struct{}
+}
+
+func (s *flakyServer) StreamMetrics(stream pb.MetricsService_StreamMetricsServer) error {
+	s.mu.Lock()
+	s.streams++
+	first := s.streams == 1
+	s.mu.Unlock()
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.received = append(s.received, req.GetSeq())
+		if s.clientIDs == nil {
+			s.clientIDs = make(map[string]struct{})
+		}
+		s.clientIDs[req.GetClientId()] = struct{}{}
+		s.mu.Unlock()
+
+		if first {
+			return errors.New("connection lost")
+		}
+		if err = stream.Send(&pb.StreamMetricsResponse{Seq: req.GetSeq()}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestMetricsStream_Reconnect(t *testing.T) {
+	server := &flakyServer{}
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	pb.RegisterMetricsServiceServer(srv, server)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ms := newMetricsStream(pb.NewMetricsServiceClient(conn), 2, 10*time.Millisecond, 50*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ms.run(ctx)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer sendCancel()
+	metrics := []*pb.Metric{{Id: "cpu", Kind: "gauge", Value: 1}}
+	for range 4 {
+		require.NoError(t, ms.acquire(sendCtx))
+		ms.send(metrics)
+	}
+	require.NoError(t, ms.flush(sendCtx))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	// Пакет, оставшийся без подтверждения в оборванном потоке, отправляется повторно
+	assert.GreaterOrEqual(t, server.streams, 2)
+	assert.Equal(t, uint64(1), server.received[0])
+	assert.Subset(t, server.received[1:], []uint64{1, 2, 3, 4})
+
+	// Все пакеты передаются с одним идентификатором клиента для отбрасывания повторов сервером
+	require.Len(t, server.clientIDs, 1)
+	assert.NotContains(t, server.clientIDs, "")
+}
+
+func TestMetricsStream_Flush(t *testing.T) {
+	ms := newMetricsStream(nil, 2, 0, 0)
+
+	// Без неподтвержденных пакетов ожидание завершается сразу
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, ms.flush(ctx))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Неподтвержденный пакет ожидается до подтверждения или закрытия контекста
+	require.NoError(t, ms.acquire(ctx))
+	ms.send([]*pb.Metric{{Id: "cpu", Kind: "gauge", Value: 1}})
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shortCancel()
+	require.ErrorIs(t, ms.flush(shortCtx), context.DeadlineExceeded)
+
+	go ms.ack(&pb.StreamMetricsResponse{Seq: 1})
+	require.NoError(t, ms.flush(ctx))
+}
+
+// Буфер метрик, считающий вызовы Pull.
+type countingBuffer struct {
+	pulls int
+}
+
+func (b *countingBuffer) Pull() ([]view.Metric, error) {
+	b.pulls++
+	value := 1.0
+	return []view.Metric{{ID: "cpu", MType: view.KindGauge, Value: &value}}, nil
+}
+
+func TestSender_sendStream(t *testing.T) {
+	buf := &countingBuffer{}
+	s := New(Settings{Buf: buf, RateLimit: 1})
+	s.stream = newMetricsStream(nil, 1, 0, 0)
+
+	s.send(context.Background())
+	assert.Equal(t, 1, buf.pulls)
+
+	// При заполненном окне и закрытом контексте метрики остаются в буфере
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.send(ctx)
+	assert.Equal(t, 1, buf.pulls)
+
+	s.stream.ack(&pb.StreamMetricsResponse{Seq: 1})
+	s.send(context.Background())
+	assert.Equal(t, 2, buf.pulls)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/FlutterDizaster/ya-metrics/internal/server/api/middleware"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
 	"github.com/go-chi/chi/v5"
 	chimiddle "github.com/go-chi/chi/v5/middleware"
@@ -15,27 +16,41 @@ import (
 )
 
 // Интерфейс взаимодействия с репозиторием метрик.
+// Все методы принимают контекст запроса, отмена которого прерывает операцию с репозиторием.
 type MetricsStorage interface {
-	AddMetrics(...view.Metric) ([]view.Metric, error)
-	GetMetric(kind string, name string) (view.Metric, error)
-	ReadAllMetrics() ([]view.Metric, error)
-	Ping() error
+	AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error)
+	GetMetric(ctx context.Context, kind string, name string, labels view.Labels) (view.Metric, error)
+	ReadAllMetrics(ctx context.Context) ([]view.Metric, error)
+	ReadHistory(ctx context.Context, query view.HistoryQuery) (view.Samples, error)
+	ListMetrics(ctx context.Context, query view.ListQuery) (view.MetricsPage, error)
+	DeleteMetrics(ctx context.Context, metrics ...view.Metric) error
+	ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error)
+	Ping(ctx context.Context) error
 }
 
 // Структура Settings хранит параметры необходимые для создания экземпляра Router.
 // Storage принимает репозиторий реалищующий интерфейс MetricsStorage.
 // Middlewares принимает слайс Middleware функций соответствующих сигнатуре func(http.Handler) http.Handler.
 // Middlewares может иметь значение nil.
+// Broker принимает шину обновлений метрик. Если Broker равен nil, поток обновлений метрик недоступен.
+// URIValidator принимает Middleware проверки подписи запросов удаления метрики и сброса счетчика,
+// которые не имеют тела. URIValidator может иметь значение nil.
+// BodyValidator принимает Middleware обязательной проверки подписи тела запроса удаления множества метрик.
+// BodyValidator может иметь значение nil.
 type Settings struct {
-	Storage     MetricsStorage
-	Middlewares []middleware.Middleware
-	Addr        string
+	Storage       MetricsStorage
+	Broker        *broker.Broker
+	Middlewares   []middleware.Middleware
+	URIValidator  middleware.Middleware
+	BodyValidator middleware.Middleware
+	Addr          string
 }
 
 // API используется для обработки запросов к серверу.
 // Для создания экземпляра необходимо испольщовать функцию New(*Settings) *API.
 type API struct {
 	storage MetricsStorage
+	broker  *broker.Broker
 	server  *http.Server
 }
 
@@ -46,12 +61,25 @@ func New(as *Settings) *API {
 	// создание экземпляра API
 	api := &API{
 		storage: as.Storage,
+		broker:  as.Broker,
 	}
 
 	r := chi.NewRouter()
 
 	// r.Use(as.Middlewares...)
 
+	// Middleware запросов без тела, изменяющих метрики
+	var signed []func(http.Handler) http.Handler
+	if as.URIValidator != nil {
+		signed = append(signed, as.URIValidator.Handle)
+	}
+
+	// Middleware запросов с телом, удаляющих метрики
+	var signedBody []func(http.Handler) http.Handler
+	if as.BodyValidator != nil {
+		signedBody = append(signedBody, as.BodyValidator.Handle)
+	}
+
 	// настройка роутинга
 	// Application routes
 	r.Group(func(r chi.Router) {
@@ -62,7 +90,10 @@ func New(as *Settings) *API {
 
 		r.Get("/", api.getAllHandler)
 		r.Get("/ping", api.pingHandler)
+		r.Get("/metrics", api.prometheusHandler)
 		r.Post("/updates/", api.updateBatchHandler)
+		r.With(signedBody...).Post("/delete/", api.deleteBatchHandler)
+		r.With(signed...).Post("/reset/{name}", api.resetCounterHandler)
 		r.Route("/update", func(rr chi.Router) {
 			rr.Post("/", api.updateJSONHandler)
 			rr.Post("/{kind}/{name}/{value}", api.updateHandler)
@@ -70,7 +101,11 @@ func New(as *Settings) *API {
 		r.Route("/value", func(rr chi.Router) {
 			rr.Post("/", api.getJSONMetricHandler)
 			rr.Get("/{kind}/{name}", api.getMetricHandler)
+			rr.With(signed...).Delete("/{kind}/{name}", api.deleteMetricHandler)
 		})
+		r.Get("/history/{kind}/{name}", api.historyHandler)
+		r.Get("/list", api.listHandler)
+		r.Get("/watch", api.watchHandler)
 	})
 
 	// Development Routes
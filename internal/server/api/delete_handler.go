@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler для удаления метрики по её типу, имени и меткам.
+// Метки передаются в query параметрах запроса.
+//
+// Swagger описание:
+// @Summary Delete metric
+// @Description Delete metric and its history
+// @Tags metrics
+// @Param kind path string true "Metric kind"
+// @Param name path string true "Metric name"
+// @Param HashSHA256 header string false "HMAC SHA256 of 'DELETE <request URI>'. Required when the server key is set"
+// @Success 200
+// @Failure 400 {string} string "Bad request or invalid hash"
+// @Failure 404 {string} string "Metric not found"
+// @Failure 500 {string} string "Error"
+// @Router /value/{kind}/{name} [delete]
+// Конец Swagger описания.
+func (api *API) deleteMetricHandler(w http.ResponseWriter, req *http.Request) {
+	labels, err := labelsFromQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metric := view.Metric{
+		ID:     chi.URLParam(req, "name"),
+		MType:  chi.URLParam(req, "kind"),
+		Labels: labels,
+	}
+
+	// удаление метрики из репозитория
+	if err := api.storage.DeleteMetrics(req.Context(), metric); err != nil {
+		storageError(w, err.Error(), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteBatchHandler обрабатывает POST-запросы на удаление множества метрик из репозитория.
+// Метрики удаляются только все вместе: если хотя бы одна не найдена, не удаляется ни одна.
+//
+// Swagger описание:
+// @Summary Delete metrics
+// @Description Delete metrics and their history. Metric values are ignored
+// @Tags metrics
+// @Param metrics body []view.Metric true "Metrics"
+// @Param HashSHA256 header string false "HMAC SHA256 of the request body. Required when the server key is set"
+// @Success 200
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Metric not found"
+// @Failure 500 {string} string "Error"
+// @Router /delete/ [post]
+// Конец Swagger описания.
+func (api *API) deleteBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var metrics view.Metrics
+	var buf bytes.Buffer
+
+	// Чтение тела запроса
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		slog.Error("Reading error", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unmarshal
+	if err = metrics.UnmarshalJSON(buf.Bytes()); err != nil {
+		slog.Error("UnmarshalJSON error", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Удаление метрик из репозитория
+	if err = api.storage.DeleteMetrics(r.Context(), metrics...); err != nil {
+		slog.Error("DeleteMetrics error", slog.String("error", err.Error()))
+		storageError(w, err.Error(), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handler для сброса значения счетчика в ноль.
+// Метки передаются в query параметрах запроса.
+//
+// Swagger описание:
+// @Summary Reset counter
+// @Description Reset counter value to zero
+// @Tags metrics
+// @Produce json
+// @Param name path string true "Counter name"
+// @Param HashSHA256 header string false "HMAC SHA256 of 'POST <request URI>'. Required when the server key is set"
+// @Success 200 {object} view.Metric
+// @Failure 400 {string} string "Bad request or invalid hash"
+// @Failure 404 {string} string "Counter not found"
+// @Failure 500 {string} string "Error"
+// @Router /reset/{name} [post]
+// Конец Swagger описания.
+func (api *API) resetCounterHandler(w http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+	labels, err := labelsFromQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// сброс счетчика в репозитории
+	metric, err := api.storage.ResetCounter(req.Context(), name, labels)
+	if err != nil {
+		storageError(w, err.Error(), err)
+		return
+	}
+
+	// Marshal ответа
+	resp, err := metric.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// записываем ответ
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(resp); err != nil {
+		slog.Error("writing response error", "message", err)
+		http.Error(w, fmt.Sprintf("write metric error: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
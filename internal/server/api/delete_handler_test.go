@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api/middleware"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []view.Metric {
+	return []view.Metric{
+		{
+			ID:    "cpu",
+			MType: view.KindGauge,
+			Value: func(i float64) *float64 { return &i }(1),
+		},
+		{
+			ID:     "cpu",
+			MType:  view.KindGauge,
+			Labels: view.Labels{"host": "web1"},
+			Value:  func(i float64) *float64 { return &i }(2),
+		},
+		{
+			ID:    "requests",
+			MType: view.KindCounter,
+			Delta: func(i int64) *int64 { return &i }(10),
+		},
+	}
+}
+
+func TestRouter_deleteMetricHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		reqURL  string
+		code    int
+		remains int
+	}{
+		{
+			name:    "simple test",
+			reqURL:  "/value/gauge/cpu",
+			code:    200,
+			remains: 2,
+		},
+		{
+			name:    "labels test",
+			reqURL:  "/value/gauge/cpu?host=web1",
+			code:    200,
+			remains: 2,
+		},
+		{
+			name:    "wrong kind test",
+			reqURL:  "/value/counter/cpu",
+			code:    404,
+			remains: 3,
+		},
+		{
+			name:    "not found test",
+			reqURL:  "/value/gauge/memory",
+			code:    404,
+			remains: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &MockMetricsStorage{content: testMetrics()}
+			api := New(&Settings{Storage: storage})
+
+			r := chi.NewRouter()
+			r.Delete("/value/{kind}/{name}", api.deleteMetricHandler)
+
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			resp, err := resty.New().R().Delete(fmt.Sprintf("%s%s", server.URL, tt.reqURL))
+
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.code, resp.StatusCode())
+			assert.Len(t, storage.content, tt.remains)
+		})
+	}
+}
+
+func TestAPI_deleteBatchHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		code    int
+		remains int
+	}{
+		{
+			name:    "simple test",
+			body:    `[{"id":"cpu","type":"gauge"},{"id":"requests","type":"counter"}]`,
+			code:    200,
+			remains: 1,
+		},
+		{
+			name:    "partial not found test",
+			body:    `[{"id":"cpu","type":"gauge"},{"id":"memory","type":"gauge"}]`,
+			code:    404,
+			remains: 3,
+		},
+		{
+			name:    "bad body test",
+			body:    `{"id":`,
+			code:    400,
+			remains: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &MockMetricsStorage{content: testMetrics()}
+			api := New(&Settings{Storage: storage})
+
+			server := httptest.NewServer(http.HandlerFunc(api.deleteBatchHandler))
+			defer server.Close()
+
+			resp, err := resty.New().R().SetBody(tt.body).Post(server.URL)
+
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.code, resp.StatusCode())
+			assert.Len(t, storage.content, tt.remains)
+		})
+	}
+}
+
+func TestAPI_resetCounterHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		reqURL string
+		code   int
+	}{
+		{
+			name:   "simple test",
+			reqURL: "/reset/requests",
+			code:   200,
+		},
+		{
+			name:   "gauge test",
+			reqURL: "/reset/cpu",
+			code:   404,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &MockMetricsStorage{content: testMetrics()}
+			api := New(&Settings{Storage: storage})
+
+			r := chi.NewRouter()
+			r.Post("/reset/{name}", api.resetCounterHandler)
+
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			resp, err := resty.New().R().Post(fmt.Sprintf("%s%s", server.URL, tt.reqURL))
+
+			require.NoError(t, err, "error making http request")
+			require.Equal(t, tt.code, resp.StatusCode())
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var metric view.Metric
+			require.NoError(t, metric.UnmarshalJSON(resp.Body()))
+			require.NotNil(t, metric.Delta)
+			assert.Equal(t, int64(0), *metric.Delta)
+		})
+	}
+}
+
+func TestAPI_signedRoutes(t *testing.T) {
+	key := []byte("secret")
+	sign := func(method, uri string) string {
+		return hex.EncodeToString(validation.CalculateRequestHMAC(method, uri, key))
+	}
+	signBody := func(body string) string {
+		return hex.EncodeToString(validation.CalculateHashSHA256([]byte(body), key))
+	}
+	batch := `[{"id":"cpu","type":"gauge"}]`
+
+	tests := []struct {
+		name   string
+		method string
+		reqURL string
+		body   string
+		hash   string
+		code   int
+	}{
+		{
+			name:   "delete signed",
+			method: http.MethodDelete,
+			reqURL: "/value/gauge/cpu?host=web1",
+			hash:   sign(http.MethodDelete, "/value/gauge/cpu?host=web1"),
+			code:   http.StatusOK,
+		},
+		{
+			name:   "delete without hash",
+			method: http.MethodDelete,
+			reqURL: "/value/gauge/cpu",
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "delete with hash of other labels",
+			method: http.MethodDelete,
+			reqURL: "/value/gauge/cpu?host=web1",
+			hash:   sign(http.MethodDelete, "/value/gauge/cpu"),
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "reset signed",
+			method: http.MethodPost,
+			reqURL: "/reset/requests",
+			hash:   sign(http.MethodPost, "/reset/requests"),
+			code:   http.StatusOK,
+		},
+		{
+			name:   "reset with hash of other method",
+			method: http.MethodPost,
+			reqURL: "/reset/requests",
+			hash:   sign(http.MethodDelete, "/reset/requests"),
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "batch delete signed",
+			method: http.MethodPost,
+			reqURL: "/delete/",
+			body:   batch,
+			hash:   signBody(batch),
+			code:   http.StatusOK,
+		},
+		{
+			name:   "batch delete without hash",
+			method: http.MethodPost,
+			reqURL: "/delete/",
+			body:   batch,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "batch delete with hash of other body",
+			method: http.MethodPost,
+			reqURL: "/delete/",
+			body:   batch,
+			hash:   signBody(`[]`),
+			code:   http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := New(&Settings{
+				Storage:       &MockMetricsStorage{content: testMetrics()},
+				URIValidator:  &middleware.URIValidator{Key: key},
+				BodyValidator: &middleware.Validator{Key: key, Strict: true},
+			})
+
+			server := httptest.NewServer(api.server.Handler)
+			defer server.Close()
+
+			req := resty.New().R()
+			if tt.hash != "" {
+				req.SetHeader("HashSHA256", tt.hash)
+			}
+			if tt.body != "" {
+				req.SetBody(tt.body)
+			}
+			resp, err := req.Execute(tt.method, server.URL+tt.reqURL)
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.code, resp.StatusCode())
+		})
+	}
+}
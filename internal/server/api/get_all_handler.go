@@ -47,13 +47,13 @@ func (api *API) getAllHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// получение всех метрик из репозитория
-	metrics, err := api.storage.ReadAllMetrics()
+	metrics, err := api.storage.ReadAllMetrics(r.Context())
 	if err != nil {
-		http.Error(w, "Error whlie getting metrics from repository", http.StatusInternalServerError)
+		storageError(w, "Error whlie getting metrics from repository", err)
 		return
 	}
 	sort.Slice(metrics, func(i, j int) bool {
-		return metrics[i].ID < metrics[j].ID
+		return metrics[i].Key() < metrics[j].Key()
 	})
 
 	// Компиляция темплейта
@@ -74,14 +74,14 @@ func (api *API) getAllHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // getAllJSONHandler обрабатывает GET-запросы на получение таблицы со всеми имеющимися метриками в формате JSON.
-func (api *API) getAllJSONHandler(w http.ResponseWriter, _ *http.Request) {
-	metrics, err := api.storage.ReadAllMetrics()
+func (api *API) getAllJSONHandler(w http.ResponseWriter, r *http.Request) {
+	metrics, err := api.storage.ReadAllMetrics(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storageError(w, err.Error(), err)
 		return
 	}
 	sort.Slice(metrics, func(i, j int) bool {
-		return metrics[i].ID < metrics[j].ID
+		return metrics[i].Key() < metrics[j].Key()
 	})
 
 	resp, err := view.Metrics(metrics).MarshalJSON()
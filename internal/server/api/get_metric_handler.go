@@ -11,10 +11,11 @@ import (
 )
 
 // Handler для получения значения конкретной метрики по её типу и имени.
+// Метки метрики передаются в query параметрах запроса.
 //
 // Swagger описание:
 // @Summary Get metric
-// @Description Get metric
+// @Description Get metric. Metric labels are passed as query parameters (e.g. ?host=web1)
 // @Tags metrics
 // @Produce text/plain
 // @Param kind path string true "Metric kind"
@@ -25,14 +26,20 @@ import (
 // @Router /value/{kind}/{name} [get]
 // Конец Swagger описания.
 func (api *API) getMetricHandler(w http.ResponseWriter, req *http.Request) {
-	// парсинг url запроса для получения типа и имени искомой метрики
+	// парсинг url запроса для получения типа, имени и меток искомой метрики
 	kind := chi.URLParam(req, "kind")
 	name := chi.URLParam(req, "name")
+	labels, err := labelsFromQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// получение метрики из репозитория
-	metric, err := api.storage.GetMetric(kind, name)
+	metric, err := api.storage.GetMetric(req.Context(), kind, name, labels)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		storageError(w, err.Error(), err)
+		return
 	}
 
 	// отдача метрики клиенту
@@ -74,9 +81,10 @@ func (api *API) getJSONMetricHandler(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// получение метрики из репозитория
-	metric, err := api.storage.GetMetric(reqMetric.MType, reqMetric.ID)
+	metric, err := api.storage.GetMetric(req.Context(), reqMetric.MType, reqMetric.ID, reqMetric.Labels)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		storageError(w, err.Error(), err)
+		return
 	}
 
 	// Marshal ответа
@@ -41,6 +41,43 @@ func TestRouter_getMetricHandler(t *testing.T) {
 				content: "54",
 			},
 		},
+		{
+			name: "labels test",
+			values: []view.Metric{
+				{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"host": "web1", "core": "3"},
+					Value:  func(i float64) *float64 { return &i }(12),
+				},
+				{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"host": "web2", "core": "3"},
+					Value:  func(i float64) *float64 { return &i }(34),
+				},
+			},
+			reqURL: "/value/gauge/cpu?core=3&host=web2",
+			want: want{
+				code:    200,
+				content: "34",
+			},
+		},
+		{
+			name: "not found test",
+			values: []view.Metric{
+				{
+					ID:    "test1",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(54),
+				},
+			},
+			reqURL: "/value/counter/test1",
+			want: want{
+				code:    404,
+				content: "metric not found\n",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -103,6 +140,36 @@ func TestRouter_getJSONMetricHandler(t *testing.T) {
 				}(),
 			},
 		},
+		{
+			name: "labels test",
+			request: view.Metric{
+				ID:     "cpu",
+				MType:  view.KindGauge,
+				Labels: view.Labels{"host": "web1"},
+			},
+			values: []view.Metric{
+				{
+					ID:    "cpu",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(1),
+				},
+				{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"host": "web1"},
+					Value:  func(i float64) *float64 { return &i }(2),
+				},
+			},
+			want: want{
+				code: 200,
+				content: view.Metric{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"host": "web1"},
+					Value:  func(i float64) *float64 { return &i }(2),
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
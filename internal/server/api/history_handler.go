@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-chi/chi/v5"
+)
+
+// Интервал истории, возвращаемый если начало интервала не указано.
+const defaultHistoryRange = time.Hour
+
+var (
+	errInvalidTime        = errors.New("invalid time. expected RFC3339 or unix seconds")
+	errInvalidRange       = errors.New("invalid time range")
+	errInvalidStep        = errors.New("invalid step")
+	errInvalidAggregation = errors.New("invalid aggregation. expected avg, min, max or last")
+)
+
+// Handler для получения истории значений метрики по её типу и имени.
+// Метки метрики передаются в query параметрах запроса наравне с параметрами выборки.
+//
+// Swagger описание:
+// @Summary Get metric history
+// @Description Get metric values history for time range with optional downsampling.
+// @Description Metric labels are passed as additional query parameters (e.g. ?host=web1)
+// @Tags metrics
+// @Produce json
+// @Param kind path string true "Metric kind"
+// @Param name path string true "Metric name"
+// @Param from query string false "Range start, RFC3339 or unix seconds. Default: to - 1h"
+// @Param to query string false "Range end, RFC3339 or unix seconds. Default: now"
+// @Param step query string false "Downsampling step, e.g. 30s, 5m"
+// @Param agg query string false "Downsampling aggregation: avg, min, max, last. Default: avg"
+// @Success 200 {array} view.Sample
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Metric not found"
+// @Failure 500 {string} string "Error"
+// @Router /history/{kind}/{name} [get]
+// Конец Swagger описания.
+func (api *API) historyHandler(w http.ResponseWriter, req *http.Request) {
+	// парсинг параметров запроса
+	query, err := parseHistoryQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// получение истории из репозитория
+	samples, err := api.storage.ReadHistory(req.Context(), query)
+	if err != nil {
+		slog.Error("ReadHistory error", slog.String("error", err.Error()))
+		storageError(w, err.Error(), err)
+		return
+	}
+
+	// Marshal ответа
+	resp, err := samples.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// записываем ответ
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(resp); err != nil {
+		slog.Error("writing response error", "message", err)
+		http.Error(w, fmt.Sprintf("write metric error: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseHistoryQuery формирует параметры запроса истории из HTTP запроса.
+func parseHistoryQuery(req *http.Request) (view.HistoryQuery, error) {
+	params := req.URL.Query()
+
+	query := view.HistoryQuery{
+		Kind:        chi.URLParam(req, "kind"),
+		Name:        chi.URLParam(req, "name"),
+		To:          time.Now(),
+		Aggregation: view.AggregationAvg,
+	}
+
+	var err error
+	if query.Labels, err = labelsFromQuery(req, "from", "to", "step", "agg"); err != nil {
+		return query, err
+	}
+	if raw := params.Get("to"); raw != "" {
+		if query.To, err = parseTime(raw); err != nil {
+			return query, err
+		}
+	}
+
+	query.From = query.To.Add(-defaultHistoryRange)
+	if raw := params.Get("from"); raw != "" {
+		if query.From, err = parseTime(raw); err != nil {
+			return query, err
+		}
+	}
+
+	if query.From.After(query.To) {
+		return query, errInvalidRange
+	}
+
+	if raw := params.Get("step"); raw != "" {
+		query.Step, err = time.ParseDuration(raw)
+		if err != nil || query.Step <= 0 {
+			return query, errInvalidStep
+		}
+	}
+
+	if raw := params.Get("agg"); raw != "" {
+		switch raw {
+		case view.AggregationAvg, view.AggregationMin, view.AggregationMax, view.AggregationLast:
+			query.Aggregation = raw
+		default:
+			return query, errInvalidAggregation
+		}
+	}
+
+	return query, nil
+}
+
+// parseTime разбирает время в формате RFC3339 или unix секундах.
+func parseTime(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, errInvalidTime
+	}
+	return t, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_historyHandler(t *testing.T) {
+	type want struct {
+		code  int
+		query view.HistoryQuery
+	}
+	type test struct {
+		name    string
+		reqURL  string
+		history view.Samples
+		want    want
+	}
+
+	history := view.Samples{
+		{Timestamp: time.Unix(1700000000, 0).UTC(), Value: 1},
+		{Timestamp: time.Unix(1700000060, 0).UTC(), Value: 2},
+	}
+
+	tests := []test{
+		{
+			name:    "range with downsampling",
+			reqURL:  "/history/gauge/cpu?from=1700000000&to=2023-11-14T22:23:20Z&step=1m&agg=max&host=web1",
+			history: history,
+			want: want{
+				code: 200,
+				query: view.HistoryQuery{
+					Kind:        view.KindGauge,
+					Name:        "cpu",
+					Labels:      view.Labels{"host": "web1"},
+					From:        time.Unix(1700000000, 0),
+					To:          time.Unix(1700000600, 0),
+					Step:        time.Minute,
+					Aggregation: view.AggregationMax,
+				},
+			},
+		},
+		{
+			name:   "invalid aggregation",
+			reqURL: "/history/gauge/cpu?agg=median",
+			want: want{
+				code: 400,
+			},
+		},
+		{
+			name:   "invalid step",
+			reqURL: "/history/gauge/cpu?step=-1m",
+			want: want{
+				code: 400,
+			},
+		},
+		{
+			name:   "invalid range",
+			reqURL: "/history/gauge/cpu?from=1700000600&to=1700000000",
+			want: want{
+				code: 400,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &MockMetricsStorage{
+				history: tt.history,
+			}
+			api := New(&Settings{
+				Storage: storage,
+			})
+
+			r := chi.NewRouter()
+			r.Get("/history/{kind}/{name}", api.historyHandler)
+
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			client := resty.New()
+
+			resp, err := client.R().Get(fmt.Sprintf("%s%s", server.URL, tt.reqURL))
+
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.want.code, resp.StatusCode())
+
+			if tt.want.code != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.want.query.Kind, storage.query.Kind)
+			assert.Equal(t, tt.want.query.Name, storage.query.Name)
+			assert.Equal(t, tt.want.query.Labels, storage.query.Labels)
+			assert.True(t, tt.want.query.From.Equal(storage.query.From))
+			assert.True(t, tt.want.query.To.Equal(storage.query.To))
+			assert.Equal(t, tt.want.query.Step, storage.query.Step)
+			assert.Equal(t, tt.want.query.Aggregation, storage.query.Aggregation)
+
+			var samples view.Samples
+			require.NoError(t, samples.UnmarshalJSON(resp.Body()))
+			assert.Equal(t, tt.history, samples)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// labelsFromQuery возвращает набор меток метрики, переданный в query параметрах запроса.
+// Каждый параметр вида key=value интерпретируется как метка key со значением value.
+// Параметры с именами из reserved не считаются метками.
+// Если параметр указан несколько раз, используется первое значение.
+// Если меток нет, возвращает nil.
+// Возвращает ошибку, если имя метки не соответствует формату [a-zA-Z_][a-zA-Z0-9_]*.
+func labelsFromQuery(req *http.Request, reserved ...string) (view.Labels, error) {
+	query := req.URL.Query()
+	for _, key := range reserved {
+		query.Del(key)
+	}
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	labels := make(view.Labels, len(query))
+	for key := range query {
+		labels[key] = query.Get(key)
+	}
+
+	return labels, labels.Validate()
+}
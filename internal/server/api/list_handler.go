@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Параметры запроса списка метрик, не являющиеся метками.
+//
+//nolint:gochecknoglobals // reserved query params
+var listParams = []string{"kind", "prefix", "glob", "regex", "sort", "order", "limit", "cursor"}
+
+var (
+	errInvalidOrder = errors.New("invalid order. expected asc or desc")
+	errInvalidLimit = errors.New("invalid limit")
+)
+
+// Handler для получения страницы списка метрик с фильтрацией и сортировкой.
+// Метки метрик передаются в query параметрах запроса наравне с параметрами выборки.
+//
+// Swagger описание:
+// @Summary List metrics
+// @Description List metrics filtered by kind, name and labels with cursor-based pagination.
+// @Description Metric labels are passed as additional query parameters (e.g. ?host=web1)
+// @Tags metrics
+// @Produce json
+// @Param kind query string false "Metric kind: gauge, counter or histogram"
+// @Param prefix query string false "Metric name prefix"
+// @Param glob query string false "Metric name glob: * - any string, ? - any character, [...] - character class"
+// @Param regex query string false "Metric name regular expression"
+// @Param sort query string false "Sort field: id, type or last_updated. Default: id"
+// @Param order query string false "Sort order: asc or desc. Default: asc"
+// @Param limit query int false "Page size, up to 1000. Default: 100"
+// @Param cursor query string false "Page cursor from next_cursor of the previous page"
+// @Success 200 {object} view.MetricsPage
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Error"
+// @Router /list [get]
+// Конец Swagger описания.
+func (api *API) listHandler(w http.ResponseWriter, req *http.Request) {
+	// парсинг параметров запроса
+	query, err := parseListQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// получение страницы из репозитория
+	page, err := api.storage.ListMetrics(req.Context(), query)
+	if err != nil {
+		slog.Error("ListMetrics error", slog.String("error", err.Error()))
+		storageError(w, err.Error(), err)
+		return
+	}
+
+	// Marshal ответа
+	resp, err := page.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// записываем ответ
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(resp); err != nil {
+		slog.Error("writing response error", "message", err)
+		http.Error(w, fmt.Sprintf("write metric error: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseListQuery формирует параметры запроса списка метрик из HTTP запроса.
+func parseListQuery(req *http.Request) (view.ListQuery, error) {
+	params := req.URL.Query()
+
+	query := view.ListQuery{
+		Kind:   params.Get("kind"),
+		Prefix: params.Get("prefix"),
+		Glob:   params.Get("glob"),
+		Regex:  params.Get("regex"),
+		Sort:   params.Get("sort"),
+		Cursor: params.Get("cursor"),
+	}
+
+	var err error
+	if query.Labels, err = labelsFromQuery(req, listParams...); err != nil {
+		return query, err
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errInvalidOrder
+	}
+
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return query, errInvalidLimit
+		}
+		query.Limit = limit
+	}
+
+	return query, query.Normalize()
+}
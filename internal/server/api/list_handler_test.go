@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_listHandler(t *testing.T) {
+	type want struct {
+		code  int
+		query view.ListQuery
+		ids   []string
+	}
+	type test struct {
+		name   string
+		reqURL string
+		want   want
+	}
+
+	content := view.Metrics{
+		{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "web1"}},
+		{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "web2"}},
+		{ID: "requests", MType: view.KindCounter, Labels: view.Labels{"host": "web1"}},
+		{ID: "ram", MType: view.KindGauge},
+	}
+
+	tests := []test{
+		{
+			name:   "filters and labels",
+			reqURL: "/list?kind=gauge&prefix=c&host=web1",
+			want: want{
+				code: 200,
+				query: view.ListQuery{
+					Kind:   view.KindGauge,
+					Prefix: "c",
+					Labels: view.Labels{"host": "web1"},
+					Sort:   view.SortByID,
+					Limit:  view.DefaultListLimit,
+				},
+				ids: []string{"cpu"},
+			},
+		},
+		{
+			name:   "sort and limit",
+			reqURL: "/list?sort=type&order=desc&limit=2&glob=r*",
+			want: want{
+				code: 200,
+				query: view.ListQuery{
+					Glob:  "r*",
+					Sort:  view.SortByKind,
+					Desc:  true,
+					Limit: 2,
+				},
+				ids: []string{"ram", "requests"},
+			},
+		},
+		{
+			name:   "invalid order",
+			reqURL: "/list?order=up",
+			want:   want{code: 400},
+		},
+		{
+			name:   "invalid limit",
+			reqURL: "/list?limit=0",
+			want:   want{code: 400},
+		},
+		{
+			name:   "invalid regex",
+			reqURL: "/list?regex=(",
+			want:   want{code: 400},
+		},
+		{
+			name:   "invalid label name",
+			reqURL: "/list?host-name=web1",
+			want:   want{code: 400},
+		},
+		{
+			name:   "invalid cursor",
+			reqURL: "/list?cursor=abc",
+			want:   want{code: 400},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &MockMetricsStorage{
+				content: content,
+			}
+			api := New(&Settings{
+				Storage: storage,
+			})
+
+			r := chi.NewRouter()
+			r.Get("/list", api.listHandler)
+
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			client := resty.New()
+
+			resp, err := client.R().Get(fmt.Sprintf("%s%s", server.URL, tt.reqURL))
+
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.want.code, resp.StatusCode())
+
+			if tt.want.code != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.want.query, storage.list)
+
+			var page view.MetricsPage
+			require.NoError(t, page.UnmarshalJSON(resp.Body()))
+			ids := make([]string, 0, len(page.Metrics))
+			for _, metric := range page.Metrics {
+				ids = append(ids, metric.ID)
+			}
+			assert.Equal(t, tt.want.ids, ids)
+		})
+	}
+}
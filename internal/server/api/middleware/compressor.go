@@ -23,6 +23,10 @@ type gzipResponseWriter struct {
 
 // Write переопределение функции http.ResponseWriter.Write([]byte).
 func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	// Поток событий не сжимается, так как каждое событие должно передаваться клиенту сразу
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		return w.ResponseWriter.Write(data)
+	}
 	// TODO: переделать длинну порога
 	// Сжимаем данные только если их размер больше 75 байт
 	if len(data) > w.minDataLength {
@@ -66,6 +70,11 @@ func (w *gzipResponseWriter) Write(data []byte) (int, error) {
 	return w.ResponseWriter.Write(data)
 }
 
+// Unwrap возвращает исходный http.ResponseWriter для использования в http.ResponseController.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // GzipCompressor является middleware функцией для использования совместно с chi роутером.
 // Сжимает тело ответа, если клиент принимает его в таком виде.
 func (c *Compressor) Handle(next http.Handler) http.Handler {
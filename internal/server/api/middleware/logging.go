@@ -37,6 +37,11 @@ func (r *responseRecorder) Write(data []byte) (int, error) {
 	return size, err
 }
 
+// Unwrap возвращает исходный http.ResponseWriter для использования в http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 type Logger struct{}
 
 // Logger является middleware функцией для использования совместно с chi роутером.
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"encoding/hex"
 	"io"
 	"net/http"
@@ -9,7 +10,20 @@ import (
 	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
 )
 
+// Validator проверяет подпись тела запроса HMAC SHA256 из заголовка HashSHA256
+// и подписывает тело ответа.
+// Если Strict равен false, запросы без тела или без заголовка HashSHA256 пропускаются без проверки.
+// Если Strict равен true, заголовок HashSHA256 обязателен.
 type Validator struct {
+	Key    []byte
+	Strict bool
+}
+
+// URIValidator проверяет подпись запросов без тела, изменяющих метрики: удаления и сброса счетчика.
+// Тело таких запросов пустое, поэтому Validator их не проверяет.
+// Заголовок HashSHA256 обязателен и должен содержать HMAC SHA256 метода и пути запроса,
+// см. validation.CalculateRequestHMAC.
+type URIValidator struct {
 	Key []byte
 }
 
@@ -26,6 +40,11 @@ func (w *hashWriter) Write(data []byte) (int, error) {
 	return w.ResponseWriter.Write(data)
 }
 
+// Unwrap возвращает исходный http.ResponseWriter для использования в http.ResponseController.
+func (w *hashWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (h *Validator) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Создание hashWriter для записи хеша ответа в хедер
@@ -35,7 +54,7 @@ func (h *Validator) Handle(next http.Handler) http.Handler {
 		}
 
 		// Проверка на наличие тела запроса
-		if r.ContentLength <= 0 {
+		if r.ContentLength <= 0 && !h.Strict {
 			r.Body = http.NoBody
 			next.ServeHTTP(hw, r)
 			return
@@ -44,6 +63,10 @@ func (h *Validator) Handle(next http.Handler) http.Handler {
 		// Получение хеша из заголовка запроса
 		sampleHashString := r.Header.Get("HashSHA256")
 		if sampleHashString == "" {
+			if h.Strict {
+				http.Error(w, "HashSHA256 Header required", http.StatusBadRequest)
+				return
+			}
 			// TODO: for tests
 			next.ServeHTTP(hw, r)
 			// http.Error(w, "HashSHA256 Header required", http.StatusBadRequest)
@@ -79,3 +102,27 @@ func (h *Validator) Handle(next http.Handler) http.Handler {
 		next.ServeHTTP(hw, r)
 	})
 }
+
+func (h *URIValidator) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sampleHashString := r.Header.Get("HashSHA256")
+		if sampleHashString == "" {
+			http.Error(w, "HashSHA256 Header required", http.StatusBadRequest)
+			return
+		}
+		sampleHash, err := hex.DecodeString(sampleHashString)
+		if err != nil {
+			http.Error(w, "Can't decode hash", http.StatusBadRequest)
+			return
+		}
+
+		// Сравнение подписи метода и пути запроса
+		hash := validation.CalculateRequestHMAC(r.Method, r.URL.RequestURI(), h.Key)
+		if !hmac.Equal(hash, sampleHash) {
+			http.Error(w, "Invalid Hash", http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,8 +1,9 @@
 package api
 
 import (
-	"errors"
+	"context"
 
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
 )
 
@@ -10,23 +11,31 @@ type MockMetricsStorage struct {
 	pingErr error
 	err     error
 	content view.Metrics
+	history view.Samples
+	query   view.HistoryQuery
+	list    view.ListQuery
 }
 
 var _ MetricsStorage = &MockMetricsStorage{}
 
-func (m *MockMetricsStorage) GetMetric(kind string, name string) (view.Metric, error) {
+func (m *MockMetricsStorage) GetMetric(
+	_ context.Context,
+	kind string,
+	name string,
+	labels view.Labels,
+) (view.Metric, error) {
 	if m.err != nil {
 		return view.Metric{}, m.err
 	}
 	for _, metric := range m.content {
-		if metric.ID == name && metric.MType == kind {
+		if metric.Key() == name+labels.String() && metric.MType == kind {
 			return metric, nil
 		}
 	}
-	return view.Metric{}, errors.New("not found")
+	return view.Metric{}, repository.ErrNotFound
 }
 
-func (m *MockMetricsStorage) AddMetrics(metrics ...view.Metric) ([]view.Metric, error) {
+func (m *MockMetricsStorage) AddMetrics(_ context.Context, metrics ...view.Metric) ([]view.Metric, error) {
 	if m.err != nil {
 		return []view.Metric{}, m.err
 	}
@@ -34,13 +43,68 @@ func (m *MockMetricsStorage) AddMetrics(metrics ...view.Metric) ([]view.Metric,
 	return metrics, nil
 }
 
-func (m *MockMetricsStorage) ReadAllMetrics() ([]view.Metric, error) {
+func (m *MockMetricsStorage) ReadAllMetrics(_ context.Context) ([]view.Metric, error) {
 	if m.err != nil {
 		return []view.Metric{}, m.err
 	}
 	return m.content, nil
 }
 
-func (m *MockMetricsStorage) Ping() error {
+func (m *MockMetricsStorage) ReadHistory(_ context.Context, query view.HistoryQuery) (view.Samples, error) {
+	m.query = query
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.history, nil
+}
+
+func (m *MockMetricsStorage) ListMetrics(_ context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	m.list = query
+	if m.err != nil {
+		return view.MetricsPage{}, m.err
+	}
+	return view.ListMetrics(m.content, query)
+}
+
+func (m *MockMetricsStorage) DeleteMetrics(_ context.Context, metrics ...view.Metric) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, metric := range metrics {
+		if m.find(metric.MType, metric.Key()) < 0 {
+			return repository.ErrNotFound
+		}
+	}
+	for _, metric := range metrics {
+		if i := m.find(metric.MType, metric.Key()); i >= 0 {
+			m.content = append(m.content[:i], m.content[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (m *MockMetricsStorage) ResetCounter(_ context.Context, name string, labels view.Labels) (view.Metric, error) {
+	if m.err != nil {
+		return view.Metric{}, m.err
+	}
+	i := m.find(view.KindCounter, name+labels.String())
+	if i < 0 {
+		return view.Metric{}, repository.ErrNotFound
+	}
+	var delta int64
+	m.content[i].Delta = &delta
+	return m.content[i], nil
+}
+
+func (m *MockMetricsStorage) find(kind string, key string) int {
+	for i := range m.content {
+		if m.content[i].Key() == key && m.content[i].MType == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *MockMetricsStorage) Ping(_ context.Context) error {
 	return m.pingErr
 }
@@ -3,6 +3,7 @@ package api
 import "net/http"
 
 // Handler для проверки соединения с базой данных.
+// Если база данных временно недоступна и обращения к ней приостановлены, возвращает 503.
 //
 // Swagger описание:
 // @Summary Ping
@@ -11,12 +12,13 @@ import "net/http"
 // @Produce text/plain
 // @Success 200 {string} string "OK"
 // @Failure 500 {string} string "Error"
+// @Failure 503 {string} string "DB temporarily unavailable"
 // @Router /ping [get]
 // Конец Swagger описания.
-func (api *API) pingHandler(w http.ResponseWriter, _ *http.Request) {
-	err := api.storage.Ping()
+func (api *API) pingHandler(w http.ResponseWriter, r *http.Request) {
+	err := api.storage.Ping(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storageError(w, err.Error(), err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,30 +15,30 @@ import (
 
 func TestAPI_pingHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		expectErr bool
-		code      int
+		name    string
+		pingErr error
+		code    int
 	}{
 		{
-			name:      "no err",
-			expectErr: false,
-			code:      200,
+			name:    "no err",
+			pingErr: nil,
+			code:    200,
 		},
 		{
-			name:      "err",
-			expectErr: true,
-			code:      500,
+			name:    "err",
+			pingErr: errors.New("err"),
+			code:    500,
+		},
+		{
+			name:    "circuit breaker open",
+			pingErr: fmt.Errorf("ping: %w", circuitbreaker.ErrOpen),
+			code:    503,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Settings{}
-
-			if tt.expectErr {
-				s.Storage = &MockMetricsStorage{pingErr: errors.New("err")}
-			} else {
-				s.Storage = &MockMetricsStorage{pingErr: nil}
-			}
+			s.Storage = &MockMetricsStorage{pingErr: tt.pingErr}
 			r := New(s)
 
 			server := httptest.NewServer(http.HandlerFunc(r.pingHandler))
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+const (
+	// Content-Type текстового формата Prometheus.
+	contentTypePrometheus = "text/plain; version=0.0.4; charset=utf-8"
+	// Content-Type формата OpenMetrics.
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Handler отдающий все имеющиеся метрики в текстовом формате Prometheus.
+// Если клиент принимает application/openmetrics-text, то метрики отдаются в формате OpenMetrics.
+//
+// Swagger описание:
+// @Summary Get all metrics in Prometheus format
+// @Description Get all metrics in Prometheus text exposition format.
+// @Description OpenMetrics format is used if client accepts application/openmetrics-text
+// @Tags metrics
+// @Produce text/plain
+// @Success 200 {string} string "Metrics"
+// @Failure 500 {string} string "Error"
+// @Router /metrics [get]
+// Конец Swagger описания.
+func (api *API) prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+
+	// получение всех метрик из репозитория
+	metrics, err := api.storage.ReadAllMetrics(r.Context())
+	if err != nil {
+		storageError(w, "Error whlie getting metrics from repository", err)
+		return
+	}
+
+	// Сортировка метрик для группировки серий по имени семейства.
+	// Имена приводятся к формату Prometheus на копии, чтобы не изменять данные репозитория
+	families := make([]prometheusFamily, 0, len(metrics))
+	for _, metric := range metrics {
+		families = append(families, prometheusFamily{
+			name:   familyName(metric, openMetrics),
+			metric: metric,
+		})
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		if families[i].name != families[j].name {
+			return families[i].name < families[j].name
+		}
+		return families[i].metric.Key() < families[j].metric.Key()
+	})
+
+	var resp bytes.Buffer
+	exporter := newPrometheusExporter(openMetrics)
+	for _, family := range families {
+		if err = exporter.check(family); err != nil {
+			slog.Error(
+				"skipping metric",
+				slog.String("metric", family.metric.Key()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		// Запись TYPE строки для нового семейства метрик
+		if family.name != exporter.familyName {
+			exporter.familyName, exporter.familyKind = family.name, family.metric.MType
+			fmt.Fprintf(&resp, "# TYPE %s %s\n", family.name, family.metric.MType)
+		}
+
+		writePrometheusMetric(&resp, family.name, family.metric, openMetrics)
+	}
+
+	contentType := contentTypePrometheus
+	if openMetrics {
+		resp.WriteString("# EOF\n")
+		contentType = contentTypeOpenMetrics
+	}
+
+	// Передача ответа клиенту
+	w.Header().Set("Content-Type", contentType)
+	if _, err = w.Write(resp.Bytes()); err != nil {
+		slog.Error("writing response error", "message", err)
+		http.Error(w, fmt.Sprintf("write metric error: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// prometheusFamily - метрика и имя семейства Prometheus, в которое она входит.
+type prometheusFamily struct {
+	name   string
+	metric view.Metric
+}
+
+// prometheusExporter отслеживает уже записанные серии, чтобы разные метрики,
+// имена которых совпали после приведения к формату Prometheus, не давали повторяющихся серий.
+type prometheusExporter struct {
+	openMetrics bool
+	familyName  string
+	familyKind  string
+	// Имя сэмпла -> исходное имя метрики, серии которой используют это имя
+	owners map[string]string
+	// Записанные серии: имя сэмпла вместе с метками
+	series map[string]struct{}
+}
+
+func newPrometheusExporter(openMetrics bool) *prometheusExporter {
+	return &prometheusExporter{
+		openMetrics: openMetrics,
+		owners:      make(map[string]string),
+		series:      make(map[string]struct{}),
+	}
+}
+
+// check проверяет, что серии метрики можно записать без конфликтов с уже записанными сериями.
+// При успешной проверке серии метрики считаются записанными.
+func (e *prometheusExporter) check(family prometheusFamily) error {
+	metric := family.metric
+
+	// Метрика без значения своего типа не записывается
+	if !hasValue(metric) {
+		return errors.New("metric has no value")
+	}
+
+	// Разные метки не должны совпадать после приведения имен к формату Prometheus
+	labelNames := make(map[string]string, len(metric.Labels))
+	for name := range metric.Labels {
+		sanitized := sanitizeLabelName(name)
+		if other, ok := labelNames[sanitized]; ok {
+			return fmt.Errorf("labels %s and %s collide as %s", other, name, sanitized)
+		}
+		labelNames[sanitized] = name
+	}
+
+	// В семействе все серии должны иметь один тип
+	if family.name == e.familyName && metric.MType != e.familyKind {
+		return fmt.Errorf("conflicting type %s", metric.MType)
+	}
+
+	// Метка le зарезервирована за границами корзин гистограммы
+	if metric.MType == view.KindHistogram {
+		for name := range metric.Labels {
+			if sanitizeLabelName(name) == "le" {
+				return errors.New("histogram uses reserved label le")
+			}
+		}
+	}
+
+	names := sampleNames(family.name, metric.MType, e.openMetrics)
+	labels := formatPrometheusLabels(metric.Labels, "", "")
+	for _, name := range names {
+		if owner, ok := e.owners[name]; ok && owner != metric.ID {
+			return fmt.Errorf("name %s collides with metric %s", name, owner)
+		}
+		if _, ok := e.series[name+labels]; ok {
+			return fmt.Errorf("duplicate series %s%s", name, labels)
+		}
+	}
+
+	for _, name := range names {
+		e.owners[name] = metric.ID
+		e.series[name+labels] = struct{}{}
+	}
+	return nil
+}
+
+// hasValue проверяет, что у метрики задано значение, соответствующее её типу.
+func hasValue(metric view.Metric) bool {
+	switch metric.MType {
+	case view.KindGauge:
+		return metric.Value != nil
+	case view.KindCounter:
+		return metric.Delta != nil
+	case view.KindHistogram:
+		return metric.Histogram != nil
+	default:
+		return false
+	}
+}
+
+// familyName возвращает имя семейства Prometheus для метрики.
+// Для счетчиков в формате OpenMetrics суффикс _total не входит в имя семейства.
+func familyName(metric view.Metric, openMetrics bool) string {
+	name := sanitizeMetricName(metric.ID)
+	if openMetrics && metric.MType == view.KindCounter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	return name
+}
+
+// sampleNames возвращает имена сэмплов, которые записываются для семейства name типа kind.
+func sampleNames(name, kind string, openMetrics bool) []string {
+	switch {
+	case kind == view.KindHistogram:
+		return []string{name + "_bucket", name + "_sum", name + "_count"}
+	case kind == view.KindCounter && openMetrics:
+		return []string{name + "_total"}
+	default:
+		return []string{name}
+	}
+}
+
+// writePrometheusMetric записывает строки с сэмплами метрики семейства name.
+func writePrometheusMetric(buf *bytes.Buffer, name string, metric view.Metric, openMetrics bool) {
+	labels := formatPrometheusLabels(metric.Labels, "", "")
+
+	switch metric.MType {
+	case view.KindGauge:
+		fmt.Fprintf(buf, "%s%s %s\n", name, labels, formatPrometheusFloat(*metric.Value))
+	case view.KindCounter:
+		if openMetrics {
+			name += "_total"
+		}
+		fmt.Fprintf(buf, "%s%s %d\n", name, labels, *metric.Delta)
+	case view.KindHistogram:
+		// Корзины в формате Prometheus накопительные
+		var cumulative uint64
+		for i, bucket := range metric.Histogram.Buckets {
+			cumulative += bucket
+			le := "+Inf"
+			if i < len(metric.Histogram.Bounds) {
+				le = formatPrometheusFloat(metric.Histogram.Bounds[i])
+			}
+			fmt.Fprintf(
+				buf,
+				"%s_bucket%s %d\n",
+				name,
+				formatPrometheusLabels(metric.Labels, "le", le),
+				cumulative,
+			)
+		}
+		fmt.Fprintf(buf, "%s_sum%s %s\n", name, labels, formatPrometheusFloat(metric.Histogram.Sum))
+		fmt.Fprintf(buf, "%s_count%s %d\n", name, labels, metric.Histogram.Count)
+	}
+}
+
+// formatPrometheusLabels возвращает набор меток в формате Prometheus.
+// Если extraName не пустой, то к меткам добавляется метка extraName со значением extraValue.
+func formatPrometheusLabels(labels view.Labels, extraName, extraValue string) string {
+	if len(labels) == 0 && extraName == "" {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", sanitizeLabelName(k), escapeLabelValue(labels[k])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, escapeLabelValue(extraValue)))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatPrometheusFloat возвращает представление числа в формате Prometheus.
+func formatPrometheusFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sanitizeMetricName заменяет недопустимые в имени метрики Prometheus символы на '_'.
+// Допустимые символы: [a-zA-Z_:][a-zA-Z0-9_:]*.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// sanitizeLabelName заменяет недопустимые в имени метки Prometheus символы на '_'.
+// Допустимые символы: [a-zA-Z_][a-zA-Z0-9_]*.
+func sanitizeLabelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	result := []byte(name)
+	for i, c := range result {
+		valid := c == '_' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9' && i > 0) ||
+			(c == ':' && allowColon)
+		if !valid {
+			result[i] = '_'
+		}
+	}
+	return string(result)
+}
+
+// escapeLabelValue экранирует значение метки.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
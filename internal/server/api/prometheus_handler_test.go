@@ -0,0 +1,270 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_prometheusHandler(t *testing.T) {
+	type want struct {
+		code        int
+		contentType string
+		content     string
+	}
+	type test struct {
+		name   string
+		accept string
+		values []view.Metric
+		want   want
+	}
+
+	values := []view.Metric{
+		{
+			ID:     "cpu.usage",
+			MType:  view.KindGauge,
+			Labels: view.Labels{"host": "web1", "core": "1"},
+			Value:  func(i float64) *float64 { return &i }(0.5),
+		},
+		{
+			ID:    "PollCount",
+			MType: view.KindCounter,
+			Delta: func(i int64) *int64 { return &i }(5),
+		},
+		{
+			ID:    "latency",
+			MType: view.KindHistogram,
+			Histogram: &view.Histogram{
+				Bounds:  []float64{0.1, 1},
+				Buckets: []uint64{2, 1, 1},
+				Count:   4,
+				Sum:     3.25,
+			},
+		},
+	}
+
+	tests := []test{
+		{
+			name:   "prometheus text format",
+			values: values,
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content: `# TYPE PollCount counter
+PollCount 5
+# TYPE cpu_usage gauge
+cpu_usage{core="1",host="web1"} 0.5
+# TYPE latency histogram
+latency_bucket{le="0.1"} 2
+latency_bucket{le="1"} 3
+latency_bucket{le="+Inf"} 4
+latency_sum 3.25
+latency_count 4
+`,
+			},
+		},
+		{
+			name:   "openmetrics format",
+			accept: "application/openmetrics-text; version=1.0.0",
+			values: values[:2],
+			want: want{
+				code:        200,
+				contentType: contentTypeOpenMetrics,
+				content: `# TYPE PollCount counter
+PollCount_total 5
+# TYPE cpu_usage gauge
+cpu_usage{core="1",host="web1"} 0.5
+# EOF
+`,
+			},
+		},
+		{
+			name: "label escaping",
+			values: []view.Metric{
+				{
+					ID:     "test",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"path": `C:\tmp "x"`},
+					Value:  func(i float64) *float64 { return &i }(1),
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content: `# TYPE test gauge
+test{path="C:\\tmp \"x\""} 1
+`,
+			},
+		},
+		{
+			name: "name collisions",
+			values: []view.Metric{
+				{
+					ID:    "cpu.usage",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(1),
+				},
+				{
+					ID:    "cpu_usage",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(2),
+				},
+				{
+					ID:    "latency",
+					MType: view.KindHistogram,
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1},
+						Buckets: []uint64{1, 0},
+						Count:   1,
+						Sum:     0.5,
+					},
+				},
+				{
+					ID:    "latency_sum",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(3),
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content: `# TYPE cpu_usage gauge
+cpu_usage 1
+# TYPE latency histogram
+latency_bucket{le="1"} 1
+latency_bucket{le="+Inf"} 1
+latency_sum 0.5
+latency_count 1
+`,
+			},
+		},
+		{
+			name: "reserved le label",
+			values: []view.Metric{
+				{
+					ID:     "latency",
+					MType:  view.KindHistogram,
+					Labels: view.Labels{"le": "1"},
+					Histogram: &view.Histogram{
+						Bounds:  []float64{1},
+						Buckets: []uint64{1, 0},
+						Count:   1,
+						Sum:     0.5,
+					},
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content:     "",
+			},
+		},
+		{
+			name: "label name collisions",
+			values: []view.Metric{
+				{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"a.b": "1", "a_b": "2"},
+					Value:  func(i float64) *float64 { return &i }(1),
+				},
+				{
+					ID:     "cpu",
+					MType:  view.KindGauge,
+					Labels: view.Labels{"a.b": "1"},
+					Value:  func(i float64) *float64 { return &i }(2),
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content: `# TYPE cpu gauge
+cpu{a_b="1"} 2
+`,
+			},
+		},
+		{
+			name: "metrics without value",
+			values: []view.Metric{
+				{
+					ID:    "cpu",
+					MType: view.KindGauge,
+				},
+				{
+					ID:    "requests",
+					MType: view.KindCounter,
+				},
+				{
+					ID:    "latency",
+					MType: view.KindHistogram,
+				},
+				{
+					ID:    "ram",
+					MType: view.KindGauge,
+					Value: func(i float64) *float64 { return &i }(3),
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypePrometheus,
+				content: `# TYPE ram gauge
+ram 3
+`,
+			},
+		},
+		{
+			name:   "openmetrics counter with _total suffix",
+			accept: "application/openmetrics-text; version=1.0.0",
+			values: []view.Metric{
+				{
+					ID:    "requests_total",
+					MType: view.KindCounter,
+					Delta: func(i int64) *int64 { return &i }(7),
+				},
+				{
+					ID:    "requests",
+					MType: view.KindCounter,
+					Delta: func(i int64) *int64 { return &i }(1),
+				},
+			},
+			want: want{
+				code:        200,
+				contentType: contentTypeOpenMetrics,
+				content: `# TYPE requests counter
+requests_total 1
+# EOF
+`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&Settings{
+				Storage: &MockMetricsStorage{
+					content: tt.values,
+				},
+			})
+
+			server := httptest.NewServer(http.HandlerFunc(r.prometheusHandler))
+			defer server.Close()
+
+			client := resty.New()
+
+			resp, err := client.R().
+				SetHeader("Accept", tt.accept).
+				Get(fmt.Sprintf("%s/metrics", server.URL))
+
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.want.code, resp.StatusCode())
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.content, string(resp.Body()))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
+)
+
+// Значение заголовка Retry-After в секундах для ответов о временной недоступности хранилища.
+const storageRetryAfter = "1"
+
+// storageError отправляет ответ с сообщением msg и кодом, соответствующим ошибке репозитория err.
+// Ответ о временной недоступности хранилища дополняется заголовком Retry-After.
+func storageError(w http.ResponseWriter, msg string, err error) {
+	code := storageErrorStatus(err)
+	if code == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", storageRetryAfter)
+	}
+	http.Error(w, msg, code)
+}
+
+// storageErrorStatus возвращает код ответа для ошибки репозитория.
+// Ошибки, не относящиеся к ошибкам репозитория и валидации метрик, считаются внутренними.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrWrongType),
+		errors.Is(err, view.ErrInvalidHistogram),
+		errors.Is(err, view.ErrInvalidLabelName),
+		errors.Is(err, view.ErrHistogramBounds),
+		errors.Is(err, view.ErrUnknownAggregation),
+		errors.Is(err, view.ErrInvalidListQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, circuitbreaker.ErrOpen),
+		errors.Is(err, repository.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
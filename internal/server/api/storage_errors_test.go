@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "not found", err: repository.ErrNotFound, want: http.StatusNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("get: %w", repository.ErrNotFound), want: http.StatusNotFound},
+		{name: "wrong type", err: repository.ErrWrongType, want: http.StatusBadRequest},
+		{name: "histogram bounds", err: view.ErrHistogramBounds, want: http.StatusBadRequest},
+		{name: "invalid histogram", err: view.ErrInvalidHistogram, want: http.StatusBadRequest},
+		{name: "invalid label name", err: fmt.Errorf("%w: %q", view.ErrInvalidLabelName, "a-b"), want: http.StatusBadRequest},
+		{name: "invalid list query", err: fmt.Errorf("%w: invalid cursor", view.ErrInvalidListQuery), want: http.StatusBadRequest},
+		{name: "breaker open", err: circuitbreaker.ErrOpen, want: http.StatusServiceUnavailable},
+		{
+			name: "retries exhausted",
+			err:  fmt.Errorf("%w: %w", repository.ErrUnavailable, io.ErrUnexpectedEOF),
+			want: http.StatusServiceUnavailable,
+		},
+		{name: "timeout", err: context.DeadlineExceeded, want: http.StatusGatewayTimeout},
+		{name: "unknown", err: errors.New("connection reset"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, storageErrorStatus(tt.err))
+		})
+	}
+}
+
+func TestStorageError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{name: "breaker open", err: circuitbreaker.ErrOpen, wantCode: http.StatusServiceUnavailable, wantRetryAfter: "1"},
+		{name: "not found", err: repository.ErrNotFound, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			storageError(w, tt.err.Error(), tt.err)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
@@ -12,10 +12,11 @@ import (
 )
 
 // Handler для обноваления состояния метрики в репозитории.
+// Метки метрики передаются в query параметрах запроса.
 //
 // Swagger описание:
 // @Summary Update metric
-// @Description Update metric in DB
+// @Description Update metric in DB. Metric labels are passed as query parameters (e.g. ?host=web1)
 // @Tags metrics
 // @Produce text/plain
 // @Param kind path string true "Metric kind"
@@ -38,10 +39,14 @@ func (api *API) updateHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if metric.Labels, err = labelsFromQuery(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// добавление метрики в репозиторий
-	if _, err = api.storage.AddMetrics(*metric); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if _, err = api.storage.AddMetrics(req.Context(), *metric); err != nil {
+		storageError(w, err.Error(), err)
 		return
 	}
 
@@ -82,9 +87,9 @@ func (api *API) updateJSONHandler(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// добавление метрики в репозиторий
-	metrics, err := api.storage.AddMetrics(metric)
+	metrics, err := api.storage.AddMetrics(req.Context(), metric)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		storageError(w, err.Error(), err)
 		return
 	}
 
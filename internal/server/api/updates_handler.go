@@ -42,9 +42,9 @@ func (api *API) updateBatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Добавление метрики в репозиторий
-	if metrics, err = api.storage.AddMetrics(metrics...); err != nil {
+	if metrics, err = api.storage.AddMetrics(r.Context(), metrics...); err != nil {
 		slog.Error("AddBatchMetrics error", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		storageError(w, err.Error(), err)
 		return
 	}
 
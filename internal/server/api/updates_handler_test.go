@@ -7,7 +7,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,7 +20,7 @@ func TestAPI_updateBatchHandler(t *testing.T) {
 		name   string
 		values view.Metrics
 		code   int
-		dbErr  bool
+		dbErr  error
 	}{
 		{
 			name: "simple test",
@@ -45,17 +47,39 @@ func TestAPI_updateBatchHandler(t *testing.T) {
 					Delta: func(i int64) *int64 { return &i }(45),
 				},
 			},
+			code:  500,
+			dbErr: errors.New("err"),
+		},
+		{
+			name: "wrong type test",
+			values: view.Metrics{
+				{
+					ID:    view.KindCounter,
+					MType: view.KindCounter,
+					Delta: func(i int64) *int64 { return &i }(45),
+				},
+			},
 			code:  400,
-			dbErr: true,
+			dbErr: repository.ErrWrongType,
+		},
+		{
+			name: "db unavailable test",
+			values: view.Metrics{
+				{
+					ID:    view.KindCounter,
+					MType: view.KindCounter,
+					Delta: func(i int64) *int64 { return &i }(45),
+				},
+			},
+			code:  503,
+			dbErr: fmt.Errorf("add metrics: %w", circuitbreaker.ErrOpen),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &MockMetricsStorage{}
 
-			if tt.dbErr {
-				storage.err = errors.New("err")
-			}
+			storage.err = tt.dbErr
 
 			r := New(&Settings{
 				Storage: storage,
@@ -74,7 +98,7 @@ func TestAPI_updateBatchHandler(t *testing.T) {
 			require.NoError(t, err, "error making http request")
 			assert.Equal(t, tt.code, resp.StatusCode())
 
-			if !tt.dbErr {
+			if tt.dbErr == nil {
 				assert.Equal(t, tt.values, storage.content)
 			}
 			server.Close()
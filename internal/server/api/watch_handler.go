@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Параметры запроса потока обновлений метрик, не являющиеся метками.
+//
+//nolint:gochecknoglobals // reserved query params
+var watchParams = []string{"kind", "prefix", "glob", "regex"}
+
+// Handler потока обновлений метрик в формате Server-Sent Events.
+// После каждой записи в хранилище клиенту отправляется событие metrics с JSON массивом
+// обновленных метрик, удовлетворяющих фильтру запроса, а после удаления метрик -
+// событие deleted с JSON массивом удаленных метрик без значений.
+// Если клиент не успевает читать обновления или сервер останавливается,
+// отправляется событие error и поток закрывается.
+//
+// Swagger описание:
+// @Summary Watch metrics
+// @Description Stream updated metrics as Server-Sent Events. Each "metrics" event carries a JSON array of metrics.
+// @Description Each "deleted" event carries a JSON array of deleted metrics without values.
+// @Description An "error" event is sent before the stream is closed by the server.
+// @Description Metric labels are passed as additional query parameters (e.g. ?host=web1)
+// @Tags metrics
+// @Produce text/event-stream
+// @Param kind query string false "Metric kind: gauge, counter or histogram"
+// @Param prefix query string false "Metric name prefix"
+// @Param glob query string false "Metric name glob: * - any string, ? - any character, [...] - character class"
+// @Param regex query string false "Metric name regular expression"
+// @Success 200 {array} view.Metric
+// @Failure 400 {string} string "Bad request"
+// @Failure 501 {string} string "Watching disabled"
+// @Router /watch [get]
+// Конец Swagger описания.
+func (api *API) watchHandler(w http.ResponseWriter, req *http.Request) {
+	if api.broker == nil {
+		http.Error(w, "metrics watching is disabled", http.StatusNotImplemented)
+		return
+	}
+
+	// парсинг фильтра
+	params := req.URL.Query()
+	query := view.ListQuery{
+		Kind:   params.Get("kind"),
+		Prefix: params.Get("prefix"),
+		Glob:   params.Get("glob"),
+		Regex:  params.Get("regex"),
+	}
+	var err error
+	if query.Labels, err = labelsFromQuery(req, watchParams...); err == nil {
+		err = query.Normalize()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub := api.broker.Subscribe(query.Matcher())
+	defer api.broker.Unsubscribe(sub)
+
+	// отправка заголовков до первого события
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("event stream flush error", slog.String("error", err.Error()))
+		return
+	}
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event, ok := <-sub.Updates():
+			if !ok {
+				_ = writeEvent(w, "error", []byte(sub.Err().Error()))
+				_ = rc.Flush()
+				return
+			}
+
+			data, err := view.Metrics(event.Metrics).MarshalJSON()
+			if err != nil {
+				slog.Error("marshaling metrics error", slog.String("error", err.Error()))
+				continue
+			}
+			name := "metrics"
+			if event.Deleted {
+				name = "deleted"
+			}
+			if err = writeEvent(w, name, data); err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				slog.Error("writing event error", slog.String("error", err.Error()))
+				return
+			}
+		}
+	}
+}
+
+// writeEvent записывает событие Server-Sent Events с именем event и однострочными данными data.
+func writeEvent(w io.Writer, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api/middleware"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Хелпер для чтения события потока Server-Sent Events.
+func readEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestAPI_watchHandler(t *testing.T) {
+	b := broker.New(broker.Settings{})
+	api := New(&Settings{
+		Storage: &MockMetricsStorage{},
+		Broker:  b,
+	})
+
+	// Поток должен передаваться через middleware без сжатия и буферизации
+	r := chi.NewRouter()
+	r.Use((&middleware.Logger{}).Handle, (&middleware.Compressor{MinDataLength: 1}).Handle)
+	r.Get("/watch", api.watchHandler)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch?kind=gauge&host=web1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	// Заголовки отправляются после создания подписки
+	value := 1.5
+	b.Publish([]view.Metric{
+		{ID: "cpu", MType: view.KindGauge, Value: &value, Labels: view.Labels{"host": "web1"}},
+		{ID: "cpu", MType: view.KindGauge, Value: &value, Labels: view.Labels{"host": "web2"}},
+		{ID: "requests", MType: view.KindCounter, Labels: view.Labels{"host": "web1"}},
+	})
+
+	reader := bufio.NewReader(resp.Body)
+	event, data := readEvent(t, reader)
+	assert.Equal(t, "metrics", event)
+
+	var metrics view.Metrics
+	require.NoError(t, metrics.UnmarshalJSON([]byte(data)))
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "cpu", metrics[0].ID)
+	assert.Equal(t, view.Labels{"host": "web1"}, metrics[0].Labels)
+
+	// Удаление метрик передается событием deleted
+	b.PublishDeleted([]view.Metric{{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "web1"}}})
+
+	event, data = readEvent(t, reader)
+	assert.Equal(t, "deleted", event)
+	require.NoError(t, metrics.UnmarshalJSON([]byte(data)))
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "cpu", metrics[0].ID)
+	assert.Nil(t, metrics[0].Value)
+}
+
+func TestAPI_watchHandlerClosed(t *testing.T) {
+	b := broker.New(broker.Settings{})
+	api := New(&Settings{
+		Storage: &MockMetricsStorage{},
+		Broker:  b,
+	})
+
+	r := chi.NewRouter()
+	r.Get("/watch", api.watchHandler)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/watch")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Остановка шины закрывает поток событием error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, b.Start(ctx))
+
+	event, data := readEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "error", event)
+	assert.Equal(t, broker.ErrClosed.Error(), data)
+}
+
+func TestAPI_watchHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		broker   *broker.Broker
+		reqURL   string
+		wantCode int
+	}{
+		{
+			name:     "watching disabled",
+			broker:   nil,
+			reqURL:   "/watch",
+			wantCode: http.StatusNotImplemented,
+		},
+		{
+			name:     "invalid regex",
+			broker:   broker.New(broker.Settings{}),
+			reqURL:   "/watch?regex=(",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid glob",
+			broker:   broker.New(broker.Settings{}),
+			reqURL:   "/watch?glob=[z-a]",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := New(&Settings{
+				Storage: &MockMetricsStorage{},
+				Broker:  tt.broker,
+			})
+
+			r := chi.NewRouter()
+			r.Get("/watch", api.watchHandler)
+
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			resp, err := resty.New().R().Get(server.URL + tt.reqURL)
+			require.NoError(t, err, "error making http request")
+			assert.Equal(t, tt.wantCode, resp.StatusCode())
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Пакет broker содержит внутреннюю шину рассылки обновлений метрик подписчикам.
+package broker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Размер буфера обновлений подписчика по умолчанию.
+const defaultBufferSize = 64
+
+var (
+	// Ошибка возвращается подписке, которая не успевала читать обновления и была отключена.
+	ErrSlowSubscriber = errors.New("subscriber is too slow")
+	// Ошибка возвращается подпискам, закрытым при остановке сервиса.
+	ErrClosed = errors.New("broker closed")
+)
+
+// Тип Settings используется для хранения настроек сервиса.
+type Settings struct {
+	BufferSize int // Кол-во пакетов обновлений в буфере подписчика. 0 - размер по умолчанию
+}
+
+// Broker - сервис рассылки обновленных метрик подписчикам.
+// Каждый подписчик получает только метрики, удовлетворяющие его фильтру.
+// Публикация не блокируется чтением подписчиков: подписчик, буфер которого заполнен,
+// отключается с ошибкой ErrSlowSubscriber и может подписаться заново.
+// Экземпляр должен создаваться с помощью New.
+type Broker struct {
+	bufferSize int
+
+	// Публикации выполняются по очереди, чтобы подписчики получали пакеты в порядке публикации
+	publishMu sync.Mutex
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Event - пакет обновлений метрик, рассылаемый подписчикам.
+type Event struct {
+	Metrics []view.Metric // Обновленные метрики или удаленные метрики без значений
+	Deleted bool          // Метрики удалены из хранилища
+}
+
+// Subscription - подписка на обновления метрик.
+// Создается методом Broker.Subscribe.
+type Subscription struct {
+	updates chan Event
+	match   func(view.Metric) bool
+	err     error
+}
+
+// Функция фабрика для создания нового экземпляра Broker.
+func New(settings Settings) *Broker {
+	bufferSize := settings.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	return &Broker{
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Метод запускающий сервис.
+// Блокирует поток исполнения до закрытия контекста ctx, после чего закрывает все подписки.
+func (b *Broker) Start(ctx context.Context) error {
+	slog.Info("Starting broker")
+	<-ctx.Done()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub, ErrClosed)
+	}
+
+	slog.Info("Broker stopped")
+	return nil
+}
+
+// Метод создания подписки на обновления метрик, для которых match возвращает true.
+// Если match равен nil, подписчик получает все обновления.
+// Функция match не должна публиковать обновления в шину.
+// Подписка должна быть закрыта методом Unsubscribe.
+// После остановки сервиса возвращает уже закрытую подписку.
+func (b *Broker) Subscribe(match func(view.Metric) bool) *Subscription {
+	sub := &Subscription{
+		updates: make(chan Event, b.bufferSize),
+		match:   match,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.updates)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Метод закрытия подписки. Повторный вызов не имеет эффекта.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		b.remove(sub, nil)
+	}
+}
+
+// Метод рассылки обновленных метрик подписчикам.
+func (b *Broker) Publish(metrics []view.Metric) {
+	b.publish(metrics, false)
+}
+
+// Метод рассылки подписчикам метрик, удаленных из хранилища.
+func (b *Broker) PublishDeleted(metrics []view.Metric) {
+	b.publish(metrics, true)
+}
+
+// Хелпер метод рассылки пакета метрик подписчикам.
+// Фильтры подписок вызываются без блокировки b.mu, поэтому медленный фильтр
+// не задерживает подписку и отписку.
+// Публикации сериализуются блокировкой b.publishMu: пакет, опубликованный позже,
+// не может обогнать предыдущий пакет во время его фильтрации.
+func (b *Broker) publish(metrics []view.Metric, deleted bool) {
+	if len(metrics) == 0 {
+		return
+	}
+
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	b.mu.Lock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	events := make(map[*Subscription]Event, len(subs))
+	for _, sub := range subs {
+		if selected := sub.filter(metrics); len(selected) > 0 {
+			events[sub] = Event{Metrics: selected, Deleted: deleted}
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub, event := range events {
+		// Подписка могла быть закрыта во время фильтрации
+		if _, ok := b.subs[sub]; !ok {
+			continue
+		}
+
+		select {
+		case sub.updates <- event:
+		default:
+			slog.Warn("slow subscriber disconnected")
+			b.remove(sub, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Хелпер метод удаления подписки. Вызывается под блокировкой b.mu.
+func (b *Broker) remove(sub *Subscription, err error) {
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.updates)
+}
+
+// Метод возвращает канал пакетов обновленных и удаленных метрик.
+// Канал закрывается при закрытии подписки, причину закрытия возвращает метод Err.
+func (s *Subscription) Updates() <-chan Event {
+	return s.updates
+}
+
+// Метод возвращает причину закрытия подписки: ErrSlowSubscriber, ErrClosed
+// или nil, если подписка закрыта методом Unsubscribe.
+// Значение определено только после закрытия канала Updates.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Хелпер метод выбора метрик, удовлетворяющих фильтру подписки.
+// Слайс metrics не изменяется, так как передается всем подписчикам.
+func (s *Subscription) filter(metrics []view.Metric) []view.Metric {
+	selected := make([]view.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if s.match == nil || s.match(metric) {
+			selected = append(selected, metric)
+		}
+	}
+	return selected
+}
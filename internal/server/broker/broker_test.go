@@ -0,0 +1,164 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gauge(id string) view.Metric {
+	value := 1.5
+	return view.Metric{ID: id, MType: view.KindGauge, Value: &value}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	b := New(Settings{})
+
+	all := b.Subscribe(nil)
+	defer b.Unsubscribe(all)
+	cpu := b.Subscribe(func(metric view.Metric) bool { return metric.ID == "cpu" })
+	defer b.Unsubscribe(cpu)
+
+	b.Publish([]view.Metric{gauge("cpu"), gauge("mem")})
+	b.Publish([]view.Metric{gauge("mem")})
+
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("cpu"), gauge("mem")}}, <-all.Updates())
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("mem")}}, <-all.Updates())
+
+	// Пакеты без подходящих метрик подписчику не отправляются
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("cpu")}}, <-cpu.Updates())
+	assert.Empty(t, cpu.Updates())
+}
+
+func TestBroker_PublishDeleted(t *testing.T) {
+	b := New(Settings{})
+
+	sub := b.Subscribe(func(metric view.Metric) bool { return metric.ID == "cpu" })
+	defer b.Unsubscribe(sub)
+
+	cpu := view.Metric{ID: "cpu", MType: view.KindGauge}
+	b.PublishDeleted([]view.Metric{cpu, {ID: "mem", MType: view.KindGauge}})
+
+	assert.Equal(t, Event{Metrics: []view.Metric{cpu}, Deleted: true}, <-sub.Updates())
+}
+
+func TestBroker_PublishFilterUnlocked(t *testing.T) {
+	b := New(Settings{})
+
+	// Фильтр вызывается без блокировки шины и может обращаться к ней
+	other := b.Subscribe(nil)
+	sub := b.Subscribe(func(view.Metric) bool {
+		b.Unsubscribe(other)
+		return true
+	})
+	defer b.Unsubscribe(sub)
+
+	b.Publish([]view.Metric{gauge("cpu")})
+
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("cpu")}}, <-sub.Updates())
+	// Подписка, закрытая во время фильтрации, не получает пакет
+	_, ok := <-other.Updates()
+	require.False(t, ok)
+	require.NoError(t, other.Err())
+}
+
+func TestBroker_PublishOrder(t *testing.T) {
+	b := New(Settings{})
+
+	filtering := make(chan struct{})
+	release := make(chan struct{})
+	sub := b.Subscribe(func(metric view.Metric) bool {
+		if metric.ID == "cpu" {
+			close(filtering)
+			<-release
+		}
+		return true
+	})
+	defer b.Unsubscribe(sub)
+
+	first := make(chan struct{})
+	go func() {
+		defer close(first)
+		b.Publish([]view.Metric{gauge("cpu")})
+	}()
+	<-filtering
+
+	// Пакет, опубликованный во время фильтрации предыдущего, не обгоняет его
+	second := make(chan struct{})
+	go func() {
+		defer close(second)
+		b.Publish([]view.Metric{gauge("mem")})
+	}()
+	select {
+	case <-second:
+		t.Fatal("second publish finished before the first one")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-first
+	<-second
+
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("cpu")}}, <-sub.Updates())
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("mem")}}, <-sub.Updates())
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	b := New(Settings{BufferSize: 1})
+
+	slow := b.Subscribe(nil)
+	b.Publish([]view.Metric{gauge("cpu")})
+	b.Publish([]view.Metric{gauge("mem")})
+
+	// Буфер заполнен, подписка закрывается после непрочитанного пакета
+	assert.Equal(t, Event{Metrics: []view.Metric{gauge("cpu")}}, <-slow.Updates())
+	_, ok := <-slow.Updates()
+	require.False(t, ok)
+	require.ErrorIs(t, slow.Err(), ErrSlowSubscriber)
+
+	// Повторное закрытие отключенной подписки не паникует
+	b.Unsubscribe(slow)
+}
+
+func TestBroker_Unsubscribe(t *testing.T) {
+	b := New(Settings{})
+
+	sub := b.Subscribe(nil)
+	b.Unsubscribe(sub)
+	b.Unsubscribe(sub)
+
+	_, ok := <-sub.Updates()
+	require.False(t, ok)
+	require.NoError(t, sub.Err())
+
+	// Публикация без подписчиков не блокируется
+	b.Publish([]view.Metric{gauge("cpu")})
+}
+
+func TestBroker_Start(t *testing.T) {
+	b := New(Settings{})
+	sub := b.Subscribe(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- b.Start(ctx)
+	}()
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// Подписки закрываются при остановке сервиса
+	_, ok := <-sub.Updates()
+	require.False(t, ok)
+	require.ErrorIs(t, sub.Err(), ErrClosed)
+
+	// Новые подписки создаются закрытыми
+	late := b.Subscribe(nil)
+	_, ok = <-late.Updates()
+	require.False(t, ok)
+	require.ErrorIs(t, late.Err(), ErrClosed)
+}
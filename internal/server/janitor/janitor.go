@@ -0,0 +1,82 @@
+// Пакет janitor содержит сервис удаления метрик, которые перестали обновляться.
+package janitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Интервал проверки по умолчанию.
+const defaultInterval = time.Minute
+
+// Интерфейс Storage описывает хранилище, из которого удаляются устаревшие метрики.
+type Storage interface {
+	// Удаляет метрики, не обновлявшиеся с момента before, вместе с их историей.
+	// Возвращает удаленные метрики без значений: тип, ID и метки.
+	// При ошибке возвращает только метрики, удаление которых уже применено, либо nil.
+	DeleteStaleMetrics(ctx context.Context, before time.Time) ([]view.Metric, error)
+}
+
+// Тип Settings используется для хранения настроек сервиса.
+type Settings struct {
+	Storage  Storage       // Хранилище метрик
+	TTL      time.Duration // Время, после которого не обновлявшаяся метрика удаляется
+	Interval time.Duration // Интервал между проверками. 0 - интервал по умолчанию
+}
+
+// Janitor - сервис, периодически удаляющий метрики, которые не обновлялись дольше TTL.
+// Экземпляр должен создаваться с помощью New.
+type Janitor struct {
+	storage  Storage
+	ttl      time.Duration
+	interval time.Duration
+}
+
+// Функция фабрика для создания нового экземпляра Janitor.
+func New(settings Settings) *Janitor {
+	interval := settings.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &Janitor{
+		storage:  settings.Storage,
+		ttl:      settings.TTL,
+		interval: interval,
+	}
+}
+
+// Метод запускающий сервис.
+// Блокирует поток исполнения до закрытия контекста ctx.
+func (j *Janitor) Start(ctx context.Context) error {
+	slog.Info("Starting janitor", slog.Duration("ttl", j.ttl), slog.Duration("interval", j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Janitor stopped")
+			return nil
+		case <-ticker.C:
+			j.cleanup(ctx)
+		}
+	}
+}
+
+// Метод удаления метрик, устаревших на момент вызова.
+// Ошибки хранилища логируются, следующая попытка выполняется на следующем тике.
+func (j *Janitor) cleanup(ctx context.Context) {
+	deleted, err := j.storage.DeleteStaleMetrics(ctx, time.Now().Add(-j.ttl))
+	if err != nil {
+		slog.Error("stale metrics cleanup error", slog.String("error", err.Error()))
+		return
+	}
+	if len(deleted) > 0 {
+		slog.Info("Stale metrics deleted", slog.Int("count", len(deleted)))
+	}
+}
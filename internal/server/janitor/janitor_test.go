@@ -0,0 +1,82 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStorage struct {
+	mu      sync.Mutex
+	calls   []time.Time
+	deleted []view.Metric
+	err     error
+}
+
+func (m *mockStorage) DeleteStaleMetrics(_ context.Context, before time.Time) ([]view.Metric, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, before)
+	return m.deleted, m.err
+}
+
+func (m *mockStorage) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.calls)
+}
+
+func TestJanitor_cleanup(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "deleted", err: nil},
+		{name: "storage error", err: errors.New("storage error")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &mockStorage{
+				deleted: []view.Metric{{ID: "cpu", MType: view.KindGauge}},
+				err:     tt.err,
+			}
+			j := New(Settings{Storage: storage, TTL: time.Hour})
+
+			start := time.Now()
+			j.cleanup(context.Background())
+
+			require.Len(t, storage.calls, 1)
+			// Удаляются метрики, не обновлявшиеся дольше TTL
+			assert.WithinDuration(t, start.Add(-time.Hour), storage.calls[0], time.Second)
+		})
+	}
+}
+
+func TestJanitor_Start(t *testing.T) {
+	storage := &mockStorage{}
+	j := New(Settings{Storage: storage, TTL: time.Minute, Interval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- j.Start(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return storage.callCount() >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestNew_defaultInterval(t *testing.T) {
+	j := New(Settings{Storage: &mockStorage{}, TTL: time.Minute})
+	assert.Equal(t, defaultInterval, j.interval)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/postgres"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/sqlite"
+)
+
+// Команды управления миграциями схемы БД.
+const (
+	MigrateUp     = "up"     // Применение всех не примененных миграций
+	MigrateDown   = "down"   // Откат последней примененной миграции
+	MigrateStatus = "status" // Вывод состояния миграций
+)
+
+// Ошибки выполнения команды управления миграциями.
+var (
+	ErrMigrateNoDatabase     = errors.New("database connection string is not set")
+	ErrMigrateUnknownCommand = errors.New("unknown migrate command. expected up, down or status")
+	ErrMigrateUnsupported    = errors.New("migrate command supports only postgres. sqlite schema is migrated on start")
+)
+
+// Функция выполнения команды управления миграциями схемы БД.
+// Подключается к БД по строке подключения из настроек и выполняет команду command.
+// Результат выполнения команды status записывается в out.
+func Migrate(ctx context.Context, settings Settings, command string, out io.Writer) error {
+	if settings.PGConnString == "" {
+		return ErrMigrateNoDatabase
+	}
+	if sqlite.IsDSN(settings.PGConnString) {
+		return ErrMigrateUnsupported
+	}
+
+	switch command {
+	case MigrateUp, MigrateDown, MigrateStatus:
+	default:
+		return ErrMigrateUnknownCommand
+	}
+
+	storage, err := postgres.New(&postgres.Settings{
+		ConnString:  settings.PGConnString,
+		PingTimeout: time.Duration(settings.StoragePingTimeout) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	switch command {
+	case MigrateUp:
+		return storage.MigrateUp(ctx)
+	case MigrateDown:
+		return storage.MigrateDown(ctx)
+	default:
+		statuses, err := storage.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if _, err = fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, state); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// publishingStorage - обертка над хранилищем, публикующая обновленные и удаленные метрики в шину обновлений.
+// Метрики публикуются только после успешной записи в хранилище.
+type publishingStorage struct {
+	IStorageService
+	broker *broker.Broker
+}
+
+// AddMetrics добавляет метрики в хранилище и публикует их обновленные значения.
+func (s *publishingStorage) AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	updated, err := s.IStorageService.AddMetrics(ctx, metrics...)
+	if err != nil {
+		return nil, err
+	}
+	s.broker.Publish(updated)
+	return updated, nil
+}
+
+// ResetCounter сбрасывает значение счетчика и публикует его обновленное значение.
+func (s *publishingStorage) ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error) {
+	metric, err := s.IStorageService.ResetCounter(ctx, name, labels)
+	if err != nil {
+		return view.Metric{}, err
+	}
+	s.broker.Publish([]view.Metric{metric})
+	return metric, nil
+}
+
+// DeleteMetrics удаляет метрики из хранилища и публикует их удаление.
+func (s *publishingStorage) DeleteMetrics(ctx context.Context, metrics ...view.Metric) error {
+	if err := s.IStorageService.DeleteMetrics(ctx, metrics...); err != nil {
+		return err
+	}
+
+	// Публикуются только идентичности метрик, переданные значения не совпадают с удаленными
+	deleted := make([]view.Metric, len(metrics))
+	for i, metric := range metrics {
+		deleted[i] = view.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels}
+	}
+	s.broker.PublishDeleted(deleted)
+	return nil
+}
+
+// DeleteStaleMetrics удаляет устаревшие метрики и публикует их удаление.
+// Метрики, удаленные до ошибки хранилища, также публикуются:
+// хранилище возвращает при ошибке только метрики, удаление которых уже применено.
+func (s *publishingStorage) DeleteStaleMetrics(ctx context.Context, before time.Time) ([]view.Metric, error) {
+	deleted, err := s.IStorageService.DeleteStaleMetrics(ctx, before)
+	s.broker.PublishDeleted(deleted)
+	return deleted, err
+}
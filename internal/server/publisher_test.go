@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Хранилище, реализующее только методы удаления.
+type deletingStorage struct {
+	IStorageService
+	stale []view.Metric
+	err   error
+}
+
+func (s *deletingStorage) DeleteMetrics(_ context.Context, _ ...view.Metric) error {
+	return s.err
+}
+
+func (s *deletingStorage) DeleteStaleMetrics(_ context.Context, _ time.Time) ([]view.Metric, error) {
+	return s.stale, s.err
+}
+
+func TestPublishingStorage_DeleteMetrics(t *testing.T) {
+	b := broker.New(broker.Settings{})
+	sub := b.Subscribe(nil)
+	defer b.Unsubscribe(sub)
+
+	raw := &deletingStorage{}
+	storage := &publishingStorage{IStorageService: raw, broker: b}
+
+	// Публикуются идентичности метрик без переданных значений
+	value := 1.5
+	require.NoError(t, storage.DeleteMetrics(context.Background(), view.Metric{
+		ID:     "cpu",
+		MType:  view.KindGauge,
+		Value:  &value,
+		Labels: view.Labels{"host": "web1"},
+	}))
+	assert.Equal(t, broker.Event{
+		Metrics: []view.Metric{{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "web1"}}},
+		Deleted: true,
+	}, <-sub.Updates())
+
+	// Ошибка хранилища не публикуется
+	raw.err = errors.New("storage error")
+	require.Error(t, storage.DeleteMetrics(context.Background(), view.Metric{ID: "cpu", MType: view.KindGauge}))
+	assert.Empty(t, sub.Updates())
+}
+
+func TestPublishingStorage_DeleteStaleMetrics(t *testing.T) {
+	b := broker.New(broker.Settings{})
+	sub := b.Subscribe(nil)
+	defer b.Unsubscribe(sub)
+
+	stale := []view.Metric{{ID: "requests", MType: view.KindCounter}}
+	storage := &publishingStorage{IStorageService: &deletingStorage{stale: stale}, broker: b}
+
+	deleted, err := storage.DeleteStaleMetrics(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, stale, deleted)
+	assert.Equal(t, broker.Event{Metrics: stale, Deleted: true}, <-sub.Updates())
+}
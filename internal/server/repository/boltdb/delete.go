@@ -0,0 +1,147 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Метод удаления метрик из хранилища вместе с их историей.
+// Метрики идентифицируются типом, ID и набором меток, значения метрик не учитываются.
+// Все метрики пакета удаляются одной транзакцией. Если хотя бы одна метрика не найдена,
+// хранилище не изменяется и возвращается repository.ErrNotFound.
+func (ms *MetricStorage) DeleteMetrics(ctx context.Context, metrics ...view.Metric) error {
+	if err := repository.ValidateLabels(metrics...); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return ms.db.Update(func(tx *bolt.Tx) error {
+		deleted := make(map[string]struct{}, len(metrics))
+		for i := range metrics {
+			// Повторяющиеся в пакете метрики удаляются один раз
+			key := metrics[i].Key()
+			if _, ok := deleted[metrics[i].MType+key]; ok {
+				continue
+			}
+
+			metric, err := getMetric(tx, []byte(key))
+			if err != nil {
+				return err
+			}
+			if metric.MType != metrics[i].MType {
+				return repository.ErrNotFound
+			}
+
+			if err = tx.Bucket(bucketMetrics).Delete([]byte(key)); err != nil {
+				return err
+			}
+			err = tx.Bucket(bucketHistory).DeleteBucket([]byte(key))
+			if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+			deleted[metrics[i].MType+key] = struct{}{}
+		}
+		return nil
+	})
+}
+
+// Метод сброса значения счетчика в ноль.
+// Нулевое значение записывается в историю счетчика.
+// Возвращает обновленную метрику или repository.ErrNotFound, если счетчик не найден.
+func (ms *MetricStorage) ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error) {
+	if err := labels.Validate(); err != nil {
+		return view.Metric{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return view.Metric{}, err
+	}
+
+	now := time.Now()
+
+	var metric view.Metric
+	err := ms.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(view.MetricKey(name, labels))
+
+		var err error
+		metric, err = getMetric(tx, key)
+		if err != nil {
+			return err
+		}
+		if metric.MType != view.KindCounter {
+			return repository.ErrNotFound
+		}
+
+		var delta int64
+		metric.Delta = &delta
+		metric.LastUpdated = &now
+		value, err := metric.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err = tx.Bucket(bucketMetrics).Put(key, value); err != nil {
+			return err
+		}
+		return ms.putSample(tx, metric, now)
+	})
+	if err != nil {
+		return view.Metric{}, err
+	}
+
+	return metric, nil
+}
+
+// Метод удаления метрик, не обновлявшихся с момента before, вместе с их историей.
+// Метрики, записанные без времени обновления, не удаляются.
+// Возвращает удаленные метрики без значений.
+func (ms *MetricStorage) DeleteStaleMetrics(ctx context.Context, before time.Time) ([]view.Metric, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var deleted []view.Metric
+	err := ms.db.Update(func(tx *bolt.Tx) error {
+		// Ключи собираются до удаления, так как изменять бакет во время обхода нельзя
+		stale := make([][]byte, 0)
+		deleted = make([]view.Metric, 0)
+		err := tx.Bucket(bucketMetrics).ForEach(func(key, value []byte) error {
+			var metric view.Metric
+			if err := metric.UnmarshalJSON(value); err != nil {
+				return err
+			}
+			if metric.LastUpdated != nil && metric.LastUpdated.Before(before) {
+				stale = append(stale, bytes.Clone(key))
+				deleted = append(deleted, view.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range stale {
+			if err = tx.Bucket(bucketMetrics).Delete(key); err != nil {
+				return err
+			}
+			err = tx.Bucket(bucketHistory).DeleteBucket(key)
+			if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,147 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Длина ключа сэмпла истории: время в наносекундах в big-endian,
+// чтобы сэмплы в бакете были упорядочены по времени.
+const sampleKeyLen = 8
+
+// Метод получения истории значений метрики.
+// Возвращает значения метрики за интервал [query.From, query.To] в порядке возрастания времени.
+// Если query.Step больше нуля, то значения прореживаются.
+func (ms *MetricStorage) ReadHistory(ctx context.Context, query view.HistoryQuery) (view.Samples, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Устаревшие сэмплы удаляются периодически, до удаления они только пропускаются
+	from := query.From
+	if ms.historyRetention > 0 {
+		if cutoff := time.Now().Add(-ms.historyRetention); cutoff.After(from) {
+			from = cutoff
+		}
+	}
+
+	samples := make(view.Samples, 0)
+	err := ms.db.View(func(tx *bolt.Tx) error {
+		key := []byte(view.MetricKey(query.Name, query.Labels))
+
+		metric, err := getMetric(tx, key)
+		if err != nil {
+			return err
+		}
+		if metric.MType != query.Kind {
+			return repository.ErrNotFound
+		}
+
+		history := tx.Bucket(bucketHistory).Bucket(key)
+		if history == nil {
+			return nil
+		}
+
+		to := sampleKey(query.To)
+		cursor := history.Cursor()
+		for k, v := cursor.Seek(sampleKey(from)); k != nil && bytes.Compare(k, to) <= 0; k, v = cursor.Next() {
+			samples = append(samples, decodeSample(k, v))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Step > 0 {
+		return view.Downsample(samples, query.Step, query.Aggregation)
+	}
+	return samples, nil
+}
+
+// Хелпер метод для записи обновленного значения метрики в историю.
+// Кол-во сэмплов в бакете хранится в его последовательности,
+// самые старые сэмплы сверх ms.historyDepth удаляются.
+func (ms *MetricStorage) putSample(tx *bolt.Tx, metric view.Metric, ts time.Time) error {
+	if ms.historyDepth <= 0 {
+		return nil
+	}
+
+	value, ok := metric.SampleValue()
+	if !ok {
+		return nil
+	}
+
+	history, err := tx.Bucket(bucketHistory).CreateBucketIfNotExists([]byte(metric.Key()))
+	if err != nil {
+		return err
+	}
+
+	key := sampleKey(ts)
+	count := history.Sequence()
+	if history.Get(key) == nil {
+		count++
+	}
+	if err = history.Put(key, encodeSampleValue(value)); err != nil {
+		return err
+	}
+
+	// Вытеснение самых старых сэмплов
+	cursor := history.Cursor()
+	for k, _ := cursor.First(); k != nil && count > uint64(ms.historyDepth); k, _ = cursor.First() {
+		if err = cursor.Delete(); err != nil {
+			return err
+		}
+		count--
+	}
+
+	return history.SetSequence(count)
+}
+
+// Метод удаления значений истории, записанных раньше before.
+func (ms *MetricStorage) deleteOldSamples(before time.Time) error {
+	cutoff := sampleKey(before)
+	return ms.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHistory).ForEachBucket(func(name []byte) error {
+			history := tx.Bucket(bucketHistory).Bucket(name)
+			count := history.Sequence()
+
+			cursor := history.Cursor()
+			for k, _ := cursor.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = cursor.First() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				count--
+			}
+			return history.SetSequence(count)
+		})
+	})
+}
+
+// Функция формирования ключа сэмпла по времени.
+// Время до unix-эпохи приводится к нулю, чтобы сохранить порядок ключей.
+func sampleKey(ts time.Time) []byte {
+	key := make([]byte, sampleKeyLen)
+	binary.BigEndian.PutUint64(key, uint64(max(ts.UnixNano(), 0)))
+	return key
+}
+
+// Функция кодирования значения сэмпла.
+func encodeSampleValue(value float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(value))
+}
+
+// Функция декодирования сэмпла из ключа и значения.
+func decodeSample(key, value []byte) view.Sample {
+	return view.Sample{
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(key))),
+		Value:     math.Float64frombits(binary.BigEndian.Uint64(value)),
+	}
+}
@@ -0,0 +1,44 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Метод получения страницы списка метрик.
+// Ключи метрик начинаются с экранированного имени метрики, поэтому при фильтрации по префиксу
+// просматриваются только ключи, начинающиеся с экранированного префикса.
+// Возвращает ошибку, оборачивающую view.ErrInvalidListQuery, если параметры запроса некорректны.
+func (ms *MetricStorage) ListMetrics(ctx context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	if err := ctx.Err(); err != nil {
+		return view.MetricsPage{}, err
+	}
+	if err := query.Normalize(); err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	match := query.Matcher()
+	prefix := []byte(view.MetricKey(query.Prefix, nil))
+	metrics := make([]view.Metric, 0)
+	err := ms.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketMetrics).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			var metric view.Metric
+			if err := metric.UnmarshalJSON(value); err != nil {
+				return err
+			}
+			if match(metric) {
+				metrics = append(metrics, metric)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	return query.SortPage(metrics)
+}
@@ -0,0 +1,271 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Интервал между проверками истории на устаревшие значения.
+const retentionCheckInterval = time.Minute
+
+// Время ожидания блокировки файла хранилища по умолчанию.
+const defaultOpenTimeout = time.Second
+
+//nolint:gochecknoglobals // bbolt принимает имена бакетов только в виде []byte
+var (
+	bucketMetrics = []byte("metrics") // Последние значения метрик по ключу view.Metric.Key
+	bucketHistory = []byte("history") // Вложенные бакеты истории значений по ключу метрики
+)
+
+// Тип Settings используется для хранения настроек хранилища метрик.
+type Settings struct {
+	Path             string        // Путь к файлу хранилища
+	HistoryDepth     int           // Максимальное кол-во хранимых значений каждой метрики. 0 - история не хранится
+	HistoryRetention time.Duration // Время хранения истории значений. 0 - история хранится бессрочно
+	OpenTimeout      time.Duration // Время ожидания блокировки файла хранилища другим процессом
+	NoSync           bool          // Не синхронизировать файл с диском после каждой транзакции
+}
+
+// Реализация хранилища метрик во встраиваемой key-value БД bbolt.
+// Хранилище рассчитано на развертывание на одном узле без отдельного сервера БД.
+// Каждый пакет метрик записывается одной транзакцией и сохраняется на диск до возврата из AddMetrics.
+// Экземпляр должен создаваться с помощью New.
+type MetricStorage struct {
+	db               *bolt.DB
+	historyDepth     int
+	historyRetention time.Duration
+}
+
+// Функция фабрика для создания нового экземпляра MetricStorage.
+// Открывает или создает файл хранилища.
+// В случае ошибки возвращает nil и ошибку.
+func New(settings *Settings) (*MetricStorage, error) {
+	timeout := settings.OpenTimeout
+	if timeout <= 0 {
+		timeout = defaultOpenTimeout
+	}
+
+	db, err := bolt.Open(settings.Path, 0666, &bolt.Options{
+		Timeout: timeout,
+		NoSync:  settings.NoSync,
+	})
+	if err != nil {
+		slog.Error("error opening storage file", "error", err)
+		return nil, err
+	}
+
+	// Создание бакетов
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, bucketErr := tx.CreateBucketIfNotExists(bucketMetrics); bucketErr != nil {
+			return bucketErr
+		}
+		_, bucketErr := tx.CreateBucketIfNotExists(bucketHistory)
+		return bucketErr
+	})
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return &MetricStorage{
+		db:               db,
+		historyDepth:     settings.HistoryDepth,
+		historyRetention: settings.HistoryRetention,
+	}, nil
+}
+
+// Метод запускающий сервис хранилища.
+// Блокирует поток исполнения до закрытия контекста ctx, после чего закрывает файл хранилища.
+// Если задано время хранения истории, периодически удаляет устаревшие значения.
+func (ms *MetricStorage) Start(ctx context.Context) error {
+	// Без времени хранения истории канал остается nil и никогда не срабатывает
+	var cleanup <-chan time.Time
+	if ms.historyRetention > 0 {
+		ticker := time.NewTicker(retentionCheckInterval)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+
+	for {
+		select {
+		// Ожидание завершения контекста
+		case <-ctx.Done():
+			return ms.Close()
+		case <-cleanup:
+			if err := ms.deleteOldSamples(time.Now().Add(-ms.historyRetention)); err != nil {
+				slog.Error("history cleanup error", "error", err)
+			}
+		}
+	}
+}
+
+// Метод закрытия файла хранилища.
+// Используется, если хранилище создано без запуска сервиса методом Start.
+func (ms *MetricStorage) Close() error {
+	return ms.db.Close()
+}
+
+// Метод добавления метрик в хранилище.
+// Все метрики пакета записываются одной транзакцией:
+// при ошибке в любой из них хранилище не изменяется.
+// Возвращает обновленные метрики.
+func (ms *MetricStorage) AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	if err := repository.ValidateMetrics(metrics...); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]view.Metric, 0, len(metrics))
+
+	err := ms.db.Update(func(tx *bolt.Tx) error {
+		result = result[:0]
+		for i := range metrics {
+			metric, err := putMetric(tx, metrics[i], now)
+			if err != nil {
+				return err
+			}
+			if err = ms.putSample(tx, metric, now); err != nil {
+				return err
+			}
+			result = append(result, metric)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Метод получения метрики из хранилища.
+// Метрика ищется по имени name и набору меток labels.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = name другой тип.
+func (ms *MetricStorage) GetMetric(
+	ctx context.Context,
+	kind string,
+	name string,
+	labels view.Labels,
+) (view.Metric, error) {
+	if err := ctx.Err(); err != nil {
+		return view.Metric{}, err
+	}
+
+	var metric view.Metric
+	err := ms.db.View(func(tx *bolt.Tx) error {
+		var err error
+		metric, err = getMetric(tx, []byte(view.MetricKey(name, labels)))
+		if err != nil {
+			return err
+		}
+		if metric.MType != kind {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return view.Metric{}, err
+	}
+
+	return metric, nil
+}
+
+// Возвращает слайс всех хранящихся метрик.
+func (ms *MetricStorage) ReadAllMetrics(ctx context.Context) ([]view.Metric, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]view.Metric, 0)
+	err := ms.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketMetrics).ForEach(func(_, value []byte) error {
+			var metric view.Metric
+			if err := metric.UnmarshalJSON(value); err != nil {
+				return err
+			}
+			metrics = append(metrics, metric)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metrics, nil
+}
+
+// Метод проверки доступности хранилища.
+// Возвращает ошибку, если файл хранилища закрыт.
+func (ms *MetricStorage) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ms.db.View(func(_ *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Хелпер функция для чтения метрики по ключу.
+func getMetric(tx *bolt.Tx, key []byte) (view.Metric, error) {
+	var metric view.Metric
+
+	value := tx.Bucket(bucketMetrics).Get(key)
+	if value == nil {
+		return metric, repository.ErrNotFound
+	}
+	err := metric.UnmarshalJSON(value)
+	return metric, err
+}
+
+// Хелпер функция для записи метрики.
+// Значение gauge заменяется, counter суммируется с хранящимся, гистограммы объединяются.
+// Возвращает обновленную метрику.
+func putMetric(tx *bolt.Tx, metric view.Metric, now time.Time) (view.Metric, error) {
+	switch metric.MType {
+	case view.KindGauge, view.KindCounter:
+	case view.KindHistogram:
+		if metric.Histogram == nil {
+			return metric, view.ErrInvalidHistogram
+		}
+		if err := metric.Histogram.Validate(); err != nil {
+			return metric, err
+		}
+	default:
+		return metric, repository.ErrWrongType
+	}
+
+	key := []byte(metric.Key())
+	oldMetric, err := getMetric(tx, key)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return metric, err
+	case oldMetric.MType != metric.MType:
+		return metric, repository.ErrWrongType
+	case metric.MType == view.KindCounter:
+		delta := *oldMetric.Delta + *metric.Delta
+		metric.Delta = &delta
+	case metric.MType == view.KindHistogram:
+		histogram, mergeErr := oldMetric.Histogram.Merge(metric.Histogram)
+		if mergeErr != nil {
+			return metric, mergeErr
+		}
+		metric.Histogram = histogram
+	}
+
+	metric.LastUpdated = &now
+	value, err := metric.MarshalJSON()
+	if err != nil {
+		return metric, err
+	}
+	return metric, tx.Bucket(bucketMetrics).Put(key, value)
+}
@@ -0,0 +1,180 @@
+package boltdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Хелпер для создания хранилища во временном каталоге.
+func newTestStorage(t *testing.T, settings Settings) *MetricStorage {
+	t.Helper()
+	if settings.Path == "" {
+		settings.Path = filepath.Join(t.TempDir(), "metrics.db")
+	}
+	ms, err := New(&settings)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ms.Close()
+	})
+	return ms
+}
+
+func gauge(name string, value float64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindGauge, Value: &value}
+}
+
+func counter(name string, delta int64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindCounter, Delta: &delta}
+}
+
+func TestMetricStorage_AddMetrics(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{})
+
+	result, err := ms.AddMetrics(ctx, gauge("gauge", 1.5), counter("counter", 2), counter("counter", 3))
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, int64(5), *result[2].Delta)
+
+	metric, err := ms.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, *metric.Value, 0)
+
+	metric, err = ms.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *metric.Delta)
+
+	_, err = ms.GetMetric(ctx, view.KindCounter, "gauge", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = ms.GetMetric(ctx, view.KindGauge, "unknown", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	metrics, err := ms.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+}
+
+func TestMetricStorage_AddMetricsLabels(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{})
+
+	first := counter("requests", 1)
+	first.Labels = view.Labels{"host": "a"}
+	second := counter("requests", 10)
+	second.Labels = view.Labels{"host": "b"}
+
+	_, err := ms.AddMetrics(ctx, first, second)
+	require.NoError(t, err)
+
+	metric, err := ms.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *metric.Delta)
+
+	metric, err = ms.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *metric.Delta)
+}
+
+func TestMetricStorage_AddMetricsTransactional(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{HistoryDepth: 10})
+
+	_, err := ms.AddMetrics(ctx, counter("counter", 1))
+	require.NoError(t, err)
+
+	// Ошибка в последней метрике пакета отменяет весь пакет
+	_, err = ms.AddMetrics(ctx, counter("counter", 5), gauge("gauge", 1), gauge("counter", 1))
+	require.ErrorIs(t, err, repository.ErrWrongType)
+
+	metric, err := ms.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *metric.Delta)
+
+	_, err = ms.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	samples, err := ms.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindCounter,
+		Name: "counter",
+		To:   time.Now(),
+	})
+	require.NoError(t, err)
+	assert.Len(t, samples, 1)
+}
+
+func TestMetricStorage_Persistence(t *testing.T) {
+	ctx := context.Background()
+	settings := Settings{
+		Path:         filepath.Join(t.TempDir(), "metrics.db"),
+		HistoryDepth: 10,
+	}
+
+	ms, err := New(&settings)
+	require.NoError(t, err)
+	_, err = ms.AddMetrics(ctx, counter("counter", 7))
+	require.NoError(t, err)
+	require.NoError(t, ms.Close())
+
+	reopened := newTestStorage(t, settings)
+	metric, err := reopened.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *metric.Delta)
+}
+
+func TestMetricStorage_ReadHistory(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{HistoryDepth: 3})
+
+	for i := 1; i <= 5; i++ {
+		_, err := ms.AddMetrics(ctx, gauge("gauge", float64(i)))
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	// Хранятся только последние HistoryDepth значений
+	samples, err := ms.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindGauge,
+		Name: "gauge",
+		To:   time.Now(),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	for i, sample := range samples {
+		assert.InDelta(t, float64(i+3), sample.Value, 0)
+	}
+
+	// Удаление устаревших значений
+	require.NoError(t, ms.deleteOldSamples(samples[2].Timestamp))
+	samples, err = ms.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindGauge,
+		Name: "gauge",
+		To:   time.Now(),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 5.0, samples[0].Value, 0)
+
+	_, err = ms.ReadHistory(ctx, view.HistoryQuery{Kind: view.KindCounter, Name: "gauge", To: time.Now()})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestMetricStorage_Start(t *testing.T) {
+	ms, err := New(&Settings{Path: filepath.Join(t.TempDir(), "metrics.db")})
+	require.NoError(t, err)
+	require.NoError(t, ms.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, ms.Start(ctx))
+
+	// После остановки сервиса файл хранилища закрыт
+	require.Error(t, ms.Ping(context.Background()))
+}
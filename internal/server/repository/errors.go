@@ -0,0 +1,18 @@
+// Пакет repository объединяет реализации хранилища метрик сервера.
+// Все реализации возвращают общие ошибки пакета, чтобы обработчики запросов
+// одинаково преобразовывали их в коды ответа независимо от выбранного хранилища.
+package repository
+
+import "errors"
+
+var (
+	// Метрика с заданными типом, именем и метками не найдена.
+	// Метрика с тем же именем и метками, но другого типа, при чтении так же считается не найденной.
+	ErrNotFound = errors.New("metric not found")
+	// Метрика не может быть записана, так как её тип неизвестен
+	// или уже хранится метрика другого типа с той же идентичностью.
+	ErrWrongType = errors.New("wrong metric type")
+	// Хранилище временно недоступно: временная ошибка не устранена после всех повторных попыток.
+	// Операцию можно повторить позже.
+	ErrUnavailable = errors.New("storage temporarily unavailable")
+)
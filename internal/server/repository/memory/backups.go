@@ -11,11 +11,20 @@ import (
 func (ms *MetricStorage) Start(ctx context.Context) error {
 	slog.Debug("Start backup service")
 	defer slog.Debug("Backup service successfully stopped")
-	ticker := &time.Ticker{
-		C: make(<-chan time.Time),
-	}
+	// Отключенные тикеры заменяются nil каналами, которые никогда не срабатывают
+	var backupTick <-chan time.Time
 	if ms.storeInterval != 0 {
-		ticker = time.NewTicker(time.Duration(ms.storeInterval) * time.Second)
+		ticker := time.NewTicker(time.Duration(ms.storeInterval) * time.Second)
+		defer ticker.Stop()
+		backupTick = ticker.C
+	}
+
+	// Тикер синхронизации журнала упреждающей записи с диском
+	var walTick <-chan time.Time
+	if ms.wal != nil && ms.wal.policy == WALSyncInterval && ms.walSyncInterval > 0 {
+		walTicker := time.NewTicker(ms.walSyncInterval)
+		defer walTicker.Stop()
+		walTick = walTicker.C
 	}
 
 	var wg sync.WaitGroup
@@ -24,15 +33,21 @@ func (ms *MetricStorage) Start(ctx context.Context) error {
 		select {
 		// Grasefull Shutdown
 		case <-ctx.Done():
-			ticker.Stop()
 			if ms.awaiting.Load() {
 				ms.cond.Broadcast()
 			} else {
 				ms.backup(true)
 			}
 			wg.Wait()
+			if ms.wal != nil {
+				return ms.wal.close()
+			}
 			return nil
-		case <-ticker.C:
+		case <-walTick:
+			if err := ms.wal.sync(); err != nil {
+				slog.Error("WAL sync error", "error", err)
+			}
+		case <-backupTick:
 			if !ms.awaiting.Load() {
 				ms.awaiting.Store(true)
 				wg.Add(1)
@@ -58,17 +73,15 @@ func (ms *MetricStorage) Start(ctx context.Context) error {
 
 // Метод сохраняющий метрики на диск.
 func (ms *MetricStorage) backup(skipWait bool) {
-	ms.cond.L.Lock()
-	defer ms.cond.L.Unlock()
-
 	if !skipWait {
+		ms.cond.L.Lock()
 		ms.cond.Wait()
+		ms.cond.L.Unlock()
 	}
 
 	slog.Debug("Creating backup", slog.String("destination", ms.fileStoragePath))
 
-	err := ms.saveToFile()
-	if err != nil {
+	if err := ms.saveToFile(); err != nil {
 		slog.Error("backup error", "error", err)
 	}
 
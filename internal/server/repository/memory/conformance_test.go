@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) api.MetricsStorage {
+		ms, err := New(&Settings{
+			FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+			HistoryDepth:    10,
+		})
+		require.NoError(t, err)
+		return ms
+	})
+}
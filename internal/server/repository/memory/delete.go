@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Метод удаления метрик из хранилища вместе с их историей.
+// Метрики идентифицируются типом, ID и набором меток, значения метрик не учитываются.
+// Если хотя бы одна метрика не найдена, хранилище не изменяется и возвращается repository.ErrNotFound.
+func (ms *MetricStorage) DeleteMetrics(_ context.Context, metrics ...view.Metric) error {
+	if err := repository.ValidateLabels(metrics...); err != nil {
+		return err
+	}
+
+	defer ms.notifyBackup()
+
+	ms.snapshotMu.RLock()
+	defer ms.snapshotMu.RUnlock()
+
+	unlock := ms.lockShards(metrics)
+	defer unlock()
+
+	// Проверка наличия всех метрик до изменения хранилища
+	if err := ms.checkMetrics(metrics); err != nil {
+		return err
+	}
+
+	// Запись удаления в журнал до применения
+	if ms.wal != nil {
+		if err := ms.wal.append(time.Now(), walOpDelete, metrics); err != nil {
+			slog.Error("WAL write error", "error", err)
+			return err
+		}
+	}
+
+	ms.deleteMetrics(metrics)
+	return nil
+}
+
+// Метод сброса значения счетчика в ноль.
+// Нулевое значение записывается в историю счетчика.
+// Возвращает обновленную метрику или repository.ErrNotFound, если счетчик не найден.
+func (ms *MetricStorage) ResetCounter(_ context.Context, name string, labels view.Labels) (view.Metric, error) {
+	if err := labels.Validate(); err != nil {
+		return view.Metric{}, err
+	}
+
+	defer ms.notifyBackup()
+
+	metrics := []view.Metric{{ID: name, MType: view.KindCounter, Labels: labels}}
+
+	ms.snapshotMu.RLock()
+	defer ms.snapshotMu.RUnlock()
+
+	unlock := ms.lockShards(metrics)
+	defer unlock()
+
+	if err := ms.checkMetrics(metrics); err != nil {
+		return view.Metric{}, err
+	}
+
+	now := time.Now()
+
+	// Запись сброса в журнал до применения
+	if ms.wal != nil {
+		if err := ms.wal.append(now, walOpReset, metrics); err != nil {
+			slog.Error("WAL write error", "error", err)
+			return view.Metric{}, err
+		}
+	}
+
+	return ms.resetCounters(now, metrics)[0], nil
+}
+
+// Хелпер метод для проверки, что все метрики пакета хранятся с тем же типом.
+// Должен вызываться с заблокированными сегментами метрик пакета.
+func (ms *MetricStorage) checkMetrics(metrics []view.Metric) error {
+	for i := range metrics {
+		metric, ok := ms.shardFor(metrics[i].ID).metrics[metrics[i].Key()]
+		if !ok || metric.MType != metrics[i].MType {
+			return repository.ErrNotFound
+		}
+	}
+	return nil
+}
+
+// Хелпер метод для удаления метрик и их истории.
+// Должен вызываться с заблокированными на запись сегментами метрик пакета.
+func (ms *MetricStorage) deleteMetrics(metrics []view.Metric) {
+	for i := range metrics {
+		s := ms.shardFor(metrics[i].ID)
+		delete(s.metrics, metrics[i].Key())
+		delete(s.history, metrics[i].Key())
+	}
+}
+
+// Хелпер метод для сброса счетчиков в ноль.
+// Должен вызываться с заблокированными на запись сегментами метрик пакета.
+// Возвращает обновленные счетчики. Отсутствующие счетчики пропускаются.
+func (ms *MetricStorage) resetCounters(now time.Time, metrics []view.Metric) []view.Metric {
+	result := make([]view.Metric, 0, len(metrics))
+	for i := range metrics {
+		s := ms.shardFor(metrics[i].ID)
+		metric, ok := s.metrics[metrics[i].Key()]
+		if !ok || metric.MType != view.KindCounter {
+			continue
+		}
+
+		var delta int64
+		metric.Delta = &delta
+		metric.LastUpdated = &now
+		s.metrics[metric.Key()] = metric
+		s.addSample(metric, now)
+
+		result = append(result, metric)
+	}
+	return result
+}
+
+// Метод удаления метрик, не обновлявшихся с момента before, вместе с их историей.
+// Сегменты обрабатываются по очереди, удаление каждого сегмента записывается в журнал отдельно.
+// Возвращает удаленные метрики без значений.
+// При ошибке возвращает метрики, удаленные из уже обработанных сегментов.
+func (ms *MetricStorage) DeleteStaleMetrics(_ context.Context, before time.Time) ([]view.Metric, error) {
+	defer ms.notifyBackup()
+
+	ms.snapshotMu.RLock()
+	defer ms.snapshotMu.RUnlock()
+
+	deleted := make([]view.Metric, 0)
+	for _, s := range ms.shards {
+		stale, err := ms.deleteStaleShard(s, before)
+		deleted = append(deleted, stale...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Хелпер метод для удаления устаревших метрик одного сегмента.
+// Возвращает удаленные метрики без значений.
+func (ms *MetricStorage) deleteStaleShard(s *shard, before time.Time) ([]view.Metric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := make([]view.Metric, 0)
+	for _, metric := range s.metrics {
+		if metric.LastUpdated != nil && metric.LastUpdated.Before(before) {
+			stale = append(stale, view.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels})
+		}
+	}
+	if len(stale) == 0 {
+		return nil, nil
+	}
+
+	// Запись удаления в журнал до применения
+	if ms.wal != nil {
+		if err := ms.wal.append(time.Now(), walOpDelete, stale); err != nil {
+			slog.Error("WAL write error", "error", err)
+			return nil, err
+		}
+	}
+
+	ms.deleteMetrics(stale)
+	return stale, nil
+}
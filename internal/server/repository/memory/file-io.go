@@ -2,79 +2,343 @@ package memory
 
 import (
 	"bufio"
-	"io"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
 )
 
+//go:generate easyjson file-io.go
+
+// Версия формата файла бекапа.
+// Версия 1 - заголовок содержит только номер записи журнала, контрольная сумма отсутствует.
+// Версия 2 - заголовок содержит время создания, кол-во метрик и контрольную сумму.
+// Версия 3 - заголовок содержит формат и алгоритм сжатия метрик.
+const snapshotVersion = 3
+
+// Максимальный размер строки файла бекапа.
+// Строка метрики содержит историю её значений и может превышать размер буфера сканера по умолчанию.
+const maxSnapshotLine = 16 * 1024 * 1024
+
+// Суффикс имени временного файла бекапа.
+const snapshotTmpSuffix = ".tmp"
+
+var (
+	errEmptySnapshot    = errors.New("backup file is empty")
+	errSnapshotChecksum = errors.New("backup checksum mismatch")
+	errSnapshotCount    = errors.New("backup metrics count mismatch")
+	errNoValidSnapshot  = errors.New("no valid backup found")
+)
+
+// Тип snapshotHeader - первая строка файла бекапа.
+// Файлы бекапа, созданные до появления заголовка, начинаются сразу со строки метрики.
+//
+//easyjson:json
+type snapshotHeader struct {
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	WALSeq    uint64    `json:"wal_seq"`  // Номер последней записи журнала, вошедшей в бекап
+	Count     int       `json:"count"`    // Кол-во метрик в бекапе
+	Checksum  string    `json:"checksum"` // SHA-256 тела бекапа после сжатия в hex
+	// Формат метрик. Пустое значение - json
+	Format string `json:"format,omitempty"`
+	// Алгоритм сжатия тела бекапа. Пустое значение - без сжатия
+	Compression string `json:"compression,omitempty"`
+}
+
+// Тип backupRecord - строка файла бекапа.
+// Содержит метрику и историю её значений.
+//
+//easyjson:json
+type backupRecord struct {
+	view.Metric
+	History view.Samples `json:"history,omitempty"`
+}
+
 // Метод загружающий метрики в хранилище из файла.
-func (ms *MetricStorage) loadFromFile() error {
-	slog.Debug("Loading backup", slog.String("source", ms.fileStoragePath))
-	// Открытие файла для чтения
-	file, err := os.OpenFile(ms.fileStoragePath, os.O_RDONLY, 0666)
+// Если текущий бекап поврежден, загружается самый новый целый из предыдущих.
+// Возвращает номер последней записи журнала упреждающей записи, вошедшей в бекап.
+func (ms *MetricStorage) loadFromFile() (uint64, error) {
+	for i, path := range ms.snapshotPaths() {
+		slog.Debug("Loading backup", slog.String("source", path))
+
+		header, records, err := readSnapshot(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			slog.Error("skipping invalid backup", slog.String("source", path), slog.String("error", err.Error()))
+			continue
+		}
+		if i > 0 {
+			slog.Warn(
+				"restored from previous backup, changes after it are lost",
+				slog.String("source", path),
+				slog.Time("timestamp", header.Timestamp),
+			)
+		}
+
+		// Метрики бекапов предыдущих версий не имеют времени обновления
+		// и считаются обновленными в момент записи бекапа
+		restored := header.Timestamp
+		if restored.IsZero() {
+			restored = time.Now()
+		}
+
+		for _, record := range records {
+			if record.LastUpdated == nil {
+				record.LastUpdated = &restored
+			}
+
+			// Сохранение метрики в буфер
+			key := record.Key()
+			s := ms.shardFor(record.ID)
+			s.metrics[key] = record.Metric
+
+			// Восстановление истории значений метрики
+			if s.historyDepth > 0 && len(record.History) > 0 {
+				history := newRing(s.historyDepth)
+				for _, sample := range record.History {
+					history.push(sample)
+				}
+				s.history[key] = history
+			}
+		}
+		return header.WALSeq, nil
+	}
+	return 0, errNoValidSnapshot
+}
+
+// Функция чтения и проверки файла бекапа.
+// Формат и сжатие метрик определяются по заголовку, бекапы без заголовка читаются как json.
+// Для бекапов с контрольной суммой проверяются контрольная сумма и кол-во метрик.
+func readSnapshot(path string) (snapshotHeader, []backupRecord, error) {
+	var header snapshotHeader
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return header, nil, err
+	}
+	if len(data) == 0 {
+		return header, nil, errEmptySnapshot
 	}
-	defer file.Close()
 
-	// Создание сканера
-	scanner := bufio.NewScanner(file)
+	// Чтение заголовка
+	body := data
+	if first, rest, ok := bytes.Cut(data, []byte{'\n'}); ok {
+		if err = header.UnmarshalJSON(first); err == nil && header.Version > 0 {
+			body = rest
+		} else {
+			header = snapshotHeader{}
+		}
+	}
 
-	// Проход по всем строкам файла
-	for {
-		if !scanner.Scan() {
-			return scanner.Err()
+	// Проверка контрольной суммы
+	if header.Version >= 2 {
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != header.Checksum {
+			return header, nil, errSnapshotChecksum
 		}
+	}
 
-		// Чтение строки файла
-		data := scanner.Bytes()
+	// Распаковка и декодирование метрик
+	body, err = decompressSnapshot(header.Compression, body)
+	if err != nil {
+		return header, nil, err
+	}
+	records, err := decodeSnapshot(header.Format, body, header.Count)
+	if err != nil {
+		return header, nil, err
+	}
 
-		// Анмаршалинг строки
-		metric := view.Metric{}
-		err = metric.UnmarshalJSON(data)
-		if err != nil {
-			return err
-		}
+	if header.Version >= 2 && len(records) != header.Count {
+		return header, nil, errSnapshotCount
+	}
+
+	return header, records, nil
+}
+
+// Тип snapshot - согласованный снимок хранилища для записи бекапа.
+type snapshot struct {
+	records []backupRecord
+	walSeq  uint64 // Номер последней записи журнала, вошедшей в снимок
+	walSize int64  // Размер журнала на момент снимка
+}
+
+// Метод создания снимка хранилища.
+// Запись метрик блокируется только на время копирования, но не на время записи бекапа.
+func (ms *MetricStorage) takeSnapshot() snapshot {
+	ms.snapshotMu.Lock()
+	defer ms.snapshotMu.Unlock()
 
-		// Сохранение метрики в буфер
-		ms.metrics[metric.ID] = metric
+	var snap snapshot
+	for _, s := range ms.shards {
+		s.mu.RLock()
+		for key, metric := range s.metrics {
+			record := backupRecord{Metric: metric}
+			if history, ok := s.history[key]; ok {
+				record.History = history.all()
+			}
+			snap.records = append(snap.records, record)
+		}
+		s.mu.RUnlock()
+	}
+	if ms.wal != nil {
+		snap.walSeq, snap.walSize = ms.wal.position()
 	}
+	return snap
 }
 
 // Метод записывающий метрики в файл.
+// Бекап записывается во временный файл, синхронизируется с диском и атомарно
+// заменяет текущий. Предыдущие бекапы сохраняются в файлах с суффиксами .1, .2 и т.д.
+// После записи бекапа из журнала упреждающей записи удаляются вошедшие в него записи.
 func (ms *MetricStorage) saveToFile() error {
-	// Очистка файла
-	err := ms.file.Truncate(0)
+	ms.backupMu.Lock()
+	defer ms.backupMu.Unlock()
+
+	snap := ms.takeSnapshot()
+
+	// Маршалинг и сжатие метрик
+	body, err := encodeSnapshot(ms.snapshotFormat, snap.records)
 	if err != nil {
+		slog.Error("marshaling error", "error", err)
 		return err
 	}
-	_, err = ms.file.Seek(0, io.SeekStart)
+	body, err = compressSnapshot(ms.snapshotCompression, body)
 	if err != nil {
+		slog.Error("compression error", "error", err)
 		return err
 	}
-	// Проход по всем метрикам
-	for _, metric := range ms.metrics {
-		// Маршалинг метрики в JSON
-		var bmetric []byte
-		bmetric, err = metric.MarshalJSON()
-		if err != nil {
-			slog.Error("marshaling error", "error", err)
-			return err
+
+	// Формирование заголовка
+	sum := sha256.Sum256(body)
+	header := snapshotHeader{
+		Version:     snapshotVersion,
+		Timestamp:   time.Now(),
+		Count:       len(snap.records),
+		Checksum:    hex.EncodeToString(sum[:]),
+		Format:      ms.snapshotFormat,
+		Compression: ms.snapshotCompression,
+		WALSeq:      snap.walSeq,
+	}
+	bheader, err := header.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	// Запись во временный файл
+	tmpPath := ms.fileStoragePath + snapshotTmpSuffix
+	if err = writeFileSync(tmpPath, append(bheader, '\n'), body); err != nil {
+		slog.Error("writing to file error", "error", err)
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+
+	// Ротация предыдущих бекапов и замена текущего
+	if err = ms.rotateSnapshots(); err != nil {
+		slog.Error("backup rotation error", "error", err)
+	}
+	if err = os.Rename(tmpPath, ms.fileStoragePath); err != nil {
+		return err
+	}
+	if err = syncDir(filepath.Dir(ms.fileStoragePath)); err != nil {
+		return err
+	}
+
+	// Компактификация журнала
+	if ms.wal != nil {
+		if err = ms.wal.compact(snap.walSize); err != nil {
+			slog.Error("WAL compaction error", "error", err)
 		}
-		// Запись метрики в файл
-		_, err = ms.writer.Write(bmetric)
-		if err != nil {
-			slog.Error("writing to file error", "error", err)
+	}
+	return nil
+}
+
+// Метод сдвигающий предыдущие бекапы: .1 -> .2, ..., текущий -> .1.
+// Самый старый бекап сверх ms.backupKeep удаляется.
+// Текущий бекап связывается жесткой ссылкой, чтобы файл по основному пути существовал всегда.
+// Если файловая система не поддерживает жесткие ссылки, текущий бекап копируется.
+func (ms *MetricStorage) rotateSnapshots() error {
+	if ms.backupKeep <= 0 {
+		return nil
+	}
+
+	paths := ms.snapshotPaths()
+	if err := os.Remove(paths[ms.backupKeep]); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := ms.backupKeep - 1; i >= 1; i-- {
+		if err := os.Rename(paths[i], paths[i+1]); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return err
 		}
-		// Добавление переноса строки
-		err = ms.writer.WriteByte('\n')
-		if err != nil {
-			slog.Error("writing to file error", "error", err)
-			return err
+	}
+	err := os.Link(paths[0], paths[1])
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	slog.Warn("backup hard link failed, copying", slog.String("error", err.Error()))
+	return copyFileSync(paths[0], paths[1])
+}
+
+// Функция копирования файла src в dst с синхронизацией с диском.
+// Отсутствие файла src ошибкой не считается.
+func copyFileSync(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileSync(dst, data)
+}
+
+// Метод возвращает пути к бекапам от самого нового к самому старому.
+// Первым идет путь к текущему бекапу.
+func (ms *MetricStorage) snapshotPaths() []string {
+	paths := make([]string, 0, ms.backupKeep+1)
+	paths = append(paths, ms.fileStoragePath)
+	for i := 1; i <= ms.backupKeep; i++ {
+		paths = append(paths, fmt.Sprintf("%s.%d", ms.fileStoragePath, i))
+	}
+	return paths
+}
+
+// Функция записи файла с синхронизацией с диском перед закрытием.
+func writeFileSync(path string, chunks ...[]byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, chunk := range chunks {
+		if _, err = writer.Write(chunk); err != nil {
+			return errors.Join(err, file.Close())
 		}
 	}
-	return ms.writer.Flush()
+	if err = writer.Flush(); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	if err = file.Sync(); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	return file.Close()
+}
+
+// Функция синхронизации каталога с диском.
+// Необходима, чтобы переименование файла в каталоге пережило сбой.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return errors.Join(dir.Sync(), dir.Close())
 }
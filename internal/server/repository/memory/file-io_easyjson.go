@@ -0,0 +1,319 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package memory
+
+import (
+	json "encoding/json"
+	time "time"
+
+	_view "github.com/FlutterDizaster/ya-metrics/internal/view"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(in *jlexer.Lexer, out *snapshotHeader) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "version":
+			out.Version = int(in.Int())
+		case "timestamp":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Timestamp).UnmarshalJSON(data))
+			}
+		case "wal_seq":
+			out.WALSeq = uint64(in.Uint64())
+		case "count":
+			out.Count = int(in.Int())
+		case "checksum":
+			out.Checksum = string(in.String())
+		case "format":
+			out.Format = string(in.String())
+		case "compression":
+			out.Compression = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(out *jwriter.Writer, in snapshotHeader) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"version\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Version))
+	}
+	{
+		const prefix string = ",\"timestamp\":"
+		out.RawString(prefix)
+		out.Raw((in.Timestamp).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"wal_seq\":"
+		out.RawString(prefix)
+		out.Uint64(uint64(in.WALSeq))
+	}
+	{
+		const prefix string = ",\"count\":"
+		out.RawString(prefix)
+		out.Int(int(in.Count))
+	}
+	{
+		const prefix string = ",\"checksum\":"
+		out.RawString(prefix)
+		out.String(string(in.Checksum))
+	}
+	if in.Format != "" {
+		const prefix string = ",\"format\":"
+		out.RawString(prefix)
+		out.String(string(in.Format))
+	}
+	if in.Compression != "" {
+		const prefix string = ",\"compression\":"
+		out.RawString(prefix)
+		out.String(string(in.Compression))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v snapshotHeader) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v snapshotHeader) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *snapshotHeader) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *snapshotHeader) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(l, v)
+}
+func easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(in *jlexer.Lexer, out *backupRecord) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "history":
+			(out.History).UnmarshalEasyJSON(in)
+		case "id":
+			out.ID = string(in.String())
+		case "type":
+			out.MType = string(in.String())
+		case "labels":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Labels = make(_view.Labels)
+				} else {
+					out.Labels = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v1 string
+					v1 = string(in.String())
+					(out.Labels)[key] = v1
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "delta":
+			if in.IsNull() {
+				in.Skip()
+				out.Delta = nil
+			} else {
+				if out.Delta == nil {
+					out.Delta = new(int64)
+				}
+				*out.Delta = int64(in.Int64())
+			}
+		case "value":
+			if in.IsNull() {
+				in.Skip()
+				out.Value = nil
+			} else {
+				if out.Value == nil {
+					out.Value = new(float64)
+				}
+				*out.Value = float64(in.Float64())
+			}
+		case "histogram":
+			if in.IsNull() {
+				in.Skip()
+				out.Histogram = nil
+			} else {
+				if out.Histogram == nil {
+					out.Histogram = new(_view.Histogram)
+				}
+				(*out.Histogram).UnmarshalEasyJSON(in)
+			}
+		case "last_updated":
+			if in.IsNull() {
+				in.Skip()
+				out.LastUpdated = nil
+			} else {
+				if out.LastUpdated == nil {
+					out.LastUpdated = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.LastUpdated).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(out *jwriter.Writer, in backupRecord) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if len(in.History) != 0 {
+		const prefix string = ",\"history\":"
+		first = false
+		out.RawString(prefix[1:])
+		(in.History).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"id\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"type\":"
+		out.RawString(prefix)
+		out.String(string(in.MType))
+	}
+	if len(in.Labels) != 0 {
+		const prefix string = ",\"labels\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v2First := true
+			for v2Name, v2Value := range in.Labels {
+				if v2First {
+					v2First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v2Name))
+				out.RawByte(':')
+				out.String(string(v2Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if in.Delta != nil {
+		const prefix string = ",\"delta\":"
+		out.RawString(prefix)
+		out.Int64(int64(*in.Delta))
+	}
+	if in.Value != nil {
+		const prefix string = ",\"value\":"
+		out.RawString(prefix)
+		out.Float64(float64(*in.Value))
+	}
+	if in.Histogram != nil {
+		const prefix string = ",\"histogram\":"
+		out.RawString(prefix)
+		(*in.Histogram).MarshalEasyJSON(out)
+	}
+	if in.LastUpdated != nil {
+		const prefix string = ",\"last_updated\":"
+		out.RawString(prefix)
+		out.Raw((*in.LastUpdated).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v backupRecord) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v backupRecord) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson2fa62e39EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *backupRecord) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *backupRecord) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson2fa62e39DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory1(l, v)
+}
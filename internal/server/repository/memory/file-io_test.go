@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricStorage_SnapshotAtomicWrite(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 5)
+	require.NoError(t, ms.saveToFile())
+
+	// Временный файл не остается после записи
+	_, err = os.Stat(settings.FileStoragePath + snapshotTmpSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	header, records, err := readSnapshot(settings.FileStoragePath)
+	require.NoError(t, err)
+	assert.Equal(t, snapshotVersion, header.Version)
+	assert.Equal(t, 1, header.Count)
+	assert.NotEmpty(t, header.Checksum)
+	assert.Len(t, records, 1)
+
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 5)
+}
+
+func TestMetricStorage_SnapshotRotation(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		BackupKeep:      2,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		addCounter(t, ms, 1)
+		require.NoError(t, ms.saveToFile())
+	}
+
+	// Хранятся текущий и два предыдущих бекапа
+	wants := []int{4, 3, 2}
+	for i, want := range wants {
+		path := settings.FileStoragePath
+		if i > 0 {
+			path = fmt.Sprintf("%s.%d", path, i)
+		}
+		_, records, readErr := readSnapshot(path)
+		require.NoError(t, readErr, path)
+		require.Len(t, records, 1)
+		assert.Equal(t, int64(want), *records[0].Delta, path)
+	}
+	_, err = os.Stat(fmt.Sprintf("%s.%d", settings.FileStoragePath, len(wants)))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCopyFileSync(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "metrics.json")
+	dst := filepath.Join(dir, "metrics.json.1")
+
+	// Отсутствующий файл не копируется
+	require.NoError(t, copyFileSync(src, dst))
+	_, err := os.Stat(dst)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(src, []byte("backup"), 0666))
+	require.NoError(t, os.WriteFile(dst, []byte("previous backup"), 0666))
+	require.NoError(t, copyFileSync(src, dst))
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", string(data))
+}
+
+func TestMetricStorage_SnapshotFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, path string)
+	}{
+		{
+			name: "checksum mismatch",
+			corrupt: func(t *testing.T, path string) {
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				data[len(data)-3]++
+				require.NoError(t, os.WriteFile(path, data, 0666))
+			},
+		},
+		{
+			name: "truncated",
+			corrupt: func(t *testing.T, path string) {
+				require.NoError(t, os.Truncate(path, 0))
+			},
+		},
+		{
+			name: "missing",
+			corrupt: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(path))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &Settings{
+				FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+				Restore:         true,
+				BackupKeep:      1,
+			}
+
+			ms, err := New(settings)
+			require.NoError(t, err)
+			addCounter(t, ms, 1)
+			require.NoError(t, ms.saveToFile())
+			addCounter(t, ms, 1)
+			require.NoError(t, ms.saveToFile())
+
+			tt.corrupt(t, settings.FileStoragePath)
+
+			// Восстановление из предыдущего бекапа
+			restored, err := New(settings)
+			require.NoError(t, err)
+			assertCounter(t, restored, 1)
+		})
+	}
+}
+
+func TestMetricStorage_LegacySnapshot(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+	}
+
+	// Бекап без заголовка
+	legacy := `{"id":"counter","type":"counter","delta":7}` + "\n"
+	require.NoError(t, os.WriteFile(settings.FileStoragePath, []byte(legacy), 0666))
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, ms, 7)
+}
+
+func TestMetricStorage_SnapshotCodecs(t *testing.T) {
+	for _, format := range []string{SnapshotFormatJSON, SnapshotFormatProto} {
+		for _, compression := range []string{SnapshotCompressionNone, SnapshotCompressionGzip, SnapshotCompressionZstd} {
+			t.Run(format+"/"+compression, func(t *testing.T) {
+				settings := &Settings{
+					FileStoragePath:     filepath.Join(t.TempDir(), "metrics.json"),
+					Restore:             true,
+					HistoryDepth:        10,
+					SnapshotFormat:      format,
+					SnapshotCompression: compression,
+				}
+
+				ms, err := New(settings)
+				require.NoError(t, err)
+				addCounter(t, ms, 1)
+				addCounter(t, ms, 2)
+				require.NoError(t, ms.saveToFile())
+
+				header, _, err := readSnapshot(settings.FileStoragePath)
+				require.NoError(t, err)
+				assert.Equal(t, format, header.Format)
+				assert.Equal(t, compression, header.Compression)
+
+				// Бекап читается независимо от настроек формата
+				restored, err := New(&Settings{
+					FileStoragePath: settings.FileStoragePath,
+					Restore:         true,
+					HistoryDepth:    10,
+				})
+				require.NoError(t, err)
+				assertCounter(t, restored, 3)
+				assert.Len(t, restored.shardFor("counter").history["counter"].all(), 2)
+			})
+		}
+	}
+}
+
+func TestNew_InvalidSnapshotCodec(t *testing.T) {
+	_, err := New(&Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		SnapshotFormat:  "xml",
+	})
+	require.ErrorIs(t, err, errUnknownSnapshotFormat)
+
+	_, err = New(&Settings{
+		FileStoragePath:     filepath.Join(t.TempDir(), "metrics.json"),
+		SnapshotCompression: "lz4",
+	})
+	require.ErrorIs(t, err, errUnknownSnapshotCompression)
+}
+
+func BenchmarkMetricStorage_SaveToFile(b *testing.B) {
+	const seriesCount = 100000
+	for _, format := range []string{SnapshotFormatJSON, SnapshotFormatProto} {
+		for _, compression := range []string{SnapshotCompressionNone, SnapshotCompressionZstd} {
+			b.Run(format+"/"+compression, func(b *testing.B) {
+				ms, err := New(&Settings{
+					FileStoragePath:     filepath.Join(b.TempDir(), "metrics.json"),
+					SnapshotFormat:      format,
+					SnapshotCompression: compression,
+				})
+				require.NoError(b, err)
+
+				metrics := make([]view.Metric, 0, seriesCount)
+				for i := 0; i < seriesCount; i++ {
+					value := float64(i)
+					metrics = append(metrics, view.Metric{
+						ID:    fmt.Sprintf("gauge%d", i),
+						MType: view.KindGauge,
+						Value: &value,
+					})
+				}
+				_, err = ms.AddMetrics(context.Background(), metrics...)
+				require.NoError(b, err)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					require.NoError(b, ms.saveToFile())
+				}
+				b.StopTimer()
+
+				info, err := os.Stat(ms.fileStoragePath)
+				require.NoError(b, err)
+				b.ReportMetric(float64(info.Size()), "bytes/snapshot")
+			})
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Тип ring - кольцевой буфер сэмплов метрики ограниченной глубины.
+// При заполнении буфера новые сэмплы вытесняют самые старые.
+type ring struct {
+	samples []view.Sample
+	start   int
+	size    int
+}
+
+// Функция создания кольцевого буфера глубиной depth.
+func newRing(depth int) *ring {
+	return &ring{
+		samples: make([]view.Sample, depth),
+	}
+}
+
+// Метод добавления сэмпла в буфер.
+func (r *ring) push(sample view.Sample) {
+	if len(r.samples) == 0 {
+		return
+	}
+	if r.size < len(r.samples) {
+		r.samples[(r.start+r.size)%len(r.samples)] = sample
+		r.size++
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// Метод удаления сэмплов, записанных раньше before.
+func (r *ring) trim(before time.Time) {
+	for r.size > 0 && r.samples[r.start].Timestamp.Before(before) {
+		r.samples[r.start] = view.Sample{}
+		r.start = (r.start + 1) % len(r.samples)
+		r.size--
+	}
+}
+
+// Метод возвращает сэмплы из интервала [from, to] в порядке добавления.
+func (r *ring) between(from, to time.Time) view.Samples {
+	samples := make(view.Samples, 0)
+	for i := 0; i < r.size; i++ {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// Метод возвращает все сэмплы буфера в порядке добавления.
+func (r *ring) all() view.Samples {
+	samples := make(view.Samples, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		samples = append(samples, r.samples[(r.start+i)%len(r.samples)])
+	}
+	return samples
+}
+
+// Функция возвращает более позднее из двух значений времени.
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Метод получения истории значений метрики.
+// Возвращает значения метрики за интервал [query.From, query.To] в порядке возрастания времени.
+// Если query.Step больше нуля, то значения прореживаются.
+func (ms *MetricStorage) ReadHistory(_ context.Context, query view.HistoryQuery) (view.Samples, error) {
+	s := ms.shardFor(query.Name)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := view.MetricKey(query.Name, query.Labels)
+
+	metric, ok := s.metrics[key]
+	if !ok || metric.MType != query.Kind {
+		return nil, repository.ErrNotFound
+	}
+
+	history, ok := s.history[key]
+	if !ok {
+		return view.Samples{}, nil
+	}
+
+	// Устаревшие сэмплы удаляются при записи, при чтении они только пропускаются
+	from := query.From
+	if ms.historyDuration > 0 {
+		from = latest(from, time.Now().Add(-ms.historyDuration))
+	}
+
+	samples := history.between(from, query.To)
+	if query.Step > 0 {
+		return view.Downsample(samples, query.Step, query.Aggregation)
+	}
+	return samples, nil
+}
@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing_Push(t *testing.T) {
+	type test struct {
+		name  string
+		depth int
+		count int
+		want  []float64
+	}
+
+	tests := []test{
+		{
+			name:  "not full",
+			depth: 5,
+			count: 3,
+			want:  []float64{0, 1, 2},
+		},
+		{
+			name:  "overflow",
+			depth: 3,
+			count: 5,
+			want:  []float64{2, 3, 4},
+		},
+		{
+			name:  "zero depth",
+			depth: 0,
+			count: 3,
+			want:  []float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRing(tt.depth)
+			start := time.Unix(0, 0)
+			for i := 0; i < tt.count; i++ {
+				r.push(view.Sample{Timestamp: start.Add(time.Duration(i) * time.Second), Value: float64(i)})
+			}
+
+			got := make([]float64, 0)
+			for _, sample := range r.all() {
+				got = append(got, sample.Value)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRing_Trim(t *testing.T) {
+	r := newRing(5)
+	start := time.Unix(0, 0)
+	for i := 0; i < 5; i++ {
+		r.push(view.Sample{Timestamp: start.Add(time.Duration(i) * time.Second), Value: float64(i)})
+	}
+
+	r.trim(start.Add(3 * time.Second))
+
+	got := r.all()
+	require.Len(t, got, 2)
+	assert.InDelta(t, float64(3), got[0].Value, 0)
+	assert.InDelta(t, float64(4), got[1].Value, 0)
+}
+
+func TestMetricStorage_ReadHistory(t *testing.T) {
+	type test struct {
+		name    string
+		query   view.HistoryQuery
+		want    int
+		wantErr bool
+	}
+
+	ms, err := New(&Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		HistoryDepth:    3,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = ms.AddMetrics(context.Background(), view.Metric{
+			ID:    "test",
+			MType: view.KindCounter,
+			Delta: func(i int64) *int64 { return &i }(1),
+		})
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+
+	tests := []test{
+		{
+			name: "depth limited",
+			query: view.HistoryQuery{
+				Kind: view.KindCounter,
+				Name: "test",
+				From: now.Add(-time.Hour),
+				To:   now,
+			},
+			want: 3,
+		},
+		{
+			name: "out of range",
+			query: view.HistoryQuery{
+				Kind: view.KindCounter,
+				Name: "test",
+				From: now.Add(-2 * time.Hour),
+				To:   now.Add(-time.Hour),
+			},
+			want: 0,
+		},
+		{
+			name: "not found",
+			query: view.HistoryQuery{
+				Kind: view.KindCounter,
+				Name: "unknown",
+				From: now.Add(-time.Hour),
+				To:   now,
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong type",
+			query: view.HistoryQuery{
+				Kind: view.KindGauge,
+				Name: "test",
+				From: now.Add(-time.Hour),
+				To:   now,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := ms.ReadHistory(context.Background(), tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, samples, tt.want)
+		})
+	}
+}
+
+func TestMetricStorage_BackupHistory(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		HistoryDepth:    10,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = ms.AddMetrics(context.Background(), view.Metric{
+			ID:     "test",
+			MType:  view.KindGauge,
+			Labels: view.Labels{"host": "web1"},
+			Value:  func(i float64) *float64 { return &i }(float64(i)),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, ms.saveToFile())
+
+	settings.Restore = true
+	restored, err := New(settings)
+	require.NoError(t, err)
+
+	metric, err := restored.GetMetric(context.Background(), view.KindGauge, "test", view.Labels{"host": "web1"})
+	require.NoError(t, err)
+	assert.InDelta(t, float64(2), *metric.Value, 0)
+
+	samples, err := restored.ReadHistory(context.Background(), view.HistoryQuery{
+		Kind:   view.KindGauge,
+		Name:   "test",
+		Labels: view.Labels{"host": "web1"},
+		From:   time.Now().Add(-time.Hour),
+		To:     time.Now(),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	for i, sample := range samples {
+		assert.InDelta(t, float64(i), sample.Value, 0)
+	}
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Метод получения страницы списка метрик.
+// Сегменты читаются по очереди, как и в ReadAllMetrics.
+// Возвращает ошибку, оборачивающую view.ErrInvalidListQuery, если параметры запроса некорректны.
+func (ms *MetricStorage) ListMetrics(_ context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	if err := query.Normalize(); err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	match := query.Matcher()
+	metrics := make([]view.Metric, 0)
+	for _, s := range ms.shards {
+		s.mu.RLock()
+		for _, metric := range s.metrics {
+			if match(metric) {
+				metrics = append(metrics, metric)
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	return query.SortPage(metrics)
+}
@@ -1,6 +1,8 @@
 package memory
 
+import "context"
+
 // Метод заглушка для реализации интерфейса api.MetricStorage.
-func (ms *MetricStorage) Ping() error {
+func (ms *MetricStorage) Ping(_ context.Context) error {
 	return nil
 }
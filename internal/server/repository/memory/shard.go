@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Кол-во сегментов хранилища по умолчанию.
+const defaultShards = 32
+
+// Тип shard - сегмент хранилища со своей блокировкой.
+// Метрика попадает в сегмент по хешу ID, поэтому все метрики с одним ID,
+// но разными метками, хранятся в одном сегменте.
+type shard struct {
+	mu              sync.RWMutex
+	metrics         map[string]view.Metric
+	history         map[string]*ring
+	historyDepth    int
+	historyDuration time.Duration
+}
+
+// Функция создания сегмента хранилища.
+func newShard(historyDepth int, historyDuration time.Duration) *shard {
+	return &shard{
+		metrics:         make(map[string]view.Metric),
+		history:         make(map[string]*ring),
+		historyDepth:    historyDepth,
+		historyDuration: historyDuration,
+	}
+}
+
+// Метод возвращает сегмент, в котором хранятся метрики с ID = name.
+func (ms *MetricStorage) shardFor(name string) *shard {
+	return ms.shards[shardIndex(name, len(ms.shards))]
+}
+
+// Функция вычисления номера сегмента по ID метрики (FNV-1a).
+func shardIndex(name string, count int) int {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(name); i++ {
+		hash ^= uint32(name[i])
+		hash *= prime32
+	}
+	return int(hash % uint32(count))
+}
+
+// Хелпер метод для блокировки на запись всех сегментов, затрагиваемых пакетом метрик.
+// Сегменты блокируются в порядке возрастания номера, чтобы избежать взаимоблокировок.
+// Возвращает функцию снятия блокировок.
+func (ms *MetricStorage) lockShards(metrics []view.Metric) func() {
+	used := make([]bool, len(ms.shards))
+	for i := range metrics {
+		used[shardIndex(metrics[i].ID, len(ms.shards))] = true
+	}
+
+	locked := make([]*shard, 0, len(metrics))
+	for i, ok := range used {
+		if ok {
+			ms.shards[i].mu.Lock()
+			locked = append(locked, ms.shards[i])
+		}
+	}
+
+	return func() {
+		for _, s := range locked {
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Хелпер фенкция для добавления метрики типа kindCounter.
+func (s *shard) addCounter(metric view.Metric) (view.Metric, error) {
+	oldMetric, ok := s.metrics[metric.Key()]
+	if !ok {
+		s.metrics[metric.Key()] = metric
+		return metric, nil
+	}
+
+	if oldMetric.MType != metric.MType {
+		return metric, repository.ErrWrongType
+	}
+
+	delta := *oldMetric.Delta + *metric.Delta
+	metric.Delta = &delta
+
+	s.metrics[metric.Key()] = metric
+
+	return metric, nil
+}
+
+// Хелпер фенкция для добавления метрики типа kindHistogram.
+// Объединяет корзины новой гистограммы с уже хранящейся.
+func (s *shard) addHistogram(metric view.Metric) (view.Metric, error) {
+	if metric.Histogram == nil {
+		return metric, view.ErrInvalidHistogram
+	}
+	if err := metric.Histogram.Validate(); err != nil {
+		return metric, err
+	}
+
+	oldMetric, ok := s.metrics[metric.Key()]
+	if !ok {
+		s.metrics[metric.Key()] = metric
+		return metric, nil
+	}
+
+	if oldMetric.MType != metric.MType {
+		return metric, repository.ErrWrongType
+	}
+
+	histogram, err := oldMetric.Histogram.Merge(metric.Histogram)
+	if err != nil {
+		return metric, err
+	}
+	metric.Histogram = histogram
+
+	s.metrics[metric.Key()] = metric
+
+	return metric, nil
+}
+
+// Хелпер фенкция для добавления метрики типа kindGauge.
+func (s *shard) addGauge(metric view.Metric) (view.Metric, error) {
+	oldMetric, ok := s.metrics[metric.Key()]
+	if !ok {
+		s.metrics[metric.Key()] = metric
+		return metric, nil
+	}
+
+	if oldMetric.MType != metric.MType {
+		return metric, repository.ErrWrongType
+	}
+
+	s.metrics[metric.Key()] = metric
+
+	return metric, nil
+}
+
+// Хелпер метод для записи обновленного значения метрики в историю.
+func (s *shard) addSample(metric view.Metric, ts time.Time) {
+	if s.historyDepth <= 0 {
+		return
+	}
+
+	value, ok := metric.SampleValue()
+	if !ok {
+		return
+	}
+
+	key := metric.Key()
+	history, ok := s.history[key]
+	if !ok {
+		history = newRing(s.historyDepth)
+		s.history[key] = history
+	}
+
+	history.push(view.Sample{Timestamp: ts, Value: value})
+	if s.historyDuration > 0 {
+		history.trim(ts.Add(-s.historyDuration))
+	}
+}
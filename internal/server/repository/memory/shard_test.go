@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardIndex(t *testing.T) {
+	for _, count := range []int{1, 7, 32} {
+		for i := 0; i < 1000; i++ {
+			name := fmt.Sprintf("metric%d", i)
+			index := shardIndex(name, count)
+			assert.GreaterOrEqual(t, index, 0)
+			assert.Less(t, index, count)
+			assert.Equal(t, index, shardIndex(name, count))
+		}
+	}
+}
+
+func TestMetricStorage_ConcurrentWritesWithBackup(t *testing.T) {
+	const (
+		writers  = 8
+		batches  = 200
+		counters = 50
+	)
+
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncNever,
+		HistoryDepth:    10,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+
+	// Параллельная запись пакетов счетчиков из разных сегментов
+	var wg sync.WaitGroup
+	var done atomic.Bool
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delta := int64(1)
+			for b := 0; b < batches; b++ {
+				batch := make([]view.Metric, 0, counters)
+				for c := 0; c < counters; c++ {
+					batch = append(batch, view.Metric{
+						ID:    fmt.Sprintf("counter%d", c),
+						MType: view.KindCounter,
+						Delta: &delta,
+					})
+				}
+				_, addErr := ms.AddMetrics(context.Background(), batch...)
+				assert.NoError(t, addErr)
+			}
+		}()
+	}
+
+	// Бекапы во время записи
+	backups := make(chan struct{})
+	go func() {
+		defer close(backups)
+		for !done.Load() {
+			assert.NoError(t, ms.saveToFile())
+		}
+	}()
+
+	wg.Wait()
+	done.Store(true)
+	<-backups
+
+	metrics, err := ms.ReadAllMetrics(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics, counters)
+	for _, metric := range metrics {
+		assert.Equal(t, int64(writers*batches), *metric.Delta, metric.ID)
+	}
+
+	// Бекап и журнал после аварийного завершения восстанавливают то же состояние
+	crash(t, ms)
+	restored, err := New(settings)
+	require.NoError(t, err)
+	for c := 0; c < counters; c++ {
+		metric, getErr := restored.GetMetric(context.Background(), view.KindCounter, fmt.Sprintf("counter%d", c), nil)
+		require.NoError(t, getErr)
+		assert.Equal(t, int64(writers*batches), *metric.Delta, metric.ID)
+	}
+}
+
+func BenchmarkMetricStorage_AddMetricsParallel(b *testing.B) {
+	const seriesCount = 10000
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			ms, err := New(&Settings{
+				FileStoragePath: filepath.Join(b.TempDir(), "metrics.json"),
+				HistoryDepth:    10,
+				Shards:          shards,
+			})
+			require.NoError(b, err)
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				value := 1.0
+				for pb.Next() {
+					_, addErr := ms.AddMetrics(context.Background(), view.Metric{
+						ID:    fmt.Sprintf("gauge%d", next.Add(1)%seriesCount),
+						MType: view.KindGauge,
+						Value: &value,
+					})
+					if addErr != nil {
+						b.Fatal(addErr)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkMetricStorage_ReadWriteParallel(b *testing.B) {
+	const seriesCount = 10000
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			ms, err := New(&Settings{
+				FileStoragePath: filepath.Join(b.TempDir(), "metrics.json"),
+				Shards:          shards,
+			})
+			require.NoError(b, err)
+
+			value := 1.0
+			for i := 0; i < seriesCount; i++ {
+				_, err = ms.AddMetrics(context.Background(), view.Metric{
+					ID:    fmt.Sprintf("gauge%d", i),
+					MType: view.KindGauge,
+					Value: &value,
+				})
+				require.NoError(b, err)
+			}
+
+			// Каждая четвертая операция - запись, остальные - чтение
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := next.Add(1)
+					name := fmt.Sprintf("gauge%d", i%seriesCount)
+					var opErr error
+					if i%4 == 0 {
+						_, opErr = ms.AddMetrics(context.Background(), view.Metric{
+							ID:    name,
+							MType: view.KindGauge,
+							Value: &value,
+						})
+					} else {
+						_, opErr = ms.GetMetric(context.Background(), view.KindGauge, name, nil)
+					}
+					if opErr != nil {
+						b.Fatal(opErr)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// Форматы записи метрик в файле бекапа.
+const (
+	SnapshotFormatJSON  = "json"  // Строки easyjson, по одной метрике на строку
+	SnapshotFormatProto = "proto" // Записи proto.SnapshotRecord с префиксом длины
+)
+
+// Алгоритмы сжатия файла бекапа.
+// Заголовок бекапа не сжимается.
+const (
+	SnapshotCompressionNone = "none"
+	SnapshotCompressionGzip = "gzip"
+	SnapshotCompressionZstd = "zstd"
+)
+
+var (
+	errUnknownSnapshotFormat      = errors.New("unknown backup format. expected json or proto")
+	errUnknownSnapshotCompression = errors.New("unknown backup compression. expected none, gzip or zstd")
+)
+
+// Функция проверки формата и алгоритма сжатия бекапа.
+// Пустые значения соответствуют формату json без сжатия.
+func validateSnapshotCodec(format, compression string) error {
+	switch format {
+	case SnapshotFormatJSON, SnapshotFormatProto, "":
+	default:
+		return errUnknownSnapshotFormat
+	}
+	switch compression {
+	case SnapshotCompressionNone, SnapshotCompressionGzip, SnapshotCompressionZstd, "":
+	default:
+		return errUnknownSnapshotCompression
+	}
+	return nil
+}
+
+// Функция кодирования метрик бекапа в формате format.
+func encodeSnapshot(format string, records []backupRecord) ([]byte, error) {
+	var body bytes.Buffer
+	for _, record := range records {
+		var err error
+		switch format {
+		case SnapshotFormatProto:
+			_, err = protodelim.MarshalTo(&body, marshalSnapshotRecord(record))
+		case SnapshotFormatJSON, "":
+			var bmetric []byte
+			bmetric, err = record.MarshalJSON()
+			body.Write(bmetric)
+			body.WriteByte('\n')
+		default:
+			err = errUnknownSnapshotFormat
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body.Bytes(), nil
+}
+
+// Функция декодирования метрик бекапа в формате format.
+func decodeSnapshot(format string, body []byte, sizeHint int) ([]backupRecord, error) {
+	records := make([]backupRecord, 0, sizeHint)
+	switch format {
+	case SnapshotFormatProto:
+		reader := bufio.NewReader(bytes.NewReader(body))
+		options := protodelim.UnmarshalOptions{MaxSize: maxSnapshotLine}
+		for {
+			msg := &pb.SnapshotRecord{}
+			err := options.UnmarshalFrom(reader, msg)
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, unmarshalSnapshotRecord(msg))
+		}
+	case SnapshotFormatJSON, "":
+		// Создание сканера
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(nil, maxSnapshotLine)
+
+		// Проход по всем строкам файла
+		for scanner.Scan() {
+			// Анмаршалинг строки
+			record := backupRecord{}
+			if err := record.UnmarshalJSON(scanner.Bytes()); err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+		return records, scanner.Err()
+	default:
+		return nil, errUnknownSnapshotFormat
+	}
+}
+
+// Функция сжатия тела бекапа алгоритмом compression.
+func compressSnapshot(compression string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch compression {
+	case SnapshotCompressionGzip:
+		writer = gzip.NewWriter(&buf)
+	case SnapshotCompressionZstd:
+		var err error
+		writer, err = zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+	case SnapshotCompressionNone, "":
+		return body, nil
+	default:
+		return nil, errUnknownSnapshotCompression
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, errors.Join(err, writer.Close())
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Функция распаковки тела бекапа, сжатого алгоритмом compression.
+func decompressSnapshot(compression string, body []byte) ([]byte, error) {
+	switch compression {
+	case SnapshotCompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case SnapshotCompressionZstd:
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(body, nil)
+	case SnapshotCompressionNone, "":
+		return body, nil
+	default:
+		return nil, errUnknownSnapshotCompression
+	}
+}
+
+// Хелпер функция для маршаллинга строки бекапа в сообщение пакета proto.
+func marshalSnapshotRecord(record backupRecord) *pb.SnapshotRecord {
+	msg := &pb.SnapshotRecord{
+		Metric:  view.MarshalGRPCMetrics([]view.Metric{record.Metric})[0],
+		History: make([]*pb.Sample, 0, len(record.History)),
+	}
+	for _, sample := range record.History {
+		msg.History = append(msg.History, &pb.Sample{
+			Timestamp: sample.Timestamp.UnixNano(),
+			Value:     sample.Value,
+		})
+	}
+	return msg
+}
+
+// Хелпер функция для маршаллинга сообщения пакета proto в строку бекапа.
+func unmarshalSnapshotRecord(msg *pb.SnapshotRecord) backupRecord {
+	record := backupRecord{
+		Metric: view.UnmarshalGRPCMetrics([]*pb.Metric{msg.GetMetric()})[0],
+	}
+	if len(msg.GetHistory()) > 0 {
+		record.History = make(view.Samples, 0, len(msg.GetHistory()))
+		for _, sample := range msg.GetHistory() {
+			record.History = append(record.History, view.Sample{
+				Timestamp: time.Unix(0, sample.GetTimestamp()),
+				Value:     sample.GetValue(),
+			})
+		}
+	}
+	return record
+}
@@ -1,38 +1,56 @@
 package memory
 
 import (
-	"bufio"
-	"errors"
+	"context"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
 )
 
-var (
-	errNotFound  = errors.New("metric not found")
-	errWrongType = errors.New("wrong metric type")
-)
-
 // Тип Settings используется для хранения настроек хранилища метрик.
 type Settings struct {
-	StoreInterval   int    // Интервал между записями в файл бекапа
-	FileStoragePath string // Путь к файлу бекапа
-	Restore         bool   // Флаг восстановления бекапа
+	StoreInterval       int    // Интервал между записями в файл бекапа
+	FileStoragePath     string // Путь к файлу бекапа
+	Restore             bool   // Флаг восстановления бекапа
+	BackupKeep          int    // Кол-во хранимых предыдущих бекапов
+	SnapshotFormat      string // Формат метрик в бекапе: json или proto. По умолчанию json
+	SnapshotCompression string // Алгоритм сжатия бекапа: none, gzip или zstd. По умолчанию без сжатия
+	HistoryDepth        int    // Максимальное кол-во хранимых значений каждой метрики. 0 - история не хранится
+	HistoryDuration     int    // Время хранения значений метрик в истории в секундах. 0 - без ограничения
+	WALSync             string // Политика синхронизации журнала упреждающей записи с диском. По умолчанию журнал не ведется
+	WALSyncInterval     int    // Интервал синхронизации журнала в секундах для политики WALSyncInterval
+	Shards              int    // Кол-во сегментов хранилища. По умолчанию defaultShards
 }
 
 // Тип MetricStorage используется для хранения метрик в оперативной памяти во время исполнения
 // и бекапа метрик по заданным правилам.
+// Для каждой метрики типа gauge и counter хранится ограниченная история значений.
+// Каждый пакет метрик записывается в журнал упреждающей записи,
+// который воспроизводится поверх бекапа при восстановлении.
+// Метрики распределены по сегментам с отдельными блокировками, поэтому
+// запись и чтение метрик из разных сегментов выполняются параллельно.
 type MetricStorage struct {
-	storeInterval   int
-	fileStoragePath string
-	file            *os.File
-	writer          *bufio.Writer
-	metrics         map[string]view.Metric
-	cond            *sync.Cond
-	awaiting        atomic.Bool
+	storeInterval       int
+	fileStoragePath     string
+	backupKeep          int
+	snapshotFormat      string
+	snapshotCompression string
+	shards              []*shard
+	historyDuration     time.Duration
+	wal                 *wal
+	walSyncInterval     time.Duration
+	// Разделяемо блокируется каждой записью и монопольно при снимке хранилища для бекапа
+	snapshotMu sync.RWMutex
+	// Исключает одновременную запись нескольких бекапов
+	backupMu sync.Mutex
+	cond     *sync.Cond
+	awaiting atomic.Bool
 }
 
 // Функция фабрика для создания нового экземпляра MetricStorage.
@@ -40,30 +58,67 @@ type MetricStorage struct {
 func New(settings *Settings) (*MetricStorage, error) {
 	slog.Debug("Creating DB storage")
 	ms := &MetricStorage{
-		storeInterval:   settings.StoreInterval,
-		fileStoragePath: settings.FileStoragePath,
-		metrics:         make(map[string]view.Metric),
-		cond:            sync.NewCond(&sync.Mutex{}),
+		storeInterval:       settings.StoreInterval,
+		fileStoragePath:     settings.FileStoragePath,
+		backupKeep:          settings.BackupKeep,
+		snapshotFormat:      settings.SnapshotFormat,
+		snapshotCompression: settings.SnapshotCompression,
+		historyDuration:     time.Duration(settings.HistoryDuration) * time.Second,
+		walSyncInterval:     time.Duration(settings.WALSyncInterval) * time.Second,
+		cond:                sync.NewCond(&sync.Mutex{}),
 	}
 
 	ms.awaiting.Store(false)
 
+	shards := settings.Shards
+	if shards <= 0 {
+		shards = defaultShards
+	}
+	ms.shards = make([]*shard, shards)
+	for i := range ms.shards {
+		ms.shards[i] = newShard(settings.HistoryDepth, ms.historyDuration)
+	}
+
+	if err := validateSnapshotCodec(ms.snapshotFormat, ms.snapshotCompression); err != nil {
+		slog.Error("invalid backup settings", "error", err)
+		return nil, err
+	}
+
+	var walSeq uint64
 	if settings.Restore {
-		err := ms.loadFromFile()
+		var err error
+		walSeq, err = ms.loadFromFile()
 		if err != nil {
 			slog.Error("error reading backup file", "error", err)
 			slog.Info("Skipping loading backup...")
 		}
 	}
 
-	file, err := os.OpenFile(ms.fileStoragePath, os.O_WRONLY|os.O_CREATE, 0666)
+	// Открытие журнала упреждающей записи
+	var err error
+	ms.wal, err = openWAL(ms.fileStoragePath+walSuffix, settings.WALSync)
 	if err != nil {
-		slog.Error("error opening file", "error", err)
+		slog.Error("error opening WAL", "error", err)
 		return nil, err
 	}
+	if ms.wal != nil {
+		if settings.Restore {
+			// Воспроизведение пакетов, не попавших в бекап
+			err = ms.wal.replay(walSeq, ms.replayRecord)
+		} else {
+			err = ms.wal.truncate()
+		}
+		if err != nil {
+			slog.Error("error restoring WAL", "error", err)
+			return nil, err
+		}
+	}
 
-	ms.file = file
-	ms.writer = bufio.NewWriter(file)
+	// Проверка каталога бекапа. Сам файл создается при первом бекапе
+	if _, err = os.Stat(filepath.Dir(ms.fileStoragePath)); err != nil {
+		slog.Error("error opening backup directory", "error", err)
+		return nil, err
+	}
 
 	slog.Debug("Storage created")
 	return ms, nil
@@ -73,32 +128,69 @@ func New(settings *Settings) (*MetricStorage, error) {
 // В качестве параметров принимает метрики.
 // Возвращает обновленные метрики.
 // В случае ошибки возвращает ошибку.
-func (ms *MetricStorage) AddMetrics(metrics ...view.Metric) ([]view.Metric, error) {
-	// Блокировка mutex в cond, чтобы избежать чтения данных при бекапе.
-	ms.cond.L.Lock()
-	defer func() {
-		// Оповещение фенкции бекапа о том, что можно продолжать выполнение программы.
+func (ms *MetricStorage) AddMetrics(_ context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	if err := repository.ValidateMetrics(metrics...); err != nil {
+		return nil, err
+	}
+
+	defer ms.notifyBackup()
+
+	// Разделяемая блокировка, чтобы снимок для бекапа не содержал частично примененный пакет.
+	ms.snapshotMu.RLock()
+	defer ms.snapshotMu.RUnlock()
+
+	// Блокировка затрагиваемых сегментов до записи в журнал, чтобы порядок записей
+	// в журнале совпадал с порядком применения пакетов к одним и тем же метрикам.
+	unlock := ms.lockShards(metrics)
+	defer unlock()
+
+	now := time.Now()
+
+	// Запись пакета в журнал до применения
+	if ms.wal != nil {
+		if err := ms.wal.append(now, walOpAdd, metrics); err != nil {
+			slog.Error("WAL write error", "error", err)
+			return nil, err
+		}
+	}
+
+	return ms.applyMetrics(now, metrics)
+}
+
+// Хелпер метод для оповещения фенкции бекапа о том, что можно продолжать выполнение программы.
+func (ms *MetricStorage) notifyBackup() {
+	if ms.awaiting.Load() {
+		ms.cond.L.Lock()
 		ms.cond.Broadcast()
 		ms.cond.L.Unlock()
-	}()
+	}
+}
 
+// Хелпер метод для применения пакета метрик к хранилищу.
+// Должен вызываться с заблокированными на запись сегментами метрик пакета.
+func (ms *MetricStorage) applyMetrics(now time.Time, metrics []view.Metric) ([]view.Metric, error) {
 	result := make([]view.Metric, 0, len(metrics))
 
 	for i := range metrics {
 		metric := metrics[i]
+		metric.LastUpdated = &now
+		s := ms.shardFor(metric.ID)
 
 		var err error
 		switch metric.MType {
 		case view.KindGauge:
-			metric, err = ms.addGauge(metric)
+			metric, err = s.addGauge(metric)
 		case view.KindCounter:
-			metric, err = ms.addCounter(metric)
+			metric, err = s.addCounter(metric)
+		case view.KindHistogram:
+			metric, err = s.addHistogram(metric)
 		default:
-			return nil, errWrongType
+			return nil, repository.ErrWrongType
 		}
 		if err != nil {
 			return nil, err
 		}
+		s.addSample(metric, now)
 
 		result = append(result, metric)
 	}
@@ -106,76 +198,38 @@ func (ms *MetricStorage) AddMetrics(metrics ...view.Metric) ([]view.Metric, erro
 }
 
 // Метод получения метрики из хранилища.
-// Возвращает ошибку в случае если метрика не найдена или у метрики с ID = name другой тип.
-func (ms *MetricStorage) GetMetric(kind string, name string) (view.Metric, error) {
-	ms.cond.L.Lock()
-	defer ms.cond.L.Unlock()
-
-	metric, ok := ms.metrics[name]
-	if !ok {
-		return view.Metric{}, errNotFound
-	}
-
-	if metric.MType != kind {
-		return view.Metric{}, errWrongType
+// Метрика ищется по имени name и набору меток labels.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = name другой тип.
+func (ms *MetricStorage) GetMetric(
+	_ context.Context,
+	kind string,
+	name string,
+	labels view.Labels,
+) (view.Metric, error) {
+	s := ms.shardFor(name)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metric, ok := s.metrics[view.MetricKey(name, labels)]
+	if !ok || metric.MType != kind {
+		return view.Metric{}, repository.ErrNotFound
 	}
 
 	return metric, nil
 }
 
 // Возвращает слайс всех хранящихся метрик.
-func (ms *MetricStorage) ReadAllMetrics() ([]view.Metric, error) {
-	ms.cond.L.Lock()
-	defer ms.cond.L.Unlock()
-
-	return ms.getAllMetrics(), nil
-}
-
-// Хелпер фенкция для добавления метрики типа kindCounter.
-func (ms *MetricStorage) addCounter(metric view.Metric) (view.Metric, error) {
-	oldMetric, ok := ms.metrics[metric.ID]
-	if !ok {
-		ms.metrics[metric.ID] = metric
-		return metric, nil
-	}
-
-	if oldMetric.MType != metric.MType {
-		return metric, errWrongType
-	}
-
-	delta := *oldMetric.Delta + *metric.Delta
-	metric.Delta = &delta
-
-	ms.metrics[metric.ID] = metric
-
-	return metric, nil
-}
-
-// Хелпер фенкция для добавления метрики типа kindGauge.
-func (ms *MetricStorage) addGauge(metric view.Metric) (view.Metric, error) {
-	oldMetric, ok := ms.metrics[metric.ID]
-	if !ok {
-		ms.metrics[metric.ID] = metric
-		return metric, nil
-	}
-
-	if oldMetric.MType != metric.MType {
-		return metric, errWrongType
-	}
-
-	ms.metrics[metric.ID] = metric
-
-	return metric, nil
-}
-
-func (ms *MetricStorage) getAllMetrics() []view.Metric {
-	metrics := make([]view.Metric, len(ms.metrics))
-	iter := 0
-
-	for _, metric := range ms.metrics {
-		metrics[iter] = metric
-		iter++
+// Сегменты читаются по очереди, поэтому пакеты, записываемые во время чтения,
+// могут попасть в результат частично.
+func (ms *MetricStorage) ReadAllMetrics(_ context.Context) ([]view.Metric, error) {
+	metrics := make([]view.Metric, 0)
+	for _, s := range ms.shards {
+		s.mu.RLock()
+		for _, metric := range s.metrics {
+			metrics = append(metrics, metric)
+		}
+		s.mu.RUnlock()
 	}
 
-	return metrics
+	return metrics, nil
 }
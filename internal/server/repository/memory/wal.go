@@ -0,0 +1,300 @@
+package memory
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+//go:generate easyjson wal.go
+
+// Политики синхронизации журнала упреждающей записи с диском.
+const (
+	WALSyncAlways   = "always"   // fsync после каждой записи
+	WALSyncInterval = "interval" // fsync с заданным интервалом
+	WALSyncNever    = "never"    // fsync выполняется операционной системой
+	WALSyncOff      = "off"      // Журнал не ведется
+)
+
+// Суффикс имени файла журнала относительно пути к файлу бекапа.
+const walSuffix = ".wal"
+
+var errUnknownWALSync = errors.New("unknown WAL sync policy. expected always, interval, never or off")
+
+// Операции, записываемые в журнал упреждающей записи.
+// Записи без операции, в том числе записанные предыдущими версиями, добавляют метрики.
+const (
+	walOpAdd    = ""       // Пакет метрик, переданный в AddMetrics
+	walOpDelete = "delete" // Метрики, удаляемые DeleteMetrics
+	walOpReset  = "reset"  // Счетчик, сбрасываемый ResetCounter
+)
+
+// Тип walRecord - запись журнала упреждающей записи.
+// Содержит операцию и пакет метрик, к которому она применяется.
+//
+//easyjson:json
+type walRecord struct {
+	Seq       uint64       `json:"seq"`
+	Timestamp time.Time    `json:"timestamp"`
+	Op        string       `json:"op,omitempty"`
+	Metrics   view.Metrics `json:"metrics"`
+}
+
+// Тип wal - журнал упреждающей записи.
+// Каждый пакет метрик записывается в журнал до применения к хранилищу.
+// Записи имеют возрастающий номер, по которому при восстановлении
+// пропускаются записи, уже попавшие в бекап.
+// Методы append, sync, position, compact и close безопасны для параллельного вызова.
+type wal struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	policy string
+	seq    uint64
+	size   int64
+}
+
+// Функция открытия журнала по пути path с политикой синхронизации policy.
+// Для политики WALSyncOff и пустой политики возвращает nil.
+func openWAL(path string, policy string) (*wal, error) {
+	switch policy {
+	case WALSyncAlways, WALSyncInterval, WALSyncNever:
+	case WALSyncOff, "":
+		return nil, nil
+	default:
+		return nil, errUnknownWALSync
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wal{
+		path:   path,
+		file:   file,
+		writer: bufio.NewWriter(file),
+		policy: policy,
+	}, nil
+}
+
+// Метод воспроизведения записей журнала с номером больше afterSeq.
+// Для каждой записи вызывается apply.
+// Чтение прекращается на первой поврежденной записи, например недописанной при сбое,
+// и журнал обрезается до последней целой записи.
+// Если журнал не продолжает бекап с номером afterSeq, например при восстановлении
+// из предыдущего бекапа после компактификации журнала, записи не применяются и журнал очищается:
+// применение записей с пропуском исказило бы значения счетчиков.
+func (w *wal) replay(afterSeq uint64, apply func(record walRecord)) error {
+	w.seq = afterSeq
+	continuous := true
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(w.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				slog.Warn("skipping incomplete WAL record", slog.Int64("offset", offset))
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		record := walRecord{}
+		if err = record.UnmarshalJSON(line); err != nil {
+			slog.Warn("skipping corrupted WAL tail", slog.Int64("offset", offset), slog.String("error", err.Error()))
+			break
+		}
+		offset += int64(len(line))
+
+		if record.Seq <= afterSeq {
+			continue
+		}
+		if w.seq == afterSeq && record.Seq != afterSeq+1 {
+			slog.Error(
+				"WAL does not continue from backup, skipping replay",
+				slog.Uint64("backup_seq", afterSeq),
+				slog.Uint64("wal_seq", record.Seq),
+			)
+			continuous = false
+		}
+		if continuous {
+			apply(record)
+		}
+		w.seq = record.Seq
+	}
+
+	if !continuous {
+		return w.truncateTo(0)
+	}
+
+	// Обрезка поврежденного хвоста журнала
+	return w.truncateTo(offset)
+}
+
+// Метод добавления операции op над пакетом метрик в журнал.
+// Запись передается операционной системе до возврата из метода.
+// Для политики WALSyncAlways запись синхронизируется с диском.
+func (w *wal) append(ts time.Time, op string, metrics []view.Metric) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record := walRecord{
+		Seq:       w.seq + 1,
+		Timestamp: ts,
+		Op:        op,
+		Metrics:   metrics,
+	}
+
+	data, err := record.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	_, err = w.writer.Write(data)
+	if err == nil {
+		err = w.writer.Flush()
+	}
+	if err == nil && w.policy == WALSyncAlways {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		// Удаление частично записанной записи, чтобы не повредить следующие
+		w.writer.Reset(w.file)
+		return errors.Join(err, w.truncateTo(w.size))
+	}
+
+	w.seq = record.Seq
+	w.size += int64(len(data))
+	return nil
+}
+
+// Метод синхронизации журнала с диском.
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Sync()
+}
+
+// Метод возвращает номер последней записи и размер журнала.
+func (w *wal) position() (uint64, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.seq, w.size
+}
+
+// Метод очистки журнала.
+// Нумерация записей продолжается.
+func (w *wal) truncate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.truncateTo(0)
+}
+
+// Метод удаления из журнала первых offset байт - записей, вошедших в бекап.
+// Вызывается после записи бекапа, offset - размер журнала на момент снимка хранилища.
+// Записи, добавленные после снимка, переносятся в новый файл журнала,
+// который атомарно заменяет текущий.
+func (w *wal) compact(offset int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if offset >= w.size {
+		return w.truncateTo(0)
+	}
+
+	// Чтение записей, добавленных после снимка
+	tail := make([]byte, w.size-offset)
+	if _, err := w.file.ReadAt(tail, offset); err != nil {
+		return err
+	}
+
+	// Запись нового журнала во временный файл
+	tmpPath := w.path + snapshotTmpSuffix
+	if err := writeFileSync(tmpPath, tail); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	file, err := os.OpenFile(tmpPath, os.O_RDWR, 0666)
+	if err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if _, err = file.Seek(0, io.SeekEnd); err != nil {
+		return errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+
+	// Замена текущего журнала
+	if err = os.Rename(tmpPath, w.path); err != nil {
+		return errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if err = syncDir(filepath.Dir(w.path)); err != nil {
+		slog.Error("WAL directory sync error", "error", err)
+	}
+
+	old := w.file
+	w.file = file
+	w.writer.Reset(file)
+	w.size = int64(len(tail))
+	return old.Close()
+}
+
+// Метод обрезки журнала до размера size.
+// Следующая запись добавляется в конец обрезанного журнала.
+func (w *wal) truncateTo(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	w.size = size
+	return nil
+}
+
+// Метод закрытия журнала.
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return errors.Join(w.file.Sync(), w.file.Close())
+}
+
+// Метод применения записи журнала при восстановлении хранилища.
+// Ошибки применения повторяют ошибки исходного вызова AddMetrics и пропускаются.
+// Удаление и сброс записываются в журнал только после проверки метрик, поэтому применяются без ошибок.
+func (ms *MetricStorage) replayRecord(record walRecord) {
+	switch record.Op {
+	case walOpDelete:
+		ms.deleteMetrics(record.Metrics)
+		return
+	case walOpReset:
+		ms.resetCounters(record.Timestamp, record.Metrics)
+		return
+	}
+
+	if _, err := ms.applyMetrics(record.Timestamp, record.Metrics); err != nil {
+		slog.Warn(
+			"WAL record partially applied",
+			slog.Uint64("seq", record.Seq),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,109 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package memory
+
+import (
+	json "encoding/json"
+
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson100fcfb6DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(in *jlexer.Lexer, out *walRecord) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "seq":
+			out.Seq = uint64(in.Uint64())
+		case "timestamp":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Timestamp).UnmarshalJSON(data))
+			}
+		case "op":
+			out.Op = string(in.String())
+		case "metrics":
+			(out.Metrics).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson100fcfb6EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(out *jwriter.Writer, in walRecord) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"seq\":"
+		out.RawString(prefix[1:])
+		out.Uint64(uint64(in.Seq))
+	}
+	{
+		const prefix string = ",\"timestamp\":"
+		out.RawString(prefix)
+		out.Raw((in.Timestamp).MarshalJSON())
+	}
+	if in.Op != "" {
+		const prefix string = ",\"op\":"
+		out.RawString(prefix)
+		out.String(string(in.Op))
+	}
+	{
+		const prefix string = ",\"metrics\":"
+		out.RawString(prefix)
+		(in.Metrics).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v walRecord) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson100fcfb6EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v walRecord) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson100fcfb6EncodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *walRecord) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson100fcfb6DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *walRecord) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson100fcfb6DecodeGithubComFlutterDizasterYaMetricsInternalServerRepositoryMemory(l, v)
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Хелпер для добавления значения счетчика в хранилище.
+func addCounter(t *testing.T, ms *MetricStorage, delta int64) {
+	t.Helper()
+	_, err := ms.AddMetrics(context.Background(), view.Metric{
+		ID:    "counter",
+		MType: view.KindCounter,
+		Delta: &delta,
+	})
+	require.NoError(t, err)
+}
+
+// Хелпер для проверки значения счетчика в хранилище.
+func assertCounter(t *testing.T, ms *MetricStorage, want int64) {
+	t.Helper()
+	metric, err := ms.GetMetric(context.Background(), view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, *metric.Delta)
+}
+
+// Хелпер имитирующий аварийное завершение: журнал закрывается без записи бекапа.
+func crash(t *testing.T, ms *MetricStorage) {
+	t.Helper()
+	require.NoError(t, ms.wal.file.Close())
+}
+
+func TestMetricStorage_WALReplay(t *testing.T) {
+	for _, policy := range []string{WALSyncAlways, WALSyncInterval, WALSyncNever} {
+		t.Run(policy, func(t *testing.T) {
+			settings := &Settings{
+				FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+				Restore:         true,
+				WALSync:         policy,
+			}
+
+			ms, err := New(settings)
+			require.NoError(t, err)
+			addCounter(t, ms, 1)
+			addCounter(t, ms, 2)
+			crash(t, ms)
+
+			restored, err := New(settings)
+			require.NoError(t, err)
+			assertCounter(t, restored, 3)
+		})
+	}
+}
+
+func TestMetricStorage_WALReplayDeleteAndReset(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+	}
+	ctx := context.Background()
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 5)
+	_, err = ms.ResetCounter(ctx, "counter", nil)
+	require.NoError(t, err)
+	addCounter(t, ms, 2)
+
+	value := 1.0
+	gauge := view.Metric{ID: "gauge", MType: view.KindGauge, Value: &value}
+	_, err = ms.AddMetrics(ctx, gauge)
+	require.NoError(t, err)
+	require.NoError(t, ms.DeleteMetrics(ctx, gauge))
+
+	// Неудачное удаление не записывается в журнал
+	require.Error(t, ms.DeleteMetrics(ctx, gauge))
+	crash(t, ms)
+
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 2)
+	_, err = restored.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestMetricStorage_WALReplayDeleteStale(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+	}
+	ctx := context.Background()
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 5)
+	deleted, err := ms.DeleteStaleMetrics(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	addCounter(t, ms, 2)
+	crash(t, ms)
+
+	// Удаленный счетчик создается заново без предыдущего значения
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 2)
+}
+
+func TestMetricStorage_WALCompaction(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 1)
+	addCounter(t, ms, 2)
+
+	// Бекап очищает журнал
+	ms.backup(true)
+	info, err := os.Stat(settings.FileStoragePath + walSuffix)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	addCounter(t, ms, 4)
+	crash(t, ms)
+
+	// Значения из бекапа и журнала не суммируются повторно
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 7)
+}
+
+func TestMetricStorage_WALSkipsSnapshottedRecords(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 1)
+	addCounter(t, ms, 2)
+
+	// Сбой между записью бекапа и очисткой журнала
+	require.NoError(t, ms.saveToFile())
+	addCounter(t, ms, 4)
+	crash(t, ms)
+
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 7)
+}
+
+func TestMetricStorage_WALTornTail(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 1)
+	crash(t, ms)
+
+	// Недописанная при сбое запись
+	walFile, err := os.OpenFile(settings.FileStoragePath+walSuffix, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = walFile.WriteString(`{"seq":2,"metrics":[{"id":"coun`)
+	require.NoError(t, err)
+	require.NoError(t, walFile.Close())
+
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 1)
+
+	// Новые записи добавляются после последней целой записи
+	addCounter(t, restored, 2)
+	crash(t, restored)
+
+	restored, err = New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 3)
+}
+
+func TestMetricStorage_WALGapAfterFallback(t *testing.T) {
+	settings := &Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		WALSync:         WALSyncAlways,
+		BackupKeep:      1,
+	}
+
+	ms, err := New(settings)
+	require.NoError(t, err)
+	addCounter(t, ms, 1)
+	require.NoError(t, ms.saveToFile())
+	addCounter(t, ms, 2)
+	require.NoError(t, ms.saveToFile())
+	addCounter(t, ms, 4)
+	crash(t, ms)
+
+	// Текущий бекап поврежден, журнал содержит только записи после него
+	require.NoError(t, os.Truncate(settings.FileStoragePath, 0))
+
+	// Журнал не продолжает предыдущий бекап и не применяется
+	restored, err := New(settings)
+	require.NoError(t, err)
+	assertCounter(t, restored, 1)
+
+	info, err := os.Stat(settings.FileStoragePath + walSuffix)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Имя подготовленного запроса пакетного добавления метрик.
+const stmtAddMetrics = "add_metrics"
+
+// Хелпер структура для передачи пакета метрик в запрос в виде массивов колонок.
+// Массивы разворачиваются в строки в запросе с помощью unnest.
+type batchColumns struct {
+	ids     []string
+	mtypes  []string
+	labels  []string
+	values  []*float64
+	deltas  []*int64
+	bounds  []*string
+	buckets []*string
+	hcounts []*int64
+	hsums   []*float64
+}
+
+// Функция создания колонок пакета на size метрик.
+func newBatchColumns(size int) *batchColumns {
+	return &batchColumns{
+		ids:     make([]string, 0, size),
+		mtypes:  make([]string, 0, size),
+		labels:  make([]string, 0, size),
+		values:  make([]*float64, 0, size),
+		deltas:  make([]*int64, 0, size),
+		bounds:  make([]*string, 0, size),
+		buckets: make([]*string, 0, size),
+		hcounts: make([]*int64, 0, size),
+		hsums:   make([]*float64, 0, size),
+	}
+}
+
+// Метод добавления метрики в пакет.
+func (b *batchColumns) add(metric view.Metric) error {
+	labels, err := json.Marshal(labelsArg(metric.Labels))
+	if err != nil {
+		return err
+	}
+
+	bounds, buckets, hcount, hsum := histogramArgs(metric.Histogram)
+
+	b.ids = append(b.ids, metric.ID)
+	b.mtypes = append(b.mtypes, metric.MType)
+	b.labels = append(b.labels, string(labels))
+	b.values = append(b.values, metric.Value)
+	b.deltas = append(b.deltas, metric.Delta)
+	b.bounds = append(b.bounds, arrayLiteral(bounds, func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}))
+	b.buckets = append(b.buckets, arrayLiteral(buckets, func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}))
+	b.hcounts = append(b.hcounts, hcount)
+	b.hsums = append(b.hsums, hsum)
+	return nil
+}
+
+// Метод возвращает аргументы запроса queryAddMetrics.
+func (b *batchColumns) args() []any {
+	return []any{b.ids, b.mtypes, b.labels, b.values, b.deltas, b.bounds, b.buckets, b.hcounts, b.hsums}
+}
+
+// Хелпер функция для преобразования среза в литерал массива PostgreSQL.
+// Массивы разной длины не могут быть переданы многомерным массивом,
+// поэтому передаются текстом и приводятся к нужному типу в запросе.
+// Для nil среза возвращает nil.
+func arrayLiteral[T any](values []T, format func(T) string) *string {
+	if values == nil {
+		return nil
+	}
+	items := make([]string, len(values))
+	for i := range values {
+		items[i] = format(values[i])
+	}
+	literal := "{" + strings.Join(items, ",") + "}"
+	return &literal
+}
+
+// Функция объединения метрик пакета с одинаковой идентичностью.
+// Одна строка таблицы не может быть обновлена запросом дважды,
+// поэтому значения метрик объединяются до выполнения запроса:
+// значения gauge заменяются, значения counter и гистограммы суммируются.
+// Пакет предварительно проверяется целиком функцией repository.ValidateMetrics,
+// поэтому объединение не прерывается на середине.
+// Возвращает уникальные метрики в порядке их первого появления.
+func mergeMetrics(metrics []view.Metric) ([]view.Metric, error) {
+	if err := repository.ValidateMetrics(metrics...); err != nil {
+		return nil, err
+	}
+
+	merged := make([]view.Metric, 0, len(metrics))
+	index := make(map[string]int, len(metrics))
+
+	for _, metric := range metrics {
+		key := metric.Key()
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, metric)
+			continue
+		}
+
+		switch metric.MType {
+		case view.KindCounter:
+			delta := *merged[i].Delta + *metric.Delta
+			merged[i].Delta = &delta
+		case view.KindHistogram:
+			histogram, err := merged[i].Histogram.Merge(metric.Histogram)
+			if err != nil {
+				return nil, err
+			}
+			merged[i].Histogram = histogram
+		default:
+			merged[i] = metric
+		}
+	}
+
+	return merged, nil
+}
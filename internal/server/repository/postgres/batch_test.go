@@ -0,0 +1,283 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Запросы записи метрик по одной, использовавшиеся до пакетной записи.
+// Сохранены для сравнения производительности в BenchmarkAddMetrics.
+const (
+	legacyQueryAdd = `INSERT INTO metrics (id, mtype, labels, value, delta, bounds, buckets, hcount, hsum)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (id, labels) DO UPDATE
+	SET 
+		value = EXCLUDED.value,
+		delta = CASE WHEN EXCLUDED.mtype = 'counter' THEN metrics.delta + EXCLUDED.delta ELSE EXCLUDED.delta END,
+		bounds = EXCLUDED.bounds,
+		buckets = CASE WHEN EXCLUDED.mtype = 'histogram' THEN ARRAY(
+			SELECT a + b
+			FROM unnest(metrics.buckets, EXCLUDED.buckets) WITH ORDINALITY AS t(a, b, n)
+			ORDER BY n
+		) ELSE EXCLUDED.buckets END,
+		hcount = CASE WHEN EXCLUDED.mtype = 'histogram' THEN metrics.hcount + EXCLUDED.hcount ELSE EXCLUDED.hcount END,
+		hsum = CASE WHEN EXCLUDED.mtype = 'histogram' THEN metrics.hsum + EXCLUDED.hsum ELSE EXCLUDED.hsum END
+	WHERE EXCLUDED.mtype <> 'histogram' OR metrics.bounds = EXCLUDED.bounds
+	RETURNING value, delta, bounds, buckets, hcount, hsum;`
+	legacyQueryAddSample = `INSERT INTO metric_samples (id, mtype, labels, value)
+	VALUES ($1, $2, $3, $4)`
+)
+
+// addMetricsLoop - реализация записи метрик по одной, использовавшаяся до пакетной записи.
+func (ms *MetricStorage) addMetricsLoop(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	ctx, cancle := context.WithTimeout(ctx, 3*time.Second)
+	defer cancle()
+	resutl := make([]view.Metric, 0, len(metrics))
+	tx, err := ms.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metrics {
+		metric := metrics[i]
+		var columns valueColumns
+		bounds, buckets, hcount, hsum := histogramArgs(metric.Histogram)
+		err = tx.QueryRow(
+			ctx,
+			legacyQueryAdd,
+			metric.ID,
+			metric.MType,
+			labelsArg(metric.Labels),
+			metric.Value,
+			metric.Delta,
+			bounds,
+			buckets,
+			hcount,
+			hsum,
+		).Scan(columns.dest()...)
+		if err != nil {
+			return nil, errors.Join(err, tx.Rollback(ctx))
+		}
+		columns.fill(&metric)
+		if value, ok := metric.SampleValue(); ok {
+			_, err = tx.Exec(ctx, legacyQueryAddSample, metric.ID, metric.MType, labelsArg(metric.Labels), value)
+			if err != nil {
+				return nil, errors.Join(err, tx.Rollback(ctx))
+			}
+		}
+		resutl = append(resutl, metric)
+	}
+
+	return resutl, tx.Commit(ctx)
+}
+
+func TestMergeMetrics(t *testing.T) {
+	type test struct {
+		name    string
+		metrics []view.Metric
+		want    []view.Metric
+		wantErr error
+	}
+
+	tests := []test{
+		{
+			name: "gauge replaced",
+			metrics: []view.Metric{
+				{ID: "g", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(1)},
+				{ID: "g", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(2)},
+			},
+			want: []view.Metric{
+				{ID: "g", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(2)},
+			},
+		},
+		{
+			name: "counter summed",
+			metrics: []view.Metric{
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(1)},
+				{ID: "g", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(1)},
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(2)},
+			},
+			want: []view.Metric{
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(3)},
+				{ID: "g", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(1)},
+			},
+		},
+		{
+			name: "different labels",
+			metrics: []view.Metric{
+				{
+					ID:     "c",
+					MType:  view.KindCounter,
+					Labels: view.Labels{"host": "a"},
+					Delta:  func(i int64) *int64 { return &i }(1),
+				},
+				{
+					ID:     "c",
+					MType:  view.KindCounter,
+					Labels: view.Labels{"host": "b"},
+					Delta:  func(i int64) *int64 { return &i }(2),
+				},
+			},
+			want: []view.Metric{
+				{
+					ID:     "c",
+					MType:  view.KindCounter,
+					Labels: view.Labels{"host": "a"},
+					Delta:  func(i int64) *int64 { return &i }(1),
+				},
+				{
+					ID:     "c",
+					MType:  view.KindCounter,
+					Labels: view.Labels{"host": "b"},
+					Delta:  func(i int64) *int64 { return &i }(2),
+				},
+			},
+		},
+		{
+			name: "histogram merged",
+			metrics: []view.Metric{
+				{ID: "h", MType: view.KindHistogram, Histogram: &view.Histogram{
+					Bounds: []float64{1}, Buckets: []uint64{1, 0}, Count: 1, Sum: 0.5,
+				}},
+				{ID: "h", MType: view.KindHistogram, Histogram: &view.Histogram{
+					Bounds: []float64{1}, Buckets: []uint64{0, 1}, Count: 1, Sum: 2,
+				}},
+			},
+			want: []view.Metric{
+				{ID: "h", MType: view.KindHistogram, Histogram: &view.Histogram{
+					Bounds: []float64{1}, Buckets: []uint64{1, 1}, Count: 2, Sum: 2.5,
+				}},
+			},
+		},
+		{
+			name: "histogram bounds mismatch",
+			metrics: []view.Metric{
+				{ID: "h", MType: view.KindHistogram, Histogram: &view.Histogram{
+					Bounds: []float64{1}, Buckets: []uint64{1, 0}, Count: 1, Sum: 0.5,
+				}},
+				{ID: "h", MType: view.KindHistogram, Histogram: &view.Histogram{
+					Bounds: []float64{2}, Buckets: []uint64{0, 1}, Count: 1, Sum: 3,
+				}},
+			},
+			wantErr: view.ErrHistogramBounds,
+		},
+		{
+			name: "type mismatch",
+			metrics: []view.Metric{
+				{ID: "m", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(1)},
+				{ID: "m", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(1)},
+			},
+			wantErr: repository.ErrWrongType,
+		},
+		{
+			name: "type mismatch after merged counter",
+			metrics: []view.Metric{
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(1)},
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(2)},
+				{ID: "c", MType: view.KindGauge, Value: func(i float64) *float64 { return &i }(1)},
+			},
+			wantErr: repository.ErrWrongType,
+		},
+		{
+			name: "counter without delta",
+			metrics: []view.Metric{
+				{ID: "c", MType: view.KindCounter, Delta: func(i int64) *int64 { return &i }(1)},
+				{ID: "c", MType: view.KindCounter},
+			},
+			wantErr: repository.ErrWrongType,
+		},
+		{
+			name: "unknown type",
+			metrics: []view.Metric{
+				{ID: "m", MType: "summary", Value: func(i float64) *float64 { return &i }(1)},
+			},
+			wantErr: repository.ErrWrongType,
+		},
+		{
+			name: "invalid histogram",
+			metrics: []view.Metric{
+				{ID: "h", MType: view.KindHistogram},
+			},
+			wantErr: view.ErrInvalidHistogram,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeMetrics(tt.metrics)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArrayLiteral(t *testing.T) {
+	format := func(v float64) string { return fmt.Sprint(v) }
+
+	assert.Nil(t, arrayLiteral(nil, format))
+	assert.Equal(t, "{}", *arrayLiteral([]float64{}, format))
+	assert.Equal(t, "{0.5,1,2.5}", *arrayLiteral([]float64{0.5, 1, 2.5}, format))
+}
+
+// Бенчмарк сравнивает пакетную запись метрик с записью по одной.
+// Выполняется только при заданной в TEST_DATABASE_DSN строке подключения к тестовой БД.
+func BenchmarkAddMetrics(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	ms, err := New(&Settings{ConnString: dsn})
+	require.NoError(b, err)
+	defer ms.Close()
+	require.NoError(b, ms.MigrateUp(context.Background()))
+
+	for _, size := range []int{10, 100, 1000} {
+		metrics := make([]view.Metric, 0, size)
+		for i := 0; i < size; i++ {
+			if i%2 == 0 {
+				value := float64(i)
+				metrics = append(metrics, view.Metric{
+					ID:    fmt.Sprintf("bench_gauge_%d", i),
+					MType: view.KindGauge,
+					Value: &value,
+				})
+				continue
+			}
+			delta := int64(i)
+			metrics = append(metrics, view.Metric{
+				ID:    fmt.Sprintf("bench_counter_%d", i),
+				MType: view.KindCounter,
+				Delta: &delta,
+			})
+		}
+
+		b.Run(fmt.Sprintf("loop/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err = ms.addMetricsLoop(context.Background(), metrics...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err = ms.AddMetrics(context.Background(), metrics...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Хелпер структура для сканирования значений метрики из строки БД.
+type valueColumns struct {
+	value   sql.NullFloat64
+	delta   sql.NullInt64
+	bounds  []float64
+	buckets []int64
+	hcount  sql.NullInt64
+	hsum    sql.NullFloat64
+	updated sql.NullTime
+}
+
+// Метод возвращает список указателей на поля для передачи в Scan.
+func (c *valueColumns) dest() []any {
+	return []any{&c.value, &c.delta, &c.bounds, &c.buckets, &c.hcount, &c.hsum, &c.updated}
+}
+
+// Метод заполняет значение метрики по отсканированным колонкам.
+func (c *valueColumns) fill(metric *view.Metric) {
+	if c.updated.Valid {
+		metric.LastUpdated = &c.updated.Time
+	}
+
+	switch {
+	case c.value.Valid:
+		metric.Value = &c.value.Float64
+	case c.delta.Valid:
+		metric.Delta = &c.delta.Int64
+	case c.hcount.Valid:
+		buckets := make([]uint64, len(c.buckets))
+		for i := range c.buckets {
+			buckets[i] = uint64(c.buckets[i])
+		}
+		metric.Histogram = &view.Histogram{
+			Bounds:  c.bounds,
+			Buckets: buckets,
+			Count:   uint64(c.hcount.Int64),
+			Sum:     c.hsum.Float64,
+		}
+	}
+}
+
+// Хелпер функция для преобразования меток в аргумент запроса.
+// Отсутствующие метки хранятся в БД как пустой JSON объект.
+func labelsArg(labels view.Labels) view.Labels {
+	if labels == nil {
+		return view.Labels{}
+	}
+	return labels
+}
+
+// Хелпер функция для преобразования меток, полученных из БД.
+// Пустой набор меток возвращается как nil.
+func scanLabels(labels view.Labels) view.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// Хелпер функция для преобразования гистограммы в аргументы запроса.
+// Для метрик без гистограммы возвращает nil значения.
+func histogramArgs(histogram *view.Histogram) ([]float64, []int64, *int64, *float64) {
+	if histogram == nil {
+		return nil, nil, nil, nil
+	}
+	buckets := make([]int64, len(histogram.Buckets))
+	for i := range histogram.Buckets {
+		buckets[i] = int64(histogram.Buckets[i])
+	}
+	count := int64(histogram.Count)
+	return histogram.Bounds, buckets, &count, &histogram.Sum
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// Набор тестов соответствия выполняется только при заданной в TEST_DATABASE_DSN
+// строке подключения к тестовой БД. Перед каждым тестом таблицы метрик очищаются.
+func TestMetricStorage_Conformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	ms, err := New(&Settings{ConnString: dsn})
+	if err != nil {
+		t.Skipf("test database is unavailable: %v", err)
+	}
+	t.Cleanup(ms.Close)
+	require.NoError(t, ms.MigrateUp(context.Background()))
+
+	storagetest.Run(t, func(t *testing.T) api.MetricsStorage {
+		_, err := ms.db.Exec(context.Background(), "TRUNCATE metrics, metric_samples")
+		require.NoError(t, err)
+		return ms
+	})
+}
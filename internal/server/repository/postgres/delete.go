@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/jackc/pgx/v5"
+)
+
+// Метод удаления метрик из БД вместе с их историей.
+// Метрики идентифицируются типом, ID и набором меток, значения метрик не учитываются.
+// Все метрики пакета удаляются в одной транзакции. Если хотя бы одна метрика не найдена,
+// транзакция откатывается и возвращается repository.ErrNotFound.
+func (ms *MetricStorage) DeleteMetrics(ctx context.Context, metrics ...view.Metric) error {
+	if err := repository.ValidateLabels(metrics...); err != nil {
+		return err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	return ms.withRetry(ctx, func(ctx context.Context) error {
+		return ms.deleteMetrics(ctx, metrics)
+	})
+}
+
+// Хелпер метод удаляющий метрики в одной транзакции.
+func (ms *MetricStorage) deleteMetrics(ctx context.Context, metrics []view.Metric) error {
+	// Начало транзакции
+	tx, err := ms.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleted := make(map[string]struct{}, len(metrics))
+	for i := range metrics {
+		// Повторяющиеся в пакете метрики удаляются один раз
+		key := metrics[i].MType + metrics[i].Key()
+		if _, ok := deleted[key]; ok {
+			continue
+		}
+		deleted[key] = struct{}{}
+
+		args := []any{metrics[i].ID, metrics[i].MType, labelsArg(metrics[i].Labels)}
+		tag, execErr := tx.Exec(ctx, queryDeleteMetric, args...)
+		if execErr != nil {
+			return errors.Join(execErr, tx.Rollback(ctx))
+		}
+		if tag.RowsAffected() == 0 {
+			return errors.Join(repository.ErrNotFound, tx.Rollback(ctx))
+		}
+		if _, execErr = tx.Exec(ctx, queryDeleteSamples, args...); execErr != nil {
+			return errors.Join(execErr, tx.Rollback(ctx))
+		}
+	}
+
+	// Коммитим транзакцию
+	return tx.Commit(ctx)
+}
+
+// Метод сброса значения счетчика в ноль.
+// Нулевое значение записывается в историю счетчика.
+// Возвращает обновленную метрику или repository.ErrNotFound, если счетчик не найден.
+func (ms *MetricStorage) ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error) {
+	if err := labels.Validate(); err != nil {
+		return view.Metric{}, err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	metric := view.Metric{
+		ID:     name,
+		MType:  view.KindCounter,
+		Labels: scanLabels(labels),
+	}
+	var (
+		delta     int64
+		updatedAt time.Time
+	)
+	err := ms.withRetry(ctx, func(ctx context.Context) error {
+		return ms.db.QueryRow(ctx, queryResetCounter, name, labelsArg(labels)).Scan(&delta, &updatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return metric, repository.ErrNotFound
+	}
+	if err != nil {
+		return metric, err
+	}
+
+	metric.Delta = &delta
+	metric.LastUpdated = &updatedAt
+	return metric, nil
+}
+
+// Метод удаления метрик, не обновлявшихся с момента before, вместе с их историей.
+// Возвращает удаленные метрики без значений.
+func (ms *MetricStorage) DeleteStaleMetrics(ctx context.Context, before time.Time) ([]view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	var deleted []view.Metric
+	err := ms.withRetry(ctx, func(ctx context.Context) error {
+		rows, err := ms.db.Query(ctx, queryDeleteStaleMetrics, before)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Результат повторной попытки заменяет результат предыдущей
+		deleted = make([]view.Metric, 0)
+		for rows.Next() {
+			var metric view.Metric
+			if err = rows.Scan(&metric.ID, &metric.MType, &metric.Labels); err != nil {
+				return err
+			}
+			metric.Labels = scanLabels(metric.Labels)
+			deleted = append(deleted, metric)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/jackc/pgx/v5"
+)
+
+// Метод получения истории значений метрики.
+// Возвращает значения метрики за интервал [query.From, query.To] в порядке возрастания времени.
+// Если query.Step больше нуля, то значения прореживаются средствами БД.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = query.Name другой тип.
+func (ms *MetricStorage) ReadHistory(ctx context.Context, query view.HistoryQuery) (view.Samples, error) {
+	ctx, cancle := withTimeout(ctx, ms.historyTimeout)
+	defer cancle()
+
+	var samples view.Samples
+	err := ms.withRetry(ctx, func(ctx context.Context) error {
+		// Проверка наличия метрики, так как пустая история не отличается от неизвестной метрики
+		var mtype string
+		readErr := ms.db.QueryRow(ctx, queryGetMetricType, query.Name, labelsArg(query.Labels)).Scan(&mtype)
+		switch {
+		case errors.Is(readErr, pgx.ErrNoRows):
+			return repository.ErrNotFound
+		case readErr != nil:
+			return readErr
+		case mtype != query.Kind:
+			return repository.ErrNotFound
+		}
+
+		if query.Step > 0 {
+			samples, readErr = ms.readHistoryDownsampled(ctx, query)
+		} else {
+			samples, readErr = ms.readHistory(ctx, query)
+		}
+		return readErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// Хелпер метод для получения истории значений метрики без прореживания.
+func (ms *MetricStorage) readHistory(ctx context.Context, query view.HistoryQuery) (view.Samples, error) {
+	rows, err := ms.db.Query(
+		ctx,
+		queryGetHistory,
+		query.Name,
+		query.Kind,
+		labelsArg(query.Labels),
+		query.From,
+		query.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make(view.Samples, 0)
+	for rows.Next() {
+		var sample view.Sample
+		if err = rows.Scan(&sample.Timestamp, &sample.Value); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// Хелпер метод для получения прореженной истории значений метрики.
+func (ms *MetricStorage) readHistoryDownsampled(
+	ctx context.Context,
+	query view.HistoryQuery,
+) (view.Samples, error) {
+	rows, err := ms.db.Query(
+		ctx,
+		queryGetHistoryDownsampled,
+		query.Name,
+		query.Kind,
+		labelsArg(query.Labels),
+		query.From,
+		query.To,
+		query.Step.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make(view.Samples, 0)
+	for rows.Next() {
+		var (
+			ts                  time.Time
+			avg, mn, mx, latest float64
+		)
+		if err = rows.Scan(&ts, &avg, &mn, &mx, &latest); err != nil {
+			return nil, err
+		}
+
+		sample := view.Sample{Timestamp: ts}
+		switch query.Aggregation {
+		case view.AggregationAvg:
+			sample.Value = avg
+		case view.AggregationMin:
+			sample.Value = mn
+		case view.AggregationMax:
+			sample.Value = mx
+		case view.AggregationLast:
+			sample.Value = latest
+		default:
+			return nil, view.ErrUnknownAggregation
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// Метод удаления из истории значений старше времени хранения.
+func (ms *MetricStorage) deleteOldSamples(ctx context.Context) error {
+	ctx, cancle := context.WithTimeout(ctx, retentionTimeout)
+	defer cancle()
+	_, err := ms.db.Exec(ctx, queryDeleteOldSamples, time.Now().Add(-ms.historyRetention))
+	return err
+}
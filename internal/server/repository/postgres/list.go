@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Метод получения страницы списка метрик.
+// Фильтрация, сортировка и ограничение размера страницы выполняются на стороне БД.
+// Возвращает ошибку, оборачивающую view.ErrInvalidListQuery, если параметры запроса некорректны.
+func (ms *MetricStorage) ListMetrics(ctx context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	if err := query.Normalize(); err != nil {
+		return view.MetricsPage{}, err
+	}
+	cursor, err := query.ParseCursor()
+	if err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
+	defer cancle()
+
+	sql, args := buildListQuery(query, cursor)
+
+	var metrics []view.Metric
+	err = ms.withRetry(ctx, func(ctx context.Context) error {
+		var readErr error
+		metrics, readErr = ms.queryMetrics(ctx, sql, args...)
+		return readErr
+	})
+	if err != nil {
+		return view.MetricsPage{}, listQueryError(err)
+	}
+
+	// Запрашивается на одну метрику больше, чтобы определить наличие следующей страницы
+	return query.Page(metrics), nil
+}
+
+// Хелпер функция для преобразования ошибки запроса списка метрик.
+// Регулярные выражения проверяются по синтаксису RE2, но выполняются движком регулярных выражений
+// PostgreSQL, который может отклонить выражение. Такая ошибка является ошибкой параметров запроса.
+func listQueryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.InvalidRegularExpression {
+		return fmt.Errorf("%w: %s", view.ErrInvalidListQuery, pgErr.Message)
+	}
+	return err
+}
+
+// Хелпер функция для построения запроса списка метрик.
+// Возвращает текст запроса и его аргументы.
+func buildListQuery(query view.ListQuery, cursor *view.ListCursor) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	// Добавление аргумента и возврат его плейсхолдера
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if query.Kind != "" {
+		conds = append(conds, "mtype = "+arg(query.Kind))
+	}
+	if query.Prefix != "" {
+		conds = append(conds, "starts_with(id, "+arg(query.Prefix)+")")
+	}
+	if query.Glob != "" {
+		conds = append(conds, "id ~ "+arg(view.GlobRegexp(query.Glob)))
+	}
+	if query.Regex != "" {
+		conds = append(conds, "id ~ "+arg(query.Regex))
+	}
+	if len(query.Labels) > 0 {
+		conds = append(conds, "labels @> "+arg(query.Labels)+"::jsonb")
+	}
+
+	// Колонки сортировки. Метрики с одинаковым значением поля сортировки упорядочиваются по имени и меткам
+	columns := []string{"id", "labels"}
+	switch query.Sort {
+	case view.SortByKind:
+		columns = append([]string{"mtype"}, columns...)
+	case view.SortByLastUpdated:
+		columns = append([]string{"updated_at"}, columns...)
+	}
+
+	// Страница начинается после позиции курсора
+	if cursor != nil {
+		values := make([]string, 0, len(columns))
+		switch query.Sort {
+		case view.SortByKind:
+			values = append(values, arg(cursor.Kind))
+		case view.SortByLastUpdated:
+			values = append(values, arg(time.Unix(0, cursor.LastUpdated)))
+		}
+		values = append(values, arg(cursor.ID), arg(labelsArg(cursor.Labels))+"::jsonb")
+
+		op := " > "
+		if query.Desc {
+			op = " < "
+		}
+		conds = append(conds, "("+strings.Join(columns, ", ")+")"+op+"("+strings.Join(values, ", ")+")")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(gueryGetAll)
+	if len(conds) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conds, " AND "))
+	}
+	sb.WriteString(" ORDER BY ")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(column)
+		if query.Desc {
+			sb.WriteString(" DESC")
+		}
+	}
+	sb.WriteString(" LIMIT ")
+	sb.WriteString(arg(query.Limit + 1))
+
+	return sb.String(), args
+}
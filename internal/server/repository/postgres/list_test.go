@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildListQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    view.ListQuery
+		cursor   *view.ListCursor
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "no filters",
+			query:    view.ListQuery{Sort: view.SortByID, Limit: 10},
+			wantSQL:  gueryGetAll + " ORDER BY id, labels LIMIT $1",
+			wantArgs: []any{11},
+		},
+		{
+			name: "filters",
+			query: view.ListQuery{
+				Kind:   view.KindGauge,
+				Prefix: "cpu",
+				Glob:   "cpu.*",
+				Regex:  "^cpu",
+				Labels: view.Labels{"host": "a"},
+				Sort:   view.SortByKind,
+				Desc:   true,
+				Limit:  5,
+			},
+			wantSQL: gueryGetAll + " WHERE mtype = $1 AND starts_with(id, $2) AND id ~ $3 AND id ~ $4" +
+				" AND labels @> $5::jsonb ORDER BY mtype DESC, id DESC, labels DESC LIMIT $6",
+			wantArgs: []any{
+				view.KindGauge,
+				"cpu",
+				`^cpu\..*$`,
+				"^cpu",
+				view.Labels{"host": "a"},
+				6,
+			},
+		},
+		{
+			name:   "cursor",
+			query:  view.ListQuery{Sort: view.SortByLastUpdated, Limit: 1},
+			cursor: &view.ListCursor{ID: "cpu", LastUpdated: 1700000000000000000},
+			wantSQL: gueryGetAll + " WHERE (updated_at, id, labels) > ($1, $2, $3::jsonb)" +
+				" ORDER BY updated_at, id, labels LIMIT $4",
+			wantArgs: []any{time.Unix(0, 1700000000000000000), "cpu", view.Labels{}, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := buildListQuery(tt.query, tt.cursor)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestListQueryError(t *testing.T) {
+	invalidRegex := &pgconn.PgError{
+		Code:    pgerrcode.InvalidRegularExpression,
+		Message: "invalid regular expression: invalid escape \\ sequence",
+	}
+	require.ErrorIs(t, listQueryError(invalidRegex), view.ErrInvalidListQuery)
+
+	other := &pgconn.PgError{Code: pgerrcode.UndefinedTable}
+	assert.Equal(t, error(other), listQueryError(other))
+}
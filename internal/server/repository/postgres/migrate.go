@@ -0,0 +1,274 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Идентификатор advisory lock, под которым применяются миграции.
+// Исключает одновременное применение миграций несколькими экземплярами сервера.
+const migrationsLockID int64 = 0x79615f6d6574
+
+// Встроенные файлы миграций.
+// Имя файла имеет формат <версия>_<название>.<up|down>.sql.
+//
+//go:embed migrations/*.sql
+//nolint:gochecknoglobals // embedded migrations
+var migrationsFS embed.FS
+
+var (
+	// Ошибка возвращается при откате миграции, если ни одна миграция не применена.
+	ErrNoMigrationsApplied  = errors.New("no migrations applied")
+	errInvalidMigrationName = errors.New("invalid migration file name")
+)
+
+// Тип migration описывает одну версию схемы БД.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// Тип MigrationStatus описывает состояние миграции в БД.
+type MigrationStatus struct {
+	Version   int       // Версия миграции
+	Name      string    // Название миграции
+	Applied   bool      // Флаг применения миграции
+	AppliedAt time.Time // Время применения миграции
+}
+
+// Метод применения всех не примененных миграций.
+// Миграции применяются по возрастанию версии, каждая в отдельной транзакции.
+func (ms *MetricStorage) MigrateUp(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return ms.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.version]; ok {
+				continue
+			}
+
+			slog.Info("Applying migration", slog.Int("version", m.version), slog.String("name", m.name))
+			err = runMigration(ctx, conn, m.up, queryInsertMigration, m.version, m.name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Метод отката последней примененной миграции.
+// Если ни одна миграция не применена, возвращает ErrNoMigrationsApplied.
+func (ms *MetricStorage) MigrateDown(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return ms.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+
+			slog.Info("Reverting migration", slog.Int("version", m.version), slog.String("name", m.name))
+			err = runMigration(ctx, conn, m.down, queryDeleteMigration, m.version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+			return nil
+		}
+		return ErrNoMigrationsApplied
+	})
+}
+
+// Метод получения состояния всех известных миграций.
+// Возвращает слайс состояний, упорядоченный по возрастанию версии.
+func (ms *MetricStorage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	err = ms.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			appliedAt, ok := applied[m.version]
+			result = append(result, MigrationStatus{
+				Version:   m.version,
+				Name:      m.name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Хелпер метод выполняющий fn на отдельном соединении под advisory lock.
+// Перед выполнением fn создает таблицу версий схемы при необходимости.
+func (ms *MetricStorage) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := ms.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// Блокировка удерживается до конца сессии, поэтому используется одно соединение
+	if _, err = conn.Exec(ctx, queryLockMigrations, migrationsLockID); err != nil {
+		return err
+	}
+	defer func() {
+		// Снятие блокировки не должно зависеть от отмены ctx
+		unlockCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, unlockErr := conn.Exec(unlockCtx, queryUnlockMigrations, migrationsLockID); unlockErr != nil {
+			slog.Error("migrations unlock error", "error", unlockErr)
+		}
+	}()
+
+	if _, err = conn.Exec(ctx, queryCreateMigrationsTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// Функция получения версий примененных миграций и времени их применения.
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, queryGetMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Функция выполнения SQL миграции и запроса записи версии в одной транзакции.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, sql string, versionQuery string, args ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Запрос без аргументов выполняется по простому протоколу,
+	// что позволяет передавать несколько выражений в одном запросе
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+	if _, err = tx.Exec(ctx, versionQuery, args...); err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+	return tx.Commit(ctx)
+}
+
+// Функция загрузки встроенных миграций.
+// Возвращает миграции, упорядоченные по возрастанию версии.
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, file := range files {
+		base := strings.TrimPrefix(file, "migrations/")
+
+		// Разбор имени файла
+		rest, direction, ok := cutDirection(base)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidMigrationName, base)
+		}
+		rawVersion, name, ok := strings.Cut(rest, "_")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidMigrationName, base)
+		}
+		version, err := strconv.Atoi(rawVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidMigrationName, base)
+		}
+
+		data, err := migrationsFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("%w: migration %d has no up or down file", errInvalidMigrationName, m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// Функция отделения направления миграции от имени файла.
+func cutDirection(file string) (string, string, bool) {
+	if rest, ok := strings.CutSuffix(file, ".up.sql"); ok {
+		return rest, "up", true
+	}
+	if rest, ok := strings.CutSuffix(file, ".down.sql"); ok {
+		return rest, "down", true
+	}
+	return "", "", false
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "migration versions must be sequential")
+		assert.NotEmpty(t, m.name)
+		assert.NotEmpty(t, m.up)
+		assert.NotEmpty(t, m.down)
+	}
+}
+
+func TestCutDirection(t *testing.T) {
+	type test struct {
+		name          string
+		file          string
+		wantRest      string
+		wantDirection string
+		wantOk        bool
+	}
+
+	tests := []test{
+		{
+			name:          "up",
+			file:          "0001_create.up.sql",
+			wantRest:      "0001_create",
+			wantDirection: "up",
+			wantOk:        true,
+		},
+		{
+			name:          "down",
+			file:          "0001_create.down.sql",
+			wantRest:      "0001_create",
+			wantDirection: "down",
+			wantOk:        true,
+		},
+		{
+			name:   "no direction",
+			file:   "0001_create.sql",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, direction, ok := cutDirection(tt.file)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantRest, rest)
+			assert.Equal(t, tt.wantDirection, direction)
+		})
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS metrics (
+	id VARCHAR(255) UNIQUE,
+	mtype VARCHAR(255),
+	value DOUBLE PRECISION,
+	delta BIGINT
+);
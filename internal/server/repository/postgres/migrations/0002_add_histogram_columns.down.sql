@@ -0,0 +1,6 @@
+DELETE FROM metrics WHERE mtype = 'histogram';
+ALTER TABLE metrics
+	DROP COLUMN IF EXISTS bounds,
+	DROP COLUMN IF EXISTS buckets,
+	DROP COLUMN IF EXISTS hcount,
+	DROP COLUMN IF EXISTS hsum;
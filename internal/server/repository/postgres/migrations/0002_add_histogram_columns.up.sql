@@ -0,0 +1,5 @@
+ALTER TABLE metrics
+	ADD COLUMN IF NOT EXISTS bounds DOUBLE PRECISION[],
+	ADD COLUMN IF NOT EXISTS buckets BIGINT[],
+	ADD COLUMN IF NOT EXISTS hcount BIGINT,
+	ADD COLUMN IF NOT EXISTS hsum DOUBLE PRECISION;
@@ -0,0 +1,5 @@
+-- Метрики с метками не могут быть представлены без них
+DELETE FROM metrics WHERE labels <> '{}'::jsonb;
+DROP INDEX IF EXISTS metrics_id_labels_key;
+ALTER TABLE metrics DROP COLUMN IF EXISTS labels;
+ALTER TABLE metrics ADD CONSTRAINT metrics_id_key UNIQUE (id);
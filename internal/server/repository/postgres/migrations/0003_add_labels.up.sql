@@ -0,0 +1,4 @@
+ALTER TABLE metrics
+	ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS metrics_id_labels_key ON metrics (id, labels);
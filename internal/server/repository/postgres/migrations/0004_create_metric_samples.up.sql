@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS metric_samples (
+	id VARCHAR(255) NOT NULL,
+	mtype VARCHAR(255) NOT NULL,
+	labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+	value DOUBLE PRECISION NOT NULL,
+	ts TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS metric_samples_series_ts_idx ON metric_samples (id, mtype, labels, ts);
+CREATE INDEX IF NOT EXISTS metric_samples_ts_idx ON metric_samples (ts);
@@ -0,0 +1,2 @@
+ALTER TABLE metrics
+	ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
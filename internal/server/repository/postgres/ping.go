@@ -2,12 +2,14 @@ package postgres
 
 import (
 	"context"
-	"time"
 )
 
 // Метод для проферки подключения к БД.
-func (ms *MetricStorage) Ping() error {
-	pingCtx, pingCancleCtx := context.WithTimeout(context.Background(), 1*time.Second)
+// Пока circuit breaker разомкнут, возвращает circuitbreaker.ErrOpen без обращения к БД.
+func (ms *MetricStorage) Ping(ctx context.Context) error {
+	pingCtx, pingCancleCtx := withTimeout(ctx, ms.pingTimeout)
 	defer pingCancleCtx()
-	return ms.db.Ping(pingCtx)
+	return ms.breaker.Execute(func() error {
+		return ms.db.Ping(pingCtx)
+	})
 }
@@ -2,25 +2,121 @@ package postgres
 
 const (
 	// Запрос для получения всех метрик в базе данных.
-	gueryGetAll = `SELECT id, mtype, value, delta FROM metrics`
+	gueryGetAll = `SELECT id, mtype, labels, value, delta, bounds, buckets, hcount, hsum, updated_at FROM metrics`
 	// Запрос для получения метрики по её имени и типу.
-	queryGetOne = `SELECT value, delta
+	queryGetOne = `SELECT value, delta, bounds, buckets, hcount, hsum, updated_at
 	FROM metrics
-	WHERE id = $1 AND mtype = $2
+	WHERE id = $1 AND mtype = $2 AND labels = $3
 	LIMIT 1`
-	// Запрос для добавления метрики и возврата её обновленного значения.
-	queryAdd = `INSERT INTO metrics (id, mtype, value, delta)
-	VALUES ($1, $2, $3, $4)
-	ON CONFLICT (id) DO UPDATE
-	SET 
-		value = EXCLUDED.value,
-		delta = CASE WHEN EXCLUDED.mtype = 'counter' THEN metrics.delta + EXCLUDED.delta ELSE EXCLUDED.delta END
-	RETURNING value, delta;`
-	// Запрос для проверки существования таблицы и её создания при необходимости.
-	queryCheckAndCreateDB = `CREATE TABLE IF NOT EXISTS metrics (
-		id VARCHAR(255) UNIQUE,
-		mtype VARCHAR(255),
-		value DOUBLE PRECISION,
-		delta BIGINT
+	// Запрос для пакетного добавления метрик и возврата их обновленных значений.
+	// Пакет передается массивами колонок и разворачивается в строки с помощью unnest.
+	// Корзины гистограмм суммируются только при совпадении границ,
+	// иначе строка гистограммы не возвращается.
+	// Строка метрики другого типа не обновляется и не возвращается.
+	// Обновленные значения метрик типа gauge и counter записываются в историю.
+	queryAddMetrics = `WITH batch AS (
+		SELECT * FROM unnest(
+			$1::varchar[],
+			$2::varchar[],
+			$3::jsonb[],
+			$4::double precision[],
+			$5::bigint[],
+			$6::text[],
+			$7::text[],
+			$8::bigint[],
+			$9::double precision[]
+		) AS t(id, mtype, labels, value, delta, bounds, buckets, hcount, hsum)
+	), upserted AS (
+		INSERT INTO metrics (id, mtype, labels, value, delta, bounds, buckets, hcount, hsum)
+		SELECT id, mtype, labels, value, delta, bounds::double precision[], buckets::bigint[], hcount, hsum
+		FROM batch
+		ON CONFLICT (id, labels) DO UPDATE
+		SET 
+			value = EXCLUDED.value,
+			delta = CASE WHEN EXCLUDED.mtype = 'counter' THEN metrics.delta + EXCLUDED.delta ELSE EXCLUDED.delta END,
+			bounds = EXCLUDED.bounds,
+			buckets = CASE WHEN EXCLUDED.mtype = 'histogram' THEN ARRAY(
+				SELECT a + b
+				FROM unnest(metrics.buckets, EXCLUDED.buckets) WITH ORDINALITY AS t(a, b, n)
+				ORDER BY n
+			) ELSE EXCLUDED.buckets END,
+			hcount = CASE WHEN EXCLUDED.mtype = 'histogram' THEN metrics.hcount + EXCLUDED.hcount ELSE EXCLUDED.hcount END,
+			hsum = CASE WHEN EXCLUDED.mtype = 'histogram' THEN metrics.hsum + EXCLUDED.hsum ELSE EXCLUDED.hsum END,
+			updated_at = now()
+		WHERE metrics.mtype = EXCLUDED.mtype
+			AND (EXCLUDED.mtype <> 'histogram' OR metrics.bounds = EXCLUDED.bounds)
+		RETURNING id, mtype, labels, value, delta, bounds, buckets, hcount, hsum, updated_at
+	), samples AS (
+		INSERT INTO metric_samples (id, mtype, labels, value)
+		SELECT id, mtype, labels, COALESCE(value, delta::double precision)
+		FROM upserted
+		WHERE mtype IN ('gauge', 'counter')
+	)
+	SELECT id, labels, value, delta, bounds, buckets, hcount, hsum, updated_at FROM upserted`
+	// Запрос для получения типа хранящейся метрики.
+	queryGetMetricType = `SELECT mtype FROM metrics WHERE id = $1 AND labels = $2`
+	// Запрос для удаления метрики.
+	queryDeleteMetric = `DELETE FROM metrics WHERE id = $1 AND mtype = $2 AND labels = $3`
+	// Запрос для удаления истории значений метрики.
+	queryDeleteSamples = `DELETE FROM metric_samples WHERE id = $1 AND mtype = $2 AND labels = $3`
+	// Запрос для сброса значения счетчика в ноль и записи нулевого значения в историю.
+	// Для отсутствующего счетчика строка не возвращается.
+	queryResetCounter = `WITH reset AS (
+		UPDATE metrics
+		SET delta = 0, updated_at = now()
+		WHERE id = $1 AND mtype = 'counter' AND labels = $2
+		RETURNING id, mtype, labels, delta, updated_at
+	), samples AS (
+		INSERT INTO metric_samples (id, mtype, labels, value)
+		SELECT id, mtype, labels, delta::double precision
+		FROM reset
+	)
+	SELECT delta, updated_at FROM reset`
+	// Запрос для удаления метрик, не обновлявшихся с указанного времени, вместе с их историей.
+	// Возвращает тип, ID и метки удаленных метрик.
+	queryDeleteStaleMetrics = `WITH stale AS (
+		DELETE FROM metrics
+		WHERE updated_at < $1
+		RETURNING id, mtype, labels
+	), samples AS (
+		DELETE FROM metric_samples AS s
+		USING stale
+		WHERE s.id = stale.id AND s.mtype = stale.mtype AND s.labels = stale.labels
+	)
+	SELECT id, mtype, labels FROM stale`
+	// Запрос для получения истории значений метрики за интервал.
+	queryGetHistory = `SELECT ts, value
+	FROM metric_samples
+	WHERE id = $1 AND mtype = $2 AND labels = $3 AND ts BETWEEN $4 AND $5
+	ORDER BY ts`
+	// Запрос для получения прореженной истории значений метрики за интервал.
+	// Для каждого интервала длиной $6 секунд вычисляются все поддерживаемые агрегаты.
+	queryGetHistoryDownsampled = `SELECT
+		to_timestamp(floor(extract(epoch FROM ts)::double precision / $6) * $6) AS bucket,
+		avg(value),
+		min(value),
+		max(value),
+		(array_agg(value ORDER BY ts DESC))[1]
+	FROM metric_samples
+	WHERE id = $1 AND mtype = $2 AND labels = $3 AND ts BETWEEN $4 AND $5
+	GROUP BY bucket
+	ORDER BY bucket`
+	// Запрос для удаления истории старше указанного времени.
+	queryDeleteOldSamples = `DELETE FROM metric_samples WHERE ts < $1`
+	// Запрос для создания таблицы версий схемы БД.
+	queryCreateMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
 	);`
+	// Запрос для получения примененных версий схемы БД.
+	queryGetMigrations = `SELECT version, applied_at FROM schema_migrations ORDER BY version`
+	// Запрос для записи примененной версии схемы БД.
+	queryInsertMigration = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	// Запрос для удаления записи об откаченной версии схемы БД.
+	queryDeleteMigration = `DELETE FROM schema_migrations WHERE version = $1`
+	// Запрос для захвата блокировки применения миграций.
+	queryLockMigrations = `SELECT pg_advisory_lock($1)`
+	// Запрос для освобождения блокировки применения миграций.
+	queryUnlockMigrations = `SELECT pg_advisory_unlock($1)`
 )
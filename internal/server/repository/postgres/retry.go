@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Хелпер метод для выполнения операции с БД с повторами при временных ошибках.
+// Операция выполняется через circuit breaker: пока он разомкнут,
+// операция не выполняется и возвращается circuitbreaker.ErrOpen.
+// Интервал между повторами удваивается после каждой попытки, но не превышает retryMaxWaitTime.
+// Если временная ошибка не устранена после всех повторов, возвращает ее обернутой в repository.ErrUnavailable.
+func (ms *MetricStorage) withRetry(ctx context.Context, op func(ctx context.Context) error) error {
+	wait := ms.retryInterval
+	for attempt := 0; ; attempt++ {
+		err := ms.breaker.Execute(func() error {
+			return op(ctx)
+		})
+		if err == nil || !isTransient(err) {
+			return err
+		}
+		if attempt >= ms.retryCount {
+			return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+		}
+
+		slog.Warn(
+			"transient DB error, retrying",
+			slog.Int("attempt", attempt+1),
+			slog.Duration("wait", wait),
+			slog.String("error", err.Error()),
+		)
+
+		// Ожидание следующей попытки
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+		case <-timer.C:
+		}
+
+		wait *= 2
+		if ms.retryMaxWaitTime > 0 {
+			wait = min(wait, ms.retryMaxWaitTime)
+		}
+	}
+}
+
+// Функция классификации ошибок БД.
+// Возвращает true для ошибок, после которых операцию можно повторить:
+// ошибок подключения, сбоев сериализации транзакций и перезапуска сервера БД.
+// Ошибки отмены контекста временными не считаются.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgerrcode.IsConnectionException(pgErr.Code) ||
+			pgErr.Code == pgerrcode.SerializationFailure ||
+			pgErr.Code == pgerrcode.DeadlockDetected ||
+			pgErr.Code == pgerrcode.AdminShutdown ||
+			pgErr.Code == pgerrcode.CrashShutdown ||
+			pgErr.Code == pgerrcode.CannotConnectNow
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return pgconn.SafeToRetry(err) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "connection exception",
+			err:  &pgconn.PgError{Code: pgerrcode.ConnectionFailure},
+			want: true,
+		},
+		{
+			name: "serialization failure",
+			err:  fmt.Errorf("tx: %w", &pgconn.PgError{Code: pgerrcode.SerializationFailure}),
+			want: true,
+		},
+		{
+			name: "admin shutdown",
+			err:  &pgconn.PgError{Code: pgerrcode.AdminShutdown},
+			want: true,
+		},
+		{
+			name: "unique violation",
+			err:  &pgconn.PgError{Code: pgerrcode.UniqueViolation},
+			want: false,
+		},
+		{
+			name: "no rows",
+			err:  pgx.ErrNoRows,
+			want: false,
+		},
+		{
+			name: "unexpected EOF",
+			err:  io.ErrUnexpectedEOF,
+			want: true,
+		},
+		{
+			name: "context canceled",
+			err:  context.Canceled,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+		})
+	}
+}
+
+func TestMetricStorage_withRetry(t *testing.T) {
+	transient := &pgconn.PgError{Code: pgerrcode.ConnectionFailure}
+
+	tests := []struct {
+		name            string
+		errs            []error
+		wantCalls       int
+		wantErr         error
+		wantUnavailable bool
+	}{
+		{
+			name:      "success",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "transient then success",
+			errs:      []error{transient, transient, nil},
+			wantCalls: 3,
+		},
+		{
+			name:            "retries exhausted",
+			errs:            []error{transient, transient, transient, transient},
+			wantCalls:       3,
+			wantErr:         transient,
+			wantUnavailable: true,
+		},
+		{
+			name:      "permanent error",
+			errs:      []error{pgx.ErrNoRows},
+			wantCalls: 1,
+			wantErr:   pgx.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &MetricStorage{
+				retryCount:    2,
+				retryInterval: time.Millisecond,
+				breaker: circuitbreaker.New(circuitbreaker.Settings{
+					FailureThreshold: 10,
+					IsFailure:        isTransient,
+				}),
+			}
+
+			calls := 0
+			err := ms.withRetry(context.Background(), func(_ context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantUnavailable, errors.Is(err, repository.ErrUnavailable))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMetricStorage_withRetryBreakerOpen(t *testing.T) {
+	ms := &MetricStorage{
+		retryCount:    5,
+		retryInterval: time.Millisecond,
+		breaker: circuitbreaker.New(circuitbreaker.Settings{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Hour,
+			IsFailure:        isTransient,
+		}),
+	}
+
+	calls := 0
+	err := ms.withRetry(context.Background(), func(_ context.Context) error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+
+	// После размыкания повторы прекращаются
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	assert.Equal(t, 2, calls)
+}
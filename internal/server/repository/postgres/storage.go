@@ -2,30 +2,80 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"log/slog"
 	"time"
 
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Интервал между проверками истории на устаревшие значения.
+const retentionCheckInterval = time.Minute
+
+// Время выполнения удаления устаревших значений истории.
+const retentionTimeout = 10 * time.Second
+
+// Тип Settings используется для хранения настроек хранилища метрик.
+// Нулевое время выполнения операции не ограничивает её, кроме как контекстом вызова.
+type Settings struct {
+	ConnString       string        // Строка подключения к БД
+	HistoryRetention time.Duration // Время хранения истории значений. 0 - история хранится бессрочно
+	ReadTimeout      time.Duration // Время выполнения чтения метрик
+	WriteTimeout     time.Duration // Время выполнения записи метрик
+	HistoryTimeout   time.Duration // Время выполнения чтения истории метрик
+	PingTimeout      time.Duration // Время выполнения проверки подключения
+	RetryCount       int           // Количество повторов операции при временных ошибках БД
+	RetryInterval    time.Duration // Интервал перед первым повтором
+	RetryMaxWaitTime time.Duration // Максимальный интервал между повторами
+	BreakerThreshold int           // Кол-во временных ошибок подряд, после которого операции отклоняются
+	BreakerTimeout   time.Duration // Время отклонения операций перед пробным обращением к БД
+}
+
 // Реализация хранилища метрик в таблицах PostgreSQL.
+// Помимо последних значений метрик хранит историю их изменений.
+// Операции, завершившиеся временной ошибкой БД, повторяются.
+// При продолжительной недоступности БД операции отклоняются с ошибкой circuitbreaker.ErrOpen.
 // Экземпляр должен создаваться с помощью New.
 type MetricStorage struct {
-	db *pgxpool.Pool
+	db               *pgxpool.Pool
+	historyRetention time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	historyTimeout   time.Duration
+	pingTimeout      time.Duration
+	retryCount       int
+	retryInterval    time.Duration
+	retryMaxWaitTime time.Duration
+	breaker          *circuitbreaker.CircuitBreaker
 }
 
 // Функция фабрика для создания нового экземпляра MetricStorage.
-// Принимает строку подключения к БД.
+// Принимает настройки хранилища.
 // В случае ошибки возвращает nil и ошибку.
 // В случае успеха возвращает новый экземпляр MetricStorage и nil.
-func New(conn string) (*MetricStorage, error) {
-	ms := &MetricStorage{}
+func New(settings *Settings) (*MetricStorage, error) {
+	ms := &MetricStorage{
+		historyRetention: settings.HistoryRetention,
+		readTimeout:      settings.ReadTimeout,
+		writeTimeout:     settings.WriteTimeout,
+		historyTimeout:   settings.HistoryTimeout,
+		pingTimeout:      settings.PingTimeout,
+		retryCount:       settings.RetryCount,
+		retryInterval:    settings.RetryInterval,
+		retryMaxWaitTime: settings.RetryMaxWaitTime,
+		breaker: circuitbreaker.New(circuitbreaker.Settings{
+			FailureThreshold: settings.BreakerThreshold,
+			OpenTimeout:      settings.BreakerTimeout,
+			IsFailure:        isTransient,
+		}),
+	}
 	// Создание экземпляра DB
-	poolConfig, err := pgxpool.ParseConfig(conn)
+	poolConfig, err := pgxpool.ParseConfig(settings.ConnString)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +88,7 @@ func New(conn string) (*MetricStorage, error) {
 	ms.db = db
 
 	// Проверка подключения
-	err = ms.Ping()
+	err = ms.Ping(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -48,131 +98,262 @@ func New(conn string) (*MetricStorage, error) {
 
 // Метод запускающий сервис БД.
 // Блокирует поток исполнения до закрытия контекста ctx.
+// Если задано время хранения истории, периодически удаляет устаревшие значения.
 // В случае невозможности подключения к бд возвращает ошибку.
 func (ms *MetricStorage) Start(ctx context.Context) error {
-	// Проверяем есть ли таблица в бд и при необходимости создаем её
-	err := ms.checkAndCreateTable()
+	// Применяем миграции схемы БД
+	err := ms.MigrateUp(ctx)
 	if err != nil {
 		return err
 	}
 
-	// TODO: Компиляция запросов
+	// Без времени хранения истории канал остается nil и никогда не срабатывает
+	var cleanup <-chan time.Time
+	if ms.historyRetention > 0 {
+		ticker := time.NewTicker(retentionCheckInterval)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
 
-	// Ожидание завершения контекста
-	<-ctx.Done()
+	for {
+		select {
+		// Ожидание завершения контекста
+		case <-ctx.Done():
+			ms.db.Close()
+			return nil
+		case <-cleanup:
+			if err = ms.deleteOldSamples(ctx); err != nil {
+				slog.Error("history cleanup error", "error", err)
+			}
+		}
+	}
+}
+
+// Метод закрытия соединений с БД.
+// Используется, если хранилище создано без запуска сервиса методом Start.
+func (ms *MetricStorage) Close() {
 	ms.db.Close()
-	return nil
 }
 
 // Метод добавляющий метрики в БД.
 // Принимает слайс метрик, которые необходимо добавить в БД.
-// Возвращает список обновленных метрик и nil.
+// Метрики с одинаковой идентичностью объединяются и записываются одним запросом.
+// Обновленные значения метрик типа gauge и counter записываются в историю.
+// Возвращает список обновленных метрик в порядке переданных.
+// Для повторяющихся в пакете метрик возвращается значение после применения всего пакета.
 // В случае ошибки возвращает nil и ошибку.
-func (ms *MetricStorage) AddMetrics(metrics ...view.Metric) ([]view.Metric, error) {
-	ctx, cancle := context.WithTimeout(context.TODO(), 3*time.Second)
+func (ms *MetricStorage) AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
 	defer cancle()
-	// Создание слайса возвращаемых метрик
+
+	// Проверка и объединение повторяющихся метрик
+	merged, err := mergeMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	// Подготовка колонок пакета
+	batch := newBatchColumns(len(merged))
+	for i := range merged {
+		if err = batch.add(merged[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Запись пакета
+	var updated map[string]view.Metric
+	err = ms.withRetry(ctx, func(ctx context.Context) error {
+		var addErr error
+		updated, addErr = ms.addBatch(ctx, merged, batch)
+		return addErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Сопоставление обновленных значений с переданными метриками
 	resutl := make([]view.Metric, 0, len(metrics))
+	for i := range metrics {
+		metric := updated[metrics[i].Key()]
+		metric.MType = metrics[i].MType
+		resutl = append(resutl, metric)
+	}
+
+	return resutl, nil
+}
+
+// Хелпер метод записывающий пакет метрик в одной транзакции.
+// merged - уникальные метрики пакета, batch - их колонки.
+// Возвращает обновленные метрики по ключу идентичности.
+func (ms *MetricStorage) addBatch(
+	ctx context.Context,
+	merged []view.Metric,
+	batch *batchColumns,
+) (map[string]view.Metric, error) {
 	// Начало транзакции
 	tx, err := ms.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// записываем каждую метрику
-	for i := range metrics {
-		// подготовка переменных
-		var value sql.NullFloat64
-		var delta sql.NullInt64
-		metric := metrics[i]
-		// выполнение запроса
-		err = tx.QueryRow(ctx, queryAdd, metric.ID, metric.MType, metric.Value, metric.Delta).
-			Scan(&value, &delta)
-		if err != nil {
-			return nil, errors.Join(err, tx.Rollback(ctx))
-		}
-		// Сохранение ответа
-		if value.Valid {
-			metric.Value = &value.Float64
-		} else if delta.Valid {
-			metric.Delta = &delta.Int64
+	// Подготовка запроса. Для соединения, на котором запрос уже подготовлен,
+	// повторная подготовка не выполняет обращения к БД
+	if _, err = tx.Prepare(ctx, stmtAddMetrics, queryAddMetrics); err != nil {
+		return nil, errors.Join(err, tx.Rollback(ctx))
+	}
+
+	// Выполнение запроса
+	updated, err := scanUpdated(tx.Query(ctx, stmtAddMetrics, batch.args()...))
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback(ctx))
+	}
+
+	// Проверка, что все метрики пакета обновлены
+	for i := range merged {
+		if _, ok := updated[merged[i].Key()]; ok {
+			continue
 		}
-		resutl = append(resutl, metric)
+		err = notUpdatedError(ctx, tx, merged[i])
+		return nil, errors.Join(err, tx.Rollback(ctx))
 	}
 
 	// Коммитим транзакцию
-	return resutl, tx.Commit(ctx)
+	return updated, tx.Commit(ctx)
+}
+
+// Хелпер функция для определения причины, по которой строка метрики не обновлена.
+// Возвращает repository.ErrWrongType, если хранится метрика другого типа,
+// и view.ErrHistogramBounds, если не совпали границы корзин гистограммы.
+func notUpdatedError(ctx context.Context, tx pgx.Tx, metric view.Metric) error {
+	var mtype string
+	err := tx.QueryRow(ctx, queryGetMetricType, metric.ID, labelsArg(metric.Labels)).Scan(&mtype)
+	switch {
+	case err != nil:
+		return err
+	case mtype != metric.MType:
+		return repository.ErrWrongType
+	case metric.MType == view.KindHistogram:
+		return view.ErrHistogramBounds
+	default:
+		return pgx.ErrNoRows
+	}
+}
+
+// Хелпер функция для сканирования результата запроса queryAddMetrics.
+// Возвращает обновленные метрики по ключу идентичности.
+// Тип метрик не заполняется.
+func scanUpdated(rows pgx.Rows, err error) (map[string]view.Metric, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updated := make(map[string]view.Metric)
+	for rows.Next() {
+		var (
+			metric  view.Metric
+			labels  view.Labels
+			columns valueColumns
+		)
+		err = rows.Scan(append([]any{&metric.ID, &labels}, columns.dest()...)...)
+		if err != nil {
+			return nil, err
+		}
+		metric.Labels = scanLabels(labels)
+		columns.fill(&metric)
+		updated[metric.Key()] = metric
+	}
+
+	return updated, rows.Err()
 }
 
 // Метод получения метрики из хранилища.
-// Принимает тип метрики и ID метрики.
-// Возвращает ошибку в случае если метрика не найдена или у метрики с ID = name другой тип.
+// Принимает тип метрики, ID метрики и набор её меток.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = name другой тип.
 // Так же ошибка вернется, если не удалось установить соединение с БД.
-func (ms *MetricStorage) GetMetric(kind string, name string) (view.Metric, error) {
+func (ms *MetricStorage) GetMetric(
+	ctx context.Context,
+	kind string,
+	name string,
+	labels view.Labels,
+) (view.Metric, error) {
 	var metric view.Metric
 	metric.ID = name
 	metric.MType = kind
+	metric.Labels = scanLabels(labels)
 	// Подготовка переменных
-	var value sql.NullFloat64
-	var delta sql.NullInt64
+	var columns valueColumns
 	// Выполнение запроса
-	ctx, cancle := context.WithTimeout(context.TODO(), 1*time.Second)
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
 	defer cancle()
-	err := ms.db.QueryRow(ctx, queryGetOne, name, kind).Scan(&value, &delta)
-	// Проверка переменных на валидность
-	if value.Valid {
-		metric.Value = &value.Float64
-	} else if delta.Valid {
-		metric.Delta = &delta.Int64
+	err := ms.withRetry(ctx, func(ctx context.Context) error {
+		return ms.db.QueryRow(ctx, queryGetOne, name, kind, labelsArg(labels)).Scan(columns.dest()...)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return metric, repository.ErrNotFound
 	}
+	// Проверка переменных на валидность
+	columns.fill(&metric)
 	return metric, err
 }
 
 // Метод получения всех метрик из хранилища.
 // Возврашает слайс метрик и ошибку.
-func (ms *MetricStorage) ReadAllMetrics() ([]view.Metric, error) {
+func (ms *MetricStorage) ReadAllMetrics(ctx context.Context) ([]view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
+	defer cancle()
+
+	var metrics []view.Metric
+	err := ms.withRetry(ctx, func(ctx context.Context) error {
+		var readErr error
+		metrics, readErr = ms.queryMetrics(ctx, gueryGetAll)
+		return readErr
+	})
+	return metrics, err
+}
+
+// Хелпер метод выполняющий запрос метрик.
+// Запрос должен возвращать колонки id, mtype, labels и колонки значений метрики.
+func (ms *MetricStorage) queryMetrics(ctx context.Context, query string, args ...any) ([]view.Metric, error) {
 	metrics := make([]view.Metric, 0)
 	// Выполнение запроса
-	ctx, cancle := context.WithTimeout(context.TODO(), 1*time.Second)
-	defer cancle()
-	rows, err := ms.db.Query(ctx, gueryGetAll)
+	rows, err := ms.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	// Проход по всем полученным строкам
 	for rows.Next() {
 		// Подготовка переменных
 		var (
-			metric view.Metric
-			id     string
-			mtype  string
-			value  sql.NullFloat64
-			delta  sql.NullInt64
+			metric  view.Metric
+			id      string
+			mtype   string
+			labels  view.Labels
+			columns valueColumns
 		)
 		// Сканирование строки
-		err = rows.Scan(&id, &mtype, &value, &delta)
+		err = rows.Scan(append([]any{&id, &mtype, &labels}, columns.dest()...)...)
 		if err != nil {
 			return nil, err
 		}
 		// Заполнение полей метрики
 		metric.ID = id
 		metric.MType = mtype
-		if value.Valid {
-			metric.Value = &value.Float64
-		} else if delta.Valid {
-			metric.Delta = &delta.Int64
-		}
+		metric.Labels = scanLabels(labels)
+		columns.fill(&metric)
 
 		metrics = append(metrics, metric)
 	}
 
-	return metrics, nil
+	return metrics, rows.Err()
 }
 
-func (ms *MetricStorage) checkAndCreateTable() error {
-	slog.Debug("Creating table")
-	ctx, cancle := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancle()
-	_, err := ms.db.Exec(ctx, queryCheckAndCreateDB)
-	return err
+// Хелпер функция ограничивающая время выполнения операции с БД.
+// Нулевое значение timeout ограничивает операцию только контекстом ctx.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
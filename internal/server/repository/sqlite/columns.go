@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Хелпер структура для сканирования значений метрики из строки БД.
+// Границы и корзины гистограмм хранятся в БД как JSON массивы.
+type valueColumns struct {
+	value   sql.NullFloat64
+	delta   sql.NullInt64
+	bounds  sql.NullString
+	buckets sql.NullString
+	hcount  sql.NullInt64
+	hsum    sql.NullFloat64
+	updated sql.NullInt64
+}
+
+// Метод возвращает список указателей на поля для передачи в Scan.
+func (c *valueColumns) dest() []any {
+	return []any{&c.value, &c.delta, &c.bounds, &c.buckets, &c.hcount, &c.hsum, &c.updated}
+}
+
+// Метод заполняет значение метрики по отсканированным колонкам.
+func (c *valueColumns) fill(metric *view.Metric) error {
+	// Время обновления хранится в наносекундах Unix
+	if c.updated.Valid && c.updated.Int64 > 0 {
+		updated := time.Unix(0, c.updated.Int64)
+		metric.LastUpdated = &updated
+	}
+
+	switch {
+	case c.value.Valid:
+		metric.Value = &c.value.Float64
+	case c.delta.Valid:
+		metric.Delta = &c.delta.Int64
+	case c.hcount.Valid:
+		histogram := &view.Histogram{
+			Count: uint64(c.hcount.Int64),
+			Sum:   c.hsum.Float64,
+		}
+		if err := json.Unmarshal([]byte(c.bounds.String), &histogram.Bounds); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(c.buckets.String), &histogram.Buckets); err != nil {
+			return err
+		}
+		metric.Histogram = histogram
+	}
+	return nil
+}
+
+// Хелпер функция для преобразования меток в аргумент запроса.
+// Метки хранятся в БД как JSON объект с отсортированными ключами,
+// поэтому одинаковые наборы меток дают одинаковую строку.
+// Отсутствующие метки хранятся как пустой JSON объект.
+func labelsArg(labels view.Labels) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(labels))
+	return string(data), err
+}
+
+// Хелпер функция для преобразования меток, полученных из БД.
+// Пустой набор меток возвращается как nil.
+func scanLabels(raw string) (view.Labels, error) {
+	var labels view.Labels
+	if err := json.Unmarshal([]byte(raw), (*map[string]string)(&labels)); err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
+
+// Хелпер функция для преобразования значения метрики в аргументы запроса.
+// Возвращает значения колонок value, delta, bounds, buckets, hcount и hsum.
+// Для отсутствующих значений возвращает nil.
+func valueArgs(metric view.Metric) ([]any, error) {
+	args := []any{metric.Value, metric.Delta, nil, nil, nil, nil}
+	if metric.Histogram == nil {
+		return args, nil
+	}
+
+	bounds, err := json.Marshal(metric.Histogram.Bounds)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := json.Marshal(metric.Histogram.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	args[2], args[3] = string(bounds), string(buckets)
+	args[4], args[5] = int64(metric.Histogram.Count), metric.Histogram.Sum
+	return args, nil
+}
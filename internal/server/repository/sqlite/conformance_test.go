@@ -0,0 +1,16 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/storagetest"
+)
+
+func TestMetricStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) api.MetricsStorage {
+		return newTestStorage(t, Settings{HistoryDepth: 10})
+	})
+}
@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Метод удаления метрик из БД вместе с их историей.
+// Метрики идентифицируются типом, ID и набором меток, значения метрик не учитываются.
+// Все метрики пакета удаляются в одной транзакции. Если хотя бы одна метрика не найдена,
+// транзакция откатывается и возвращается repository.ErrNotFound.
+func (ms *MetricStorage) DeleteMetrics(ctx context.Context, metrics ...view.Metric) error {
+	if err := repository.ValidateLabels(metrics...); err != nil {
+		return err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	// Начало транзакции
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = deleteMetrics(ctx, tx, metrics); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	// Коммитим транзакцию
+	return tx.Commit()
+}
+
+// Хелпер функция для удаления метрик в транзакции tx.
+func deleteMetrics(ctx context.Context, tx *sql.Tx, metrics []view.Metric) error {
+	deleted := make(map[string]struct{}, len(metrics))
+	for i := range metrics {
+		// Повторяющиеся в пакете метрики удаляются один раз
+		key := metrics[i].MType + metrics[i].Key()
+		if _, ok := deleted[key]; ok {
+			continue
+		}
+		deleted[key] = struct{}{}
+
+		labels, err := labelsArg(metrics[i].Labels)
+		if err != nil {
+			return err
+		}
+		args := []any{metrics[i].ID, metrics[i].MType, labels}
+
+		res, err := tx.ExecContext(ctx, queryDeleteMetric, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return repository.ErrNotFound
+		}
+		if _, err = tx.ExecContext(ctx, queryDeleteSamples, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Метод сброса значения счетчика в ноль.
+// Нулевое значение записывается в историю счетчика.
+// Возвращает обновленную метрику или repository.ErrNotFound, если счетчик не найден.
+func (ms *MetricStorage) ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error) {
+	if err := labels.Validate(); err != nil {
+		return view.Metric{}, err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	metric := view.Metric{ID: name, MType: view.KindCounter}
+	if len(labels) > 0 {
+		metric.Labels = labels
+	}
+
+	rawLabels, err := labelsArg(labels)
+	if err != nil {
+		return metric, err
+	}
+
+	// Начало транзакции
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return metric, err
+	}
+
+	now := time.Now()
+
+	var delta int64
+	err = tx.QueryRowContext(ctx, queryResetCounter, now.UnixNano(), name, rawLabels).Scan(&delta)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = repository.ErrNotFound
+	}
+	if err == nil {
+		err = ms.putSample(ctx, tx, name, view.KindCounter, rawLabels, float64(delta), now.UnixNano())
+	}
+	if err != nil {
+		return metric, errors.Join(err, tx.Rollback())
+	}
+
+	metric.Delta = &delta
+	metric.LastUpdated = &now
+	// Коммитим транзакцию
+	return metric, tx.Commit()
+}
+
+// Метод удаления метрик, не обновлявшихся с момента before, вместе с их историей.
+// Возвращает удаленные метрики без значений.
+func (ms *MetricStorage) DeleteStaleMetrics(ctx context.Context, before time.Time) ([]view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	// Начало транзакции
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// История удаляется первой, пока строки метрик еще существуют
+	if _, err = tx.ExecContext(ctx, queryDeleteStaleSamples, before.UnixNano()); err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	deleted, err := deleteStale(ctx, tx, before)
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+
+	// Коммитим транзакцию.
+	// Если коммит не удался, метрики не удалены, и возвращать их нельзя.
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// Хелпер функция для удаления метрик, не обновлявшихся с момента before, в транзакции tx.
+// Возвращает удаленные метрики без значений.
+func deleteStale(ctx context.Context, tx *sql.Tx, before time.Time) ([]view.Metric, error) {
+	rows, err := tx.QueryContext(ctx, queryDeleteStaleMetrics, before.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deleted := make([]view.Metric, 0)
+	for rows.Next() {
+		var (
+			metric view.Metric
+			labels string
+		)
+		if err = rows.Scan(&metric.ID, &metric.MType, &labels); err != nil {
+			return nil, err
+		}
+		if metric.Labels, err = scanLabels(labels); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, metric)
+	}
+	return deleted, rows.Err()
+}
@@ -0,0 +1,27 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"database/sql"
+	"regexp"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+//nolint:gochecknoinits // driver registration
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// Выражение X REGEXP Y вызывает функцию regexp(Y, X)
+			return conn.RegisterFunc("regexp", func(pattern, value string) (bool, error) {
+				return regexp.MatchString(pattern, value)
+			}, true)
+		},
+	})
+}
+
+// Функция проверки доступности драйвера SQLite.
+func checkDriver() error {
+	return nil
+}
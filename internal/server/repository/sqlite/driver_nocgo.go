@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package sqlite
+
+import "errors"
+
+// Драйвер SQLite использует cgo, поэтому в сборке с CGO_ENABLED=0 хранилище недоступно.
+var errNoCGO = errors.New("sqlite storage requires a cgo build (CGO_ENABLED=1)")
+
+// Функция проверки доступности драйвера SQLite.
+func checkDriver() error {
+	return errNoCGO
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Метод получения истории значений метрики.
+// Возвращает значения метрики за интервал [query.From, query.To] в порядке возрастания времени.
+// Если query.Step больше нуля, то значения прореживаются.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = query.Name другой тип.
+func (ms *MetricStorage) ReadHistory(ctx context.Context, query view.HistoryQuery) (view.Samples, error) {
+	ctx, cancle := withTimeout(ctx, ms.historyTimeout)
+	defer cancle()
+
+	labels, err := labelsArg(query.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверка наличия метрики, так как пустая история не отличается от неизвестной метрики
+	var mtype string
+	err = ms.db.QueryRowContext(ctx, queryGetMetricType, query.Name, labels).Scan(&mtype)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, repository.ErrNotFound
+	case err != nil:
+		return nil, err
+	case mtype != query.Kind:
+		return nil, repository.ErrNotFound
+	}
+
+	rows, err := ms.db.QueryContext(
+		ctx,
+		queryGetHistory,
+		query.Name,
+		query.Kind,
+		labels,
+		query.From.UnixNano(),
+		query.To.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make(view.Samples, 0)
+	for rows.Next() {
+		var (
+			ts     int64
+			sample view.Sample
+		)
+		if err = rows.Scan(&ts, &sample.Value); err != nil {
+			return nil, err
+		}
+		sample.Timestamp = time.Unix(0, ts)
+		samples = append(samples, sample)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if query.Step > 0 {
+		return view.Downsample(samples, query.Step, query.Aggregation)
+	}
+	return samples, nil
+}
+
+// Метод удаления значений истории старше времени хранения.
+func (ms *MetricStorage) deleteOldSamples(ctx context.Context) error {
+	ctx, cancle := context.WithTimeout(ctx, retentionTimeout)
+	defer cancle()
+	_, err := ms.db.ExecContext(ctx, queryDeleteOldSamples, time.Now().Add(-ms.historyRetention).UnixNano())
+	return err
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Метод получения страницы списка метрик.
+// Фильтрация, сортировка и ограничение размера страницы выполняются на стороне БД.
+// Возвращает ошибку, оборачивающую view.ErrInvalidListQuery, если параметры запроса некорректны.
+func (ms *MetricStorage) ListMetrics(ctx context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	if err := query.Normalize(); err != nil {
+		return view.MetricsPage{}, err
+	}
+	cursor, err := query.ParseCursor()
+	if err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
+	defer cancle()
+
+	text, args, err := buildListQuery(query, cursor)
+	if err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	metrics, err := ms.queryMetrics(ctx, text, args...)
+	if err != nil {
+		return view.MetricsPage{}, err
+	}
+
+	// Запрашивается на одну метрику больше, чтобы определить наличие следующей страницы
+	return query.Page(metrics), nil
+}
+
+// Хелпер функция для построения запроса списка метрик.
+// Возвращает текст запроса и его аргументы.
+func buildListQuery(query view.ListQuery, cursor *view.ListCursor) (string, []any, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if query.Kind != "" {
+		conds = append(conds, "mtype = ?")
+		args = append(args, query.Kind)
+	}
+	if query.Prefix != "" {
+		conds = append(conds, "substr(id, 1, length(?)) = ?")
+		args = append(args, query.Prefix, query.Prefix)
+	}
+	if query.Glob != "" {
+		conds = append(conds, "id GLOB ?")
+		args = append(args, query.Glob)
+	}
+	if query.Regex != "" {
+		conds = append(conds, "id REGEXP ?")
+		args = append(args, query.Regex)
+	}
+
+	// Метки проверяются по одной в порядке ключей
+	keys := make([]string, 0, len(query.Labels))
+	for key := range query.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conds = append(conds, "EXISTS (SELECT 1 FROM json_each(metrics.labels) WHERE key = ? AND value = ?)")
+		args = append(args, key, query.Labels[key])
+	}
+
+	// Колонки сортировки. Метрики с одинаковым значением поля сортировки упорядочиваются по имени и меткам
+	columns := []string{"id", "labels"}
+	switch query.Sort {
+	case view.SortByKind:
+		columns = append([]string{"mtype"}, columns...)
+	case view.SortByLastUpdated:
+		columns = append([]string{"updated_at"}, columns...)
+	}
+
+	// Страница начинается после позиции курсора
+	if cursor != nil {
+		labels, err := labelsArg(cursor.Labels)
+		if err != nil {
+			return "", nil, err
+		}
+		switch query.Sort {
+		case view.SortByKind:
+			args = append(args, cursor.Kind)
+		case view.SortByLastUpdated:
+			args = append(args, cursor.LastUpdated)
+		}
+		args = append(args, cursor.ID, labels)
+
+		op := " > "
+		if query.Desc {
+			op = " < "
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		conds = append(conds, "("+strings.Join(columns, ", ")+")"+op+"("+placeholders+")")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(queryGetAll)
+	if len(conds) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conds, " AND "))
+	}
+	sb.WriteString(" ORDER BY ")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(column)
+		if query.Desc {
+			sb.WriteString(" DESC")
+		}
+	}
+	sb.WriteString(" LIMIT ?")
+	args = append(args, query.Limit+1)
+
+	return sb.String(), args, nil
+}
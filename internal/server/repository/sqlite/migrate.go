@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Встроенные файлы миграций.
+// Имя файла имеет формат <версия>_<название>.sql.
+// Номер последней примененной миграции хранится в PRAGMA user_version.
+//
+//go:embed migrations/*.sql
+//nolint:gochecknoglobals // embedded migrations
+var migrationsFS embed.FS
+
+var errInvalidMigrationName = errors.New("invalid migration file name")
+
+// Тип migration описывает одну версию схемы БД.
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// Метод применения всех не примененных миграций.
+// Миграции применяются по возрастанию версии, каждая в отдельной транзакции.
+func (ms *MetricStorage) migrate(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err = ms.db.QueryRowContext(ctx, queryGetUserVersion).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		slog.Info("Applying migration", slog.Int("version", m.version), slog.String("name", m.name))
+		if err = ms.runMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// Хелпер метод для применения миграции в транзакции вместе с обновлением версии схемы.
+func (ms *MetricStorage) runMigration(ctx context.Context, m migration) error {
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, m.query); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	// PRAGMA не поддерживает параметры запроса
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(querySetUserVersion, m.version)); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// Функция загрузки встроенных миграций, упорядоченных по версии.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok {
+			continue
+		}
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidMigrationName, entry.Name())
+		}
+		version, convErr := strconv.Atoi(rawVersion)
+		if convErr != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidMigrationName, entry.Name())
+		}
+
+		query, readErr := fs.ReadFile(migrationsFS, "migrations/"+entry.Name())
+		if readErr != nil {
+			return nil, readErr
+		}
+		migrations = append(migrations, migration{version: version, name: name, query: string(query)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS metrics (
+	id TEXT NOT NULL,
+	mtype TEXT NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	value REAL,
+	delta INTEGER,
+	bounds TEXT,
+	buckets TEXT,
+	hcount INTEGER,
+	hsum REAL,
+	PRIMARY KEY (id, labels)
+);
+CREATE TABLE IF NOT EXISTS metric_samples (
+	id TEXT NOT NULL,
+	mtype TEXT NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	value REAL NOT NULL,
+	ts INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metric_samples_series_ts_idx ON metric_samples (id, mtype, labels, ts);
+CREATE INDEX IF NOT EXISTS metric_samples_ts_idx ON metric_samples (ts);
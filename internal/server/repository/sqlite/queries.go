@@ -0,0 +1,72 @@
+package sqlite
+
+const (
+	// Запрос для получения всех метрик в базе данных.
+	queryGetAll = `SELECT id, mtype, labels, value, delta, bounds, buckets, hcount, hsum, updated_at FROM metrics`
+	// Запрос для получения метрики по её имени и типу.
+	queryGetOne = `SELECT value, delta, bounds, buckets, hcount, hsum, updated_at
+	FROM metrics
+	WHERE id = ? AND mtype = ? AND labels = ?
+	LIMIT 1`
+	// Запрос для добавления метрики и возврата её обновленного значения.
+	// Значение gauge заменяется, counter суммируется с хранящимся.
+	// Корзины гистограмм суммируются только при совпадении границ,
+	// иначе строка гистограммы не обновляется и не возвращается.
+	// Строка метрики другого типа не обновляется и не возвращается.
+	queryAddMetric = `INSERT INTO metrics (id, mtype, labels, value, delta, bounds, buckets, hcount, hsum, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (id, labels) DO UPDATE
+	SET
+		value = excluded.value,
+		delta = CASE WHEN excluded.mtype = 'counter' THEN metrics.delta + excluded.delta ELSE excluded.delta END,
+		bounds = excluded.bounds,
+		buckets = CASE WHEN excluded.mtype = 'histogram' THEN (
+			SELECT json_group_array(a.value + b.value ORDER BY a.key)
+			FROM json_each(metrics.buckets) AS a
+			JOIN json_each(excluded.buckets) AS b ON a.key = b.key
+		) ELSE excluded.buckets END,
+		hcount = CASE WHEN excluded.mtype = 'histogram' THEN metrics.hcount + excluded.hcount ELSE excluded.hcount END,
+		hsum = CASE WHEN excluded.mtype = 'histogram' THEN metrics.hsum + excluded.hsum ELSE excluded.hsum END,
+		updated_at = excluded.updated_at
+	WHERE metrics.mtype = excluded.mtype
+		AND (excluded.mtype <> 'histogram' OR metrics.bounds = excluded.bounds)
+	RETURNING value, delta, bounds, buckets, hcount, hsum, updated_at`
+	// Запрос для получения типа хранящейся метрики.
+	queryGetMetricType = `SELECT mtype FROM metrics WHERE id = ? AND labels = ?`
+	// Запрос для удаления метрики.
+	queryDeleteMetric = `DELETE FROM metrics WHERE id = ? AND mtype = ? AND labels = ?`
+	// Запрос для удаления истории значений метрики.
+	queryDeleteSamples = `DELETE FROM metric_samples WHERE id = ? AND mtype = ? AND labels = ?`
+	// Запрос для сброса значения счетчика в ноль.
+	// Для отсутствующего счетчика строка не возвращается.
+	queryResetCounter = `UPDATE metrics
+	SET delta = 0, updated_at = ?
+	WHERE id = ? AND mtype = 'counter' AND labels = ?
+	RETURNING delta`
+	// Запрос для удаления истории метрик, не обновлявшихся с указанного времени.
+	queryDeleteStaleSamples = `DELETE FROM metric_samples
+	WHERE (id, mtype, labels) IN (SELECT id, mtype, labels FROM metrics WHERE updated_at < ?)`
+	// Запрос для удаления метрик, не обновлявшихся с указанного времени.
+	// Возвращает тип, ID и метки удаленных метрик.
+	queryDeleteStaleMetrics = `DELETE FROM metrics WHERE updated_at < ? RETURNING id, mtype, labels`
+	// Запрос для записи значения метрики в историю.
+	queryAddSample = `INSERT INTO metric_samples (id, mtype, labels, value, ts) VALUES (?, ?, ?, ?, ?)`
+	// Запрос для удаления самых старых значений истории метрики сверх заданного кол-ва.
+	queryTrimSamples = `DELETE FROM metric_samples WHERE rowid IN (
+		SELECT rowid FROM metric_samples
+		WHERE id = ? AND mtype = ? AND labels = ?
+		ORDER BY ts DESC, rowid DESC
+		LIMIT -1 OFFSET ?
+	)`
+	// Запрос для получения истории значений метрики за интервал.
+	queryGetHistory = `SELECT ts, value
+	FROM metric_samples
+	WHERE id = ? AND mtype = ? AND labels = ? AND ts BETWEEN ? AND ?
+	ORDER BY ts`
+	// Запрос для удаления истории старше указанного времени.
+	queryDeleteOldSamples = `DELETE FROM metric_samples WHERE ts < ?`
+	// Запрос для получения версии схемы БД.
+	queryGetUserVersion = `PRAGMA user_version`
+	// Запрос для записи версии схемы БД.
+	querySetUserVersion = `PRAGMA user_version = %d`
+)
@@ -0,0 +1,378 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// Схема строки подключения к хранилищу SQLite.
+const Scheme = "sqlite"
+
+// Интервал между проверками истории на устаревшие значения.
+const retentionCheckInterval = time.Minute
+
+// Время выполнения удаления устаревших значений истории.
+const retentionTimeout = 10 * time.Second
+
+// Параметры подключения по умолчанию.
+// Журнал WAL позволяет читать во время записи, транзакции сразу захватывают блокировку записи,
+// а конкурирующие соединения ожидают её освобождения вместо немедленной ошибки.
+//
+//nolint:gochecknoglobals // default connection parameters
+var defaultParams = map[string]string{
+	"_journal_mode": "WAL",
+	"_busy_timeout": "5000",
+	"_txlock":       "immediate",
+	"_synchronous":  "NORMAL",
+}
+
+// Имя драйвера SQLite с поддержкой оператора REGEXP.
+const driverName = "sqlite3_metrics"
+
+var errInvalidDSN = errors.New("invalid sqlite DSN. expected sqlite://<path>")
+
+// Тип Settings используется для хранения настроек хранилища метрик.
+// Нулевое время выполнения операции не ограничивает её, кроме как контекстом вызова.
+type Settings struct {
+	DSN              string        // Строка подключения вида sqlite:///var/lib/metrics.db
+	HistoryDepth     int           // Максимальное кол-во хранимых значений каждой метрики. 0 - история не хранится
+	HistoryRetention time.Duration // Время хранения истории значений. 0 - история хранится бессрочно
+	ReadTimeout      time.Duration // Время выполнения чтения метрик
+	WriteTimeout     time.Duration // Время выполнения записи метрик
+	HistoryTimeout   time.Duration // Время выполнения чтения истории метрик
+	PingTimeout      time.Duration // Время выполнения проверки подключения
+}
+
+// Реализация хранилища метрик в файле SQLite.
+// Схема и семантика записи метрик совпадают с хранилищем PostgreSQL.
+// Экземпляр должен создаваться с помощью New.
+type MetricStorage struct {
+	db               *sql.DB
+	historyDepth     int
+	historyRetention time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	historyTimeout   time.Duration
+	pingTimeout      time.Duration
+}
+
+// Функция проверки, что строка подключения относится к хранилищу SQLite.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, Scheme+"://")
+}
+
+// Функция фабрика для создания нового экземпляра MetricStorage.
+// Открывает или создает файл БД и применяет миграции схемы.
+// В сборке без cgo драйвер SQLite недоступен и возвращается ошибка.
+// В случае ошибки возвращает nil и ошибку.
+func New(settings *Settings) (*MetricStorage, error) {
+	if err := checkDriver(); err != nil {
+		return nil, err
+	}
+
+	source, err := driverSource(settings.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driverName, source)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &MetricStorage{
+		db:               db,
+		historyDepth:     settings.HistoryDepth,
+		historyRetention: settings.HistoryRetention,
+		readTimeout:      settings.ReadTimeout,
+		writeTimeout:     settings.WriteTimeout,
+		historyTimeout:   settings.HistoryTimeout,
+		pingTimeout:      settings.PingTimeout,
+	}
+
+	// Проверка подключения и применение миграций
+	if err = ms.Ping(context.Background()); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	if err = ms.migrate(context.Background()); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return ms, nil
+}
+
+// Метод запускающий сервис БД.
+// Блокирует поток исполнения до закрытия контекста ctx.
+// Если задано время хранения истории, периодически удаляет устаревшие значения.
+func (ms *MetricStorage) Start(ctx context.Context) error {
+	// Без времени хранения истории канал остается nil и никогда не срабатывает
+	var cleanup <-chan time.Time
+	if ms.historyRetention > 0 {
+		ticker := time.NewTicker(retentionCheckInterval)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+
+	for {
+		select {
+		// Ожидание завершения контекста
+		case <-ctx.Done():
+			return ms.Close()
+		case <-cleanup:
+			if err := ms.deleteOldSamples(ctx); err != nil {
+				slog.Error("history cleanup error", "error", err)
+			}
+		}
+	}
+}
+
+// Метод закрытия соединений с БД.
+// Используется, если хранилище создано без запуска сервиса методом Start.
+func (ms *MetricStorage) Close() error {
+	return ms.db.Close()
+}
+
+// Метод добавляющий метрики в БД.
+// Все метрики пакета записываются в одной транзакции.
+// Обновленные значения метрик типа gauge и counter записываются в историю.
+// Возвращает обновленные метрики.
+func (ms *MetricStorage) AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	// Проверка пакета до начала транзакции
+	if err := repository.ValidateMetrics(metrics...); err != nil {
+		return nil, err
+	}
+
+	ctx, cancle := withTimeout(ctx, ms.writeTimeout)
+	defer cancle()
+
+	// Начало транзакции
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ms.addMetrics(ctx, tx, time.Now(), metrics)
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+
+	// Коммитим транзакцию
+	return result, tx.Commit()
+}
+
+// Хелпер метод для записи метрик в транзакции tx.
+func (ms *MetricStorage) addMetrics(
+	ctx context.Context,
+	tx *sql.Tx,
+	now time.Time,
+	metrics []view.Metric,
+) ([]view.Metric, error) {
+	addStmt, err := tx.PrepareContext(ctx, queryAddMetric)
+	if err != nil {
+		return nil, err
+	}
+	defer addStmt.Close()
+
+	result := make([]view.Metric, 0, len(metrics))
+	updatedAt := now.UnixNano()
+	for _, metric := range metrics {
+		labels, err := labelsArg(metric.Labels)
+		if err != nil {
+			return nil, err
+		}
+		values, err := valueArgs(metric)
+		if err != nil {
+			return nil, err
+		}
+
+		// Запись метрики
+		var columns valueColumns
+		args := append([]any{metric.ID, metric.MType, labels}, values...)
+		args = append(args, updatedAt)
+		err = addStmt.QueryRowContext(ctx, args...).Scan(columns.dest()...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notUpdatedError(ctx, tx, metric, labels)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		updated := view.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels}
+		if err = columns.fill(&updated); err != nil {
+			return nil, err
+		}
+
+		// Запись значения в историю
+		if value, ok := updated.SampleValue(); ok {
+			if err = ms.putSample(ctx, tx, metric.ID, metric.MType, labels, value, updatedAt); err != nil {
+				return nil, err
+			}
+		}
+
+		result = append(result, updated)
+	}
+
+	return result, nil
+}
+
+// Хелпер метод для записи значения метрики в историю в транзакции tx.
+// Самые старые значения сверх ms.historyDepth удаляются.
+func (ms *MetricStorage) putSample(
+	ctx context.Context,
+	tx *sql.Tx,
+	id, mtype, labels string,
+	value float64,
+	ts int64,
+) error {
+	if ms.historyDepth <= 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, queryAddSample, id, mtype, labels, value, ts); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, queryTrimSamples, id, mtype, labels, ms.historyDepth)
+	return err
+}
+
+// Хелпер функция для определения причины, по которой строка метрики не обновлена.
+// Возвращает repository.ErrWrongType, если хранится метрика другого типа,
+// и view.ErrHistogramBounds, если не совпали границы корзин гистограммы.
+func notUpdatedError(ctx context.Context, tx *sql.Tx, metric view.Metric, labels string) error {
+	var mtype string
+	err := tx.QueryRowContext(ctx, queryGetMetricType, metric.ID, labels).Scan(&mtype)
+	switch {
+	case err != nil:
+		return err
+	case mtype != metric.MType:
+		return repository.ErrWrongType
+	case metric.MType == view.KindHistogram:
+		return view.ErrHistogramBounds
+	default:
+		return sql.ErrNoRows
+	}
+}
+
+// Метод получения метрики из хранилища.
+// Принимает тип метрики, ID метрики и набор её меток.
+// Возвращает repository.ErrNotFound, если метрика не найдена или у метрики с ID = name другой тип.
+func (ms *MetricStorage) GetMetric(
+	ctx context.Context,
+	kind string,
+	name string,
+	labels view.Labels,
+) (view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
+	defer cancle()
+
+	rawLabels, err := labelsArg(labels)
+	if err != nil {
+		return view.Metric{}, err
+	}
+
+	var columns valueColumns
+	err = ms.db.QueryRowContext(ctx, queryGetOne, name, kind, rawLabels).Scan(columns.dest()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return view.Metric{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return view.Metric{}, err
+	}
+
+	metric := view.Metric{ID: name, MType: kind}
+	if len(labels) > 0 {
+		metric.Labels = labels
+	}
+	return metric, columns.fill(&metric)
+}
+
+// Метод возвращающий все хранящиеся метрики.
+func (ms *MetricStorage) ReadAllMetrics(ctx context.Context) ([]view.Metric, error) {
+	ctx, cancle := withTimeout(ctx, ms.readTimeout)
+	defer cancle()
+
+	return ms.queryMetrics(ctx, queryGetAll)
+}
+
+// Хелпер метод выполняющий запрос метрик.
+// Запрос должен возвращать колонки id, mtype, labels и колонки значений метрики.
+func (ms *MetricStorage) queryMetrics(ctx context.Context, query string, args ...any) ([]view.Metric, error) {
+	rows, err := ms.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metrics := make([]view.Metric, 0)
+	for rows.Next() {
+		var (
+			metric  view.Metric
+			labels  string
+			columns valueColumns
+		)
+		err = rows.Scan(append([]any{&metric.ID, &metric.MType, &labels}, columns.dest()...)...)
+		if err != nil {
+			return nil, err
+		}
+		if metric.Labels, err = scanLabels(labels); err != nil {
+			return nil, err
+		}
+		if err = columns.fill(&metric); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, rows.Err()
+}
+
+// Метод для проверки подключения к БД.
+func (ms *MetricStorage) Ping(ctx context.Context) error {
+	ctx, cancle := withTimeout(ctx, ms.pingTimeout)
+	defer cancle()
+	return ms.db.PingContext(ctx)
+}
+
+// Функция преобразования строки подключения в источник данных драйвера.
+// Путь к файлу задается после схемы: sqlite:///абсолютный/путь или sqlite://относительный/путь.
+// Параметры строки подключения передаются драйверу и переопределяют параметры по умолчанию.
+func driverSource(dsn string) (string, error) {
+	if !IsDSN(dsn) {
+		return "", errInvalidDSN
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", errors.Join(errInvalidDSN, err)
+	}
+	path := u.Host + u.Path
+	if path == "" {
+		return "", errInvalidDSN
+	}
+
+	params := u.Query()
+	for key, value := range defaultParams {
+		if !params.Has(key) {
+			params.Set(key, value)
+		}
+	}
+
+	return "file:" + path + "?" + params.Encode(), nil
+}
+
+// Хелпер функция для ограничения времени выполнения операции с БД.
+// При нулевом timeout ограничение не устанавливается.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,267 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Хелпер для создания хранилища во временном каталоге.
+func newTestStorage(t *testing.T, settings Settings) *MetricStorage {
+	t.Helper()
+	settings.DSN = Scheme + "://" + filepath.Join(t.TempDir(), "metrics.db")
+	ms, err := New(&settings)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ms.Close()
+	})
+	return ms
+}
+
+func gauge(name string, value float64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindGauge, Value: &value}
+}
+
+func counter(name string, delta int64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindCounter, Delta: &delta}
+}
+
+func histogram(name string, bounds []float64, buckets []uint64) view.Metric {
+	var count uint64
+	for _, bucket := range buckets {
+		count += bucket
+	}
+	return view.Metric{
+		ID:    name,
+		MType: view.KindHistogram,
+		Histogram: &view.Histogram{
+			Bounds:  bounds,
+			Buckets: buckets,
+			Count:   count,
+			Sum:     float64(count),
+		},
+	}
+}
+
+func TestDriverSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "absolute path",
+			dsn:  "sqlite:///var/lib/metrics.db",
+			want: "file:/var/lib/metrics.db?_busy_timeout=5000&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate",
+		},
+		{
+			name: "relative path with params",
+			dsn:  "sqlite://data/metrics.db?_journal_mode=DELETE",
+			want: "file:data/metrics.db?_busy_timeout=5000&_journal_mode=DELETE&_synchronous=NORMAL&_txlock=immediate",
+		},
+		{
+			name:    "postgres dsn",
+			dsn:     "postgres://localhost:5432/metrics",
+			wantErr: true,
+		},
+		{
+			name:    "empty path",
+			dsn:     "sqlite://",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := driverSource(tt.dsn)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMetricStorage_AddMetrics(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{})
+
+	result, err := ms.AddMetrics(ctx, gauge("gauge", 1.5), counter("counter", 2), counter("counter", 3))
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, int64(5), *result[2].Delta)
+
+	// Gauge заменяется, counter суммируется
+	_, err = ms.AddMetrics(ctx, gauge("gauge", 2.5), counter("counter", 10))
+	require.NoError(t, err)
+
+	metric, err := ms.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, *metric.Value, 0)
+
+	metric, err = ms.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), *metric.Delta)
+
+	_, err = ms.GetMetric(ctx, view.KindCounter, "gauge", nil)
+	require.Error(t, err)
+
+	// Метрика другого типа не заменяется
+	_, err = ms.AddMetrics(ctx, counter("gauge", 4))
+	require.ErrorIs(t, err, repository.ErrWrongType)
+	metric, err = ms.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, *metric.Value, 0)
+
+	metrics, err := ms.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+}
+
+func TestMetricStorage_AddMetricsLabels(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{})
+
+	first := counter("requests", 1)
+	first.Labels = view.Labels{"host": "a", "dc": "eu"}
+	second := counter("requests", 10)
+	second.Labels = view.Labels{"host": "b"}
+
+	_, err := ms.AddMetrics(ctx, first, second, first)
+	require.NoError(t, err)
+
+	// Порядок меток не влияет на идентичность метрики
+	metric, err := ms.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"dc": "eu", "host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *metric.Delta)
+
+	metrics, err := ms.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	for _, metric := range metrics {
+		assert.NotEmpty(t, metric.Labels)
+	}
+}
+
+func TestMetricStorage_AddHistogram(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{})
+
+	bounds := []float64{1, 10}
+	_, err := ms.AddMetrics(ctx, histogram("latency", bounds, []uint64{1, 2, 3}))
+	require.NoError(t, err)
+	result, err := ms.AddMetrics(ctx, histogram("latency", bounds, []uint64{4, 5, 6}))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 7, 9}, result[0].Histogram.Buckets)
+	assert.Equal(t, uint64(21), result[0].Histogram.Count)
+
+	// Несовпадение границ отменяет весь пакет
+	_, err = ms.AddMetrics(ctx, gauge("gauge", 1), histogram("latency", []float64{5}, []uint64{1, 1}))
+	require.ErrorIs(t, err, view.ErrHistogramBounds)
+
+	_, err = ms.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.Error(t, err)
+
+	metric, err := ms.GetMetric(ctx, view.KindHistogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, bounds, metric.Histogram.Bounds)
+	assert.Equal(t, []uint64{5, 7, 9}, metric.Histogram.Buckets)
+}
+
+func TestMetricStorage_ReadHistory(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{HistoryDepth: 10})
+
+	from := time.Now()
+	for i := 1; i <= 3; i++ {
+		_, err := ms.AddMetrics(ctx, counter("counter", 1))
+		require.NoError(t, err)
+	}
+
+	samples, err := ms.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindCounter,
+		Name: "counter",
+		From: from,
+		To:   time.Now(),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	for i, sample := range samples {
+		assert.InDelta(t, float64(i+1), sample.Value, 0)
+	}
+
+	samples, err = ms.ReadHistory(ctx, view.HistoryQuery{
+		Kind:        view.KindCounter,
+		Name:        "counter",
+		From:        from,
+		To:          time.Now(),
+		Step:        time.Hour,
+		Aggregation: view.AggregationMax,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, samples)
+	assert.InDelta(t, 3.0, samples[len(samples)-1].Value, 0)
+
+	// Удаление устаревших значений
+	ms.historyRetention = time.Nanosecond
+	require.NoError(t, ms.deleteOldSamples(ctx))
+	samples, err = ms.ReadHistory(ctx, view.HistoryQuery{Kind: view.KindCounter, Name: "counter", To: time.Now()})
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestMetricStorage_HistoryDepth(t *testing.T) {
+	ctx := context.Background()
+	ms := newTestStorage(t, Settings{HistoryDepth: 3})
+
+	for i := 1; i <= 5; i++ {
+		_, err := ms.AddMetrics(ctx, gauge("gauge", float64(i)))
+		require.NoError(t, err)
+	}
+
+	// Хранятся только последние HistoryDepth значений
+	samples, err := ms.ReadHistory(ctx, view.HistoryQuery{Kind: view.KindGauge, Name: "gauge", To: time.Now()})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	for i, sample := range samples {
+		assert.InDelta(t, float64(i+3), sample.Value, 0)
+	}
+
+	// Без глубины истории значения не записываются
+	ms.historyDepth = 0
+	_, err = ms.AddMetrics(ctx, gauge("other", 1))
+	require.NoError(t, err)
+	samples, err = ms.ReadHistory(ctx, view.HistoryQuery{Kind: view.KindGauge, Name: "other", To: time.Now()})
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestMetricStorage_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dsn := Scheme + "://" + filepath.Join(t.TempDir(), "metrics.db")
+
+	ms, err := New(&Settings{DSN: dsn})
+	require.NoError(t, err)
+	_, err = ms.AddMetrics(ctx, counter("counter", 7))
+	require.NoError(t, err)
+	require.NoError(t, ms.Close())
+
+	// Повторное открытие не применяет миграции заново
+	reopened, err := New(&Settings{DSN: dsn})
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	metric, err := reopened.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *metric.Delta)
+}
@@ -0,0 +1,750 @@
+// Пакет storagetest содержит общий набор тестов соответствия для реализаций хранилища метрик.
+// Каждая реализация api.MetricsStorage должна проходить набор, чтобы обработчики
+// сервера вели себя одинаково независимо от выбранного хранилища.
+package storagetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/api"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/janitor"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Функция создания пустого хранилища для одного теста набора.
+// Освобождение ресурсов хранилища регистрируется через t.Cleanup.
+// Для записи истории хранилище должно быть настроено на хранение хотя бы нескольких значений.
+type Factory func(t *testing.T) api.MetricsStorage
+
+// Функция запуска набора тестов соответствия.
+// Каждый тест выполняется на отдельном хранилище, созданном newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, storage api.MetricsStorage)
+	}{
+		{name: "gauge is replaced", test: testGaugeReplaced},
+		{name: "counter accumulates", test: testCounterAccumulates},
+		{name: "labels separate series", test: testLabels},
+		{name: "key special characters", test: testKeyEscaping},
+		{name: "not found", test: testNotFound},
+		{name: "get with wrong type", test: testGetWrongType},
+		{name: "add with wrong type", test: testAddWrongType},
+		{name: "histogram merge", test: testHistogram},
+		{name: "nil histogram", test: testNilHistogram},
+		{name: "missing value", test: testMissingValue},
+		{name: "invalid label name", test: testInvalidLabelName},
+		{name: "read all metrics", test: testReadAll},
+		{name: "read history", test: testReadHistory},
+		{name: "delete metrics", test: testDelete},
+		{name: "delete is atomic", test: testDeleteAtomic},
+		{name: "delete removes history", test: testDeleteHistory},
+		{name: "reset counter", test: testResetCounter},
+		{name: "last updated", test: testLastUpdated},
+		{name: "delete stale metrics", test: testDeleteStale},
+		{name: "list filters", test: testListFilters},
+		{name: "list prefix special characters", test: testListPrefixEscaping},
+		{name: "list pagination", test: testListPagination},
+		{name: "list sort", test: testListSort},
+		{name: "list invalid query", test: testListInvalid},
+		{name: "ping", test: testPing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func gauge(name string, value float64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindGauge, Value: &value}
+}
+
+func counter(name string, delta int64) view.Metric {
+	return view.Metric{ID: name, MType: view.KindCounter, Delta: &delta}
+}
+
+func histogram(name string, bounds []float64, buckets []uint64) view.Metric {
+	var count uint64
+	for _, bucket := range buckets {
+		count += bucket
+	}
+	return view.Metric{
+		ID:    name,
+		MType: view.KindHistogram,
+		Histogram: &view.Histogram{
+			Bounds:  bounds,
+			Buckets: buckets,
+			Count:   count,
+			Sum:     float64(count),
+		},
+	}
+}
+
+func testGaugeReplaced(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, gauge("gauge", 1.5))
+	require.NoError(t, err)
+	result, err := storage.AddMetrics(ctx, gauge("gauge", 2.5))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.InDelta(t, 2.5, *result[0].Value, 0)
+
+	metric, err := storage.GetMetric(ctx, view.KindGauge, "gauge", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, *metric.Value, 0)
+}
+
+func testCounterAccumulates(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	// Значения суммируются как внутри пакета, так и между пакетами
+	result, err := storage.AddMetrics(ctx, counter("counter", 2), counter("counter", 3))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, int64(5), *result[1].Delta)
+
+	result, err = storage.AddMetrics(ctx, counter("counter", 10))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, int64(15), *result[0].Delta)
+
+	metric, err := storage.GetMetric(ctx, view.KindCounter, "counter", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), *metric.Delta)
+}
+
+func testLabels(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	first := counter("requests", 1)
+	first.Labels = view.Labels{"host": "a", "dc": "eu"}
+	second := counter("requests", 10)
+	second.Labels = view.Labels{"host": "b"}
+
+	_, err := storage.AddMetrics(ctx, first, second, first, counter("requests", 100))
+	require.NoError(t, err)
+
+	// Порядок меток не влияет на идентичность метрики
+	metric, err := storage.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"dc": "eu", "host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *metric.Delta)
+
+	metric, err = storage.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *metric.Delta)
+
+	metric, err = storage.GetMetric(ctx, view.KindCounter, "requests", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), *metric.Delta)
+
+	_, err = storage.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "c"})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testKeyEscaping(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	// Имя со спецсимволами не совпадает с именем и метками другой метрики
+	labeled := counter("requests", 1)
+	labeled.Labels = view.Labels{"host": "a"}
+	_, err := storage.AddMetrics(ctx, labeled, counter(`requests{host="a"}`, 10))
+	require.NoError(t, err)
+
+	metric, err := storage.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *metric.Delta)
+
+	metric, err = storage.GetMetric(ctx, view.KindCounter, `requests{host="a"}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *metric.Delta)
+
+	all, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func testNotFound(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	_, err = storage.GetMetric(ctx, view.KindGauge, "missing", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.GetMetric(ctx, view.KindCounter, "missing", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testGetWrongType(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, gauge("metric", 1))
+	require.NoError(t, err)
+
+	// При чтении метрика другого типа считается не найденной
+	_, err = storage.GetMetric(ctx, view.KindCounter, "metric", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.GetMetric(ctx, view.KindHistogram, "metric", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testAddWrongType(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, gauge("metric", 1.5))
+	require.NoError(t, err)
+
+	// Метрика другого типа с той же идентичностью отклоняется
+	_, err = storage.AddMetrics(ctx, counter("metric", 4))
+	require.ErrorIs(t, err, repository.ErrWrongType)
+	_, err = storage.AddMetrics(ctx, histogram("metric", []float64{1}, []uint64{1, 1}))
+	require.ErrorIs(t, err, repository.ErrWrongType)
+
+	metric, err := storage.GetMetric(ctx, view.KindGauge, "metric", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, *metric.Value, 0)
+}
+
+func testHistogram(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	bounds := []float64{1, 10}
+	_, err := storage.AddMetrics(ctx, histogram("latency", bounds, []uint64{1, 2, 3}))
+	require.NoError(t, err)
+	result, err := storage.AddMetrics(ctx, histogram("latency", bounds, []uint64{4, 5, 6}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, []uint64{5, 7, 9}, result[0].Histogram.Buckets)
+	assert.Equal(t, uint64(21), result[0].Histogram.Count)
+
+	// Гистограмма с другими границами корзин не объединяется с хранящейся
+	_, err = storage.AddMetrics(ctx, histogram("latency", []float64{5}, []uint64{1, 1}))
+	require.ErrorIs(t, err, view.ErrHistogramBounds)
+
+	metric, err := storage.GetMetric(ctx, view.KindHistogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, bounds, metric.Histogram.Bounds)
+	assert.Equal(t, []uint64{5, 7, 9}, metric.Histogram.Buckets)
+	assert.Equal(t, uint64(21), metric.Histogram.Count)
+}
+
+func testNilHistogram(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, view.Metric{ID: "latency", MType: view.KindHistogram})
+	require.ErrorIs(t, err, view.ErrInvalidHistogram)
+
+	_, err = storage.GetMetric(ctx, view.KindHistogram, "latency", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testMissingValue(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	// Метрики без значения своего типа отклоняются вместе со всем пакетом
+	_, err := storage.AddMetrics(ctx, gauge("mem", 1), view.Metric{ID: "cpu", MType: view.KindGauge})
+	require.ErrorIs(t, err, repository.ErrWrongType)
+	_, err = storage.AddMetrics(ctx, gauge("mem", 1), view.Metric{ID: "requests", MType: view.KindCounter})
+	require.ErrorIs(t, err, repository.ErrWrongType)
+
+	_, err = storage.GetMetric(ctx, view.KindGauge, "mem", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.GetMetric(ctx, view.KindGauge, "cpu", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.GetMetric(ctx, view.KindCounter, "requests", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testInvalidLabelName(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	metric := gauge("cpu", 1)
+	metric.Labels = view.Labels{"host-name": "web1"}
+
+	_, err := storage.AddMetrics(ctx, gauge("mem", 1), metric)
+	require.ErrorIs(t, err, view.ErrInvalidLabelName)
+
+	// Пакет с некорректной меткой не записывается целиком
+	_, err = storage.GetMetric(ctx, view.KindGauge, "mem", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	err = storage.DeleteMetrics(ctx, metric)
+	require.ErrorIs(t, err, view.ErrInvalidLabelName)
+
+	_, err = storage.ResetCounter(ctx, "requests", view.Labels{"1host": "web1"})
+	require.ErrorIs(t, err, view.ErrInvalidLabelName)
+
+	_, err = storage.ListMetrics(ctx, view.ListQuery{Labels: view.Labels{"host-name": "web1"}})
+	require.ErrorIs(t, err, view.ErrInvalidListQuery)
+}
+
+func testReadAll(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(
+		ctx,
+		gauge("gauge", 1.5),
+		counter("counter", 2),
+		histogram("latency", []float64{1}, []uint64{1, 1}),
+	)
+	require.NoError(t, err)
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	kinds := make(map[string]string, len(metrics))
+	for _, metric := range metrics {
+		kinds[metric.ID] = metric.MType
+	}
+	assert.Equal(t, map[string]string{
+		"gauge":   view.KindGauge,
+		"counter": view.KindCounter,
+		"latency": view.KindHistogram,
+	}, kinds)
+}
+
+func testReadHistory(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	values := []float64{1, 2, 3}
+	for _, value := range values {
+		_, err := storage.AddMetrics(ctx, gauge("gauge", value))
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	samples, err := storage.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindGauge,
+		Name: "gauge",
+		From: now.Add(-time.Minute),
+		To:   now.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, len(values))
+	for i, sample := range samples {
+		assert.InDelta(t, values[i], sample.Value, 0)
+		if i > 0 {
+			assert.False(t, sample.Timestamp.Before(samples[i-1].Timestamp))
+		}
+	}
+
+	// История неизвестной метрики или метрики другого типа не найдена
+	for _, query := range []view.HistoryQuery{
+		{Kind: view.KindGauge, Name: "unknown"},
+		{Kind: view.KindCounter, Name: "gauge"},
+		{Kind: view.KindGauge, Name: "gauge", Labels: view.Labels{"host": "a"}},
+		{Kind: view.KindGauge, Name: "unknown", Step: time.Minute, Aggregation: view.AggregationAvg},
+	} {
+		query.From = now.Add(-time.Minute)
+		query.To = now.Add(time.Minute)
+		_, err = storage.ReadHistory(ctx, query)
+		require.ErrorIs(t, err, repository.ErrNotFound, "query %+v", query)
+	}
+}
+
+func testDelete(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	labeled := gauge("cpu", 2)
+	labeled.Labels = view.Labels{"host": "a"}
+	_, err := storage.AddMetrics(ctx, gauge("cpu", 1), labeled, counter("requests", 10))
+	require.NoError(t, err)
+
+	// Значение удаляемой метрики не учитывается
+	require.NoError(t, storage.DeleteMetrics(ctx, view.Metric{ID: "cpu", MType: view.KindGauge}))
+
+	_, err = storage.GetMetric(ctx, view.KindGauge, "cpu", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.GetMetric(ctx, view.KindGauge, "cpu", view.Labels{"host": "a"})
+	require.NoError(t, err)
+
+	// Повторяющиеся в пакете метрики удаляются один раз
+	require.NoError(t, storage.DeleteMetrics(ctx, labeled, labeled))
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "requests", metrics[0].ID)
+}
+
+func testDeleteAtomic(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, gauge("cpu", 1), counter("requests", 10))
+	require.NoError(t, err)
+
+	// Если хотя бы одна метрика не найдена, не удаляется ни одна
+	err = storage.DeleteMetrics(ctx, counter("requests", 0), gauge("missing", 0))
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	err = storage.DeleteMetrics(ctx, gauge("cpu", 0), counter("cpu", 0))
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+}
+
+func testDeleteHistory(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	_, err := storage.AddMetrics(ctx, gauge("gauge", 1))
+	require.NoError(t, err)
+	_, err = storage.AddMetrics(ctx, gauge("gauge", 2))
+	require.NoError(t, err)
+
+	require.NoError(t, storage.DeleteMetrics(ctx, gauge("gauge", 0)))
+
+	// Метрика, созданная заново, не наследует историю удаленной
+	_, err = storage.AddMetrics(ctx, gauge("gauge", 5))
+	require.NoError(t, err)
+
+	now := time.Now()
+	samples, err := storage.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindGauge,
+		Name: "gauge",
+		From: now.Add(-time.Minute),
+		To:   now.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 5.0, samples[0].Value, 0)
+}
+
+func testResetCounter(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	labeled := counter("requests", 7)
+	labeled.Labels = view.Labels{"host": "a"}
+	_, err := storage.AddMetrics(ctx, counter("requests", 10), labeled, gauge("cpu", 1))
+	require.NoError(t, err)
+
+	metric, err := storage.ResetCounter(ctx, "requests", nil)
+	require.NoError(t, err)
+	require.NotNil(t, metric.Delta)
+	assert.Equal(t, int64(0), *metric.Delta)
+
+	// После сброса значения суммируются с нуля, остальные серии не изменяются
+	result, err := storage.AddMetrics(ctx, counter("requests", 3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *result[0].Delta)
+
+	metric, err = storage.GetMetric(ctx, view.KindCounter, "requests", view.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *metric.Delta)
+
+	// Нулевое значение записывается в историю
+	now := time.Now()
+	samples, err := storage.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindCounter,
+		Name: "requests",
+		From: now.Add(-time.Minute),
+		To:   now.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.InDelta(t, 0.0, samples[1].Value, 0)
+
+	_, err = storage.ResetCounter(ctx, "cpu", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = storage.ResetCounter(ctx, "missing", nil)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testLastUpdated(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	// Время хранилища БД может округляться, поэтому сравнение выполняется с допуском
+	from := time.Now().Add(-time.Second)
+	result, err := storage.AddMetrics(ctx, gauge("cpu", 1), counter("requests", 1))
+	require.NoError(t, err)
+	to := time.Now().Add(time.Second)
+
+	for _, metric := range result {
+		require.NotNil(t, metric.LastUpdated, metric.ID)
+		assert.WithinRange(t, *metric.LastUpdated, from, to)
+	}
+
+	metric, err := storage.GetMetric(ctx, view.KindGauge, "cpu", nil)
+	require.NoError(t, err)
+	require.NotNil(t, metric.LastUpdated)
+	assert.WithinRange(t, *metric.LastUpdated, from, to)
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	for _, metric = range metrics {
+		require.NotNil(t, metric.LastUpdated, metric.ID)
+	}
+
+	metric, err = storage.ResetCounter(ctx, "requests", nil)
+	require.NoError(t, err)
+	require.NotNil(t, metric.LastUpdated)
+	assert.WithinRange(t, *metric.LastUpdated, from, time.Now().Add(time.Second))
+}
+
+func testDeleteStale(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	deleter, ok := storage.(janitor.Storage)
+	require.True(t, ok, "storage must implement janitor.Storage")
+
+	_, err := storage.AddMetrics(ctx, gauge("cpu", 1), counter("requests", 1))
+	require.NoError(t, err)
+
+	// Метрики, обновленные позже before, не удаляются
+	deleted, err := deleter.DeleteStaleMetrics(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	metrics, err := storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+
+	deleted, err = deleter.DeleteStaleMetrics(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	// Удаленные метрики возвращаются без значений
+	assert.ElementsMatch(t, []view.Metric{
+		{ID: "cpu", MType: view.KindGauge},
+		{ID: "requests", MType: view.KindCounter},
+	}, deleted)
+
+	metrics, err = storage.ReadAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	// Вместе с метрикой удаляется её история
+	_, err = storage.AddMetrics(ctx, gauge("cpu", 5))
+	require.NoError(t, err)
+
+	now := time.Now()
+	samples, err := storage.ReadHistory(ctx, view.HistoryQuery{
+		Kind: view.KindGauge,
+		Name: "cpu",
+		From: now.Add(-time.Minute),
+		To:   now.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 5.0, samples[0].Value, 0)
+}
+
+// Хелпер для получения ключей метрик страницы в порядке следования.
+func pageKeys(page view.MetricsPage) []string {
+	keys := make([]string, 0, len(page.Metrics))
+	for _, metric := range page.Metrics {
+		keys = append(keys, metric.Key())
+	}
+	return keys
+}
+
+// Хелпер для заполнения хранилища метриками для тестов списка.
+func addListMetrics(t *testing.T, storage api.MetricsStorage) {
+	t.Helper()
+
+	cpuA := gauge("cpu", 1)
+	cpuA.Labels = view.Labels{"host": "a"}
+	cpuB := gauge("cpu", 2)
+	cpuB.Labels = view.Labels{"host": "b"}
+	requests := counter("requests", 1)
+	requests.Labels = view.Labels{"host": "a", "dc": "eu"}
+
+	_, err := storage.AddMetrics(
+		context.Background(),
+		cpuA,
+		cpuB,
+		requests,
+		gauge("ram", 3),
+		histogram("latency", []float64{1}, []uint64{1, 1}),
+	)
+	require.NoError(t, err)
+}
+
+func testListFilters(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+	addListMetrics(t, storage)
+
+	tests := []struct {
+		name  string
+		query view.ListQuery
+		want  []string
+	}{
+		{
+			name:  "all",
+			query: view.ListQuery{},
+			want:  []string{`cpu{host="a"}`, `cpu{host="b"}`, "latency", "ram", `requests{dc="eu",host="a"}`},
+		},
+		{
+			name:  "kind",
+			query: view.ListQuery{Kind: view.KindGauge},
+			want:  []string{`cpu{host="a"}`, `cpu{host="b"}`, "ram"},
+		},
+		{
+			name:  "prefix",
+			query: view.ListQuery{Prefix: "r"},
+			want:  []string{"ram", `requests{dc="eu",host="a"}`},
+		},
+		{
+			name:  "glob",
+			query: view.ListQuery{Glob: "r?m"},
+			want:  []string{"ram"},
+		},
+		{
+			name:  "glob class",
+			query: view.ListQuery{Glob: "[cl]*"},
+			want:  []string{`cpu{host="a"}`, `cpu{host="b"}`, "latency"},
+		},
+		{
+			name:  "regex",
+			query: view.ListQuery{Regex: "^(cpu|ram)$"},
+			want:  []string{`cpu{host="a"}`, `cpu{host="b"}`, "ram"},
+		},
+		{
+			name:  "labels",
+			query: view.ListQuery{Labels: view.Labels{"host": "a"}},
+			want:  []string{`cpu{host="a"}`, `requests{dc="eu",host="a"}`},
+		},
+		{
+			name:  "combined",
+			query: view.ListQuery{Kind: view.KindCounter, Prefix: "req", Labels: view.Labels{"dc": "eu"}},
+			want:  []string{`requests{dc="eu",host="a"}`},
+		},
+		{
+			name:  "nothing matches",
+			query: view.ListQuery{Labels: view.Labels{"host": "c"}},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := storage.ListMetrics(ctx, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pageKeys(page))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+}
+
+func testListPrefixEscaping(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	names := []string{"a,b", "a,c", "a=b", `a\b`, `a{b}`, `a"b`, "ab"}
+	for _, name := range names {
+		_, err := storage.AddMetrics(ctx, gauge(name, 1))
+		require.NoError(t, err)
+	}
+
+	// Префикс сравнивается с именем метрики, а не с ключом, в котором специальные символы экранированы
+	for _, prefix := range []string{"a,", "a=", `a\`, `a{`, `a"`} {
+		page, err := storage.ListMetrics(ctx, view.ListQuery{Prefix: prefix})
+		require.NoError(t, err)
+
+		want := make([]string, 0)
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) {
+				want = append(want, view.MetricKey(name, nil))
+			}
+		}
+		assert.ElementsMatch(t, want, pageKeys(page), "prefix %q", prefix)
+	}
+}
+
+func testListPagination(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+	addListMetrics(t, storage)
+
+	for _, desc := range []bool{false, true} {
+		all, err := storage.ListMetrics(ctx, view.ListQuery{Desc: desc})
+		require.NoError(t, err)
+
+		// Страницы следуют без пропусков и повторов
+		query := view.ListQuery{Desc: desc, Limit: 2}
+		keys := make([]string, 0)
+		pages := 0
+		for {
+			page, err := storage.ListMetrics(ctx, query)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Metrics), 2)
+			keys = append(keys, pageKeys(page)...)
+			pages++
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, pageKeys(all), keys)
+		assert.Equal(t, 3, pages)
+	}
+}
+
+func testListSort(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+	addListMetrics(t, storage)
+
+	page, err := storage.ListMetrics(ctx, view.ListQuery{Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, `requests{dc="eu",host="a"}`, pageKeys(page)[0])
+
+	page, err = storage.ListMetrics(ctx, view.ListQuery{Sort: view.SortByKind})
+	require.NoError(t, err)
+	kinds := make([]string, 0, len(page.Metrics))
+	for _, metric := range page.Metrics {
+		kinds = append(kinds, metric.MType)
+	}
+	assert.Equal(t, []string{
+		view.KindCounter,
+		view.KindGauge,
+		view.KindGauge,
+		view.KindGauge,
+		view.KindHistogram,
+	}, kinds)
+
+	// Метрики, обновленные позже, следуют позже
+	time.Sleep(10 * time.Millisecond)
+	_, err = storage.AddMetrics(ctx, gauge("ram", 4))
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = storage.AddMetrics(ctx, gauge("latest", 5))
+	require.NoError(t, err)
+
+	page, err = storage.ListMetrics(ctx, view.ListQuery{Sort: view.SortByLastUpdated, Desc: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest"}, pageKeys(page))
+
+	page, err = storage.ListMetrics(ctx, view.ListQuery{
+		Sort:   view.SortByLastUpdated,
+		Desc:   true,
+		Limit:  1,
+		Cursor: page.NextCursor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ram"}, pageKeys(page))
+}
+
+func testListInvalid(t *testing.T, storage api.MetricsStorage) {
+	ctx := context.Background()
+
+	for _, query := range []view.ListQuery{
+		{Sort: "value"},
+		{Limit: -1},
+		{Limit: view.MaxListLimit + 1},
+		{Regex: "("},
+		{Cursor: "not a cursor"},
+	} {
+		_, err := storage.ListMetrics(ctx, query)
+		require.ErrorIs(t, err, view.ErrInvalidListQuery, "%+v", query)
+	}
+}
+
+func testPing(t *testing.T, storage api.MetricsStorage) {
+	require.NoError(t, storage.Ping(context.Background()))
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"slices"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+)
+
+// ValidateLabels проверяет имена меток метрик перед изменением хранилища.
+// Возвращает ошибку, оборачивающую view.ErrInvalidLabelName, для первого некорректного имени.
+func ValidateLabels(metrics ...view.Metric) error {
+	for i := range metrics {
+		if err := metrics[i].Labels.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateMetrics проверяет пакет метрик перед добавлением в хранилище.
+// Проверяет имена меток, соответствие значения каждой метрики её типу и корректность гистограмм,
+// а также что метрики пакета с одинаковой идентичностью имеют один тип и одинаковые границы корзин.
+// Возвращает ошибку, оборачивающую view.ErrInvalidLabelName,
+// ErrWrongType, view.ErrInvalidHistogram или view.ErrHistogramBounds.
+func ValidateMetrics(metrics ...view.Metric) error {
+	if err := ValidateLabels(metrics...); err != nil {
+		return err
+	}
+
+	first := make(map[string]view.Metric, len(metrics))
+
+	for _, metric := range metrics {
+		switch metric.MType {
+		case view.KindGauge:
+			if metric.Value == nil {
+				return ErrWrongType
+			}
+		case view.KindCounter:
+			if metric.Delta == nil {
+				return ErrWrongType
+			}
+		case view.KindHistogram:
+			if metric.Histogram == nil {
+				return view.ErrInvalidHistogram
+			}
+			if err := metric.Histogram.Validate(); err != nil {
+				return err
+			}
+		default:
+			return ErrWrongType
+		}
+
+		key := metric.Key()
+		prev, ok := first[key]
+		switch {
+		case !ok:
+			first[key] = metric
+		case prev.MType != metric.MType:
+			return ErrWrongType
+		case metric.MType == view.KindHistogram && !slices.Equal(prev.Histogram.Bounds, metric.Histogram.Bounds):
+			return view.ErrHistogramBounds
+		}
+	}
+
+	return nil
+}
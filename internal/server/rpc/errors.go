@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
+	"google.golang.org/grpc/codes"
+)
+
+// storageErrorCode возвращает gRPC код ответа для ошибки репозитория.
+// Соответствует кодам HTTP ответов, возвращаемым обработчиками api.
+func storageErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, repository.ErrWrongType),
+		errors.Is(err, view.ErrInvalidHistogram),
+		errors.Is(err, view.ErrInvalidLabelName),
+		errors.Is(err, view.ErrHistogramBounds),
+		errors.Is(err, view.ErrUnknownAggregation),
+		errors.Is(err, view.ErrInvalidListQuery):
+		return codes.InvalidArgument
+	case errors.Is(err, circuitbreaker.ErrOpen),
+		errors.Is(err, repository.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
+
+// retryableCode сообщает, является ли ошибка с кодом code временной:
+// запрос с такой ошибкой может быть успешно выполнен повторно.
+func retryableCode(code codes.Code) bool {
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	"github.com/FlutterDizaster/ya-metrics/pkg/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestStorageErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "not found", err: repository.ErrNotFound, want: codes.NotFound},
+		{name: "wrong type", err: fmt.Errorf("add: %w", repository.ErrWrongType), want: codes.InvalidArgument},
+		{name: "histogram bounds", err: view.ErrHistogramBounds, want: codes.InvalidArgument},
+		{name: "invalid label name", err: view.ErrInvalidLabelName, want: codes.InvalidArgument},
+		{name: "invalid list query", err: view.ErrInvalidListQuery, want: codes.InvalidArgument},
+		{name: "breaker open", err: circuitbreaker.ErrOpen, want: codes.Unavailable},
+		{
+			name: "retries exhausted",
+			err:  fmt.Errorf("%w: %w", repository.ErrUnavailable, io.ErrUnexpectedEOF),
+			want: codes.Unavailable,
+		},
+		{name: "timeout", err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{name: "canceled", err: context.Canceled, want: codes.Canceled},
+		{name: "unknown", err: errors.New("connection reset"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, storageErrorCode(tt.err))
+		})
+	}
+}
@@ -20,35 +20,50 @@ var _ Interceptor = &AccessFilterInterceptor{}
 
 func (i *AccessFilterInterceptor) Unary() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		// Получение информации о клиенте
-		p, ok := peer.FromContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "unable to get client IP")
-		}
-
-		// Получение IP адреса клиента и удаление порта
-		addr := p.Addr.String()
-		var err error
-		if strings.Contains(addr, ":") {
-			addr, _, err = net.SplitHostPort(addr)
-			if err != nil {
-				return nil, status.Error(codes.Unauthenticated, "unable to get client IP")
-			}
-		}
-
-		// Проверка подсети
-		if i.TrustedSubnet != nil {
-			if !i.TrustedSubnet.Contains(net.ParseIP(addr)) {
-				return nil, status.Error(codes.PermissionDenied, "access denied")
-			}
+		if err := i.check(ctx); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
 	}
 }
 
+// Stream проверяет адрес клиента при открытии потока.
+// Если клиент не входит в доверенную подсеть, поток закрывается до вызова обработчика.
 func (i *AccessFilterInterceptor) Stream() grpc.StreamServerInterceptor {
 	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := i.check(stream.Context()); err != nil {
+			return err
+		}
+
 		return handler(srv, stream)
 	}
 }
+
+// Хелпер метод проверки, что клиент, от которого получен запрос, входит в доверенную подсеть.
+func (i *AccessFilterInterceptor) check(ctx context.Context) error {
+	// Получение информации о клиенте
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unable to get client IP")
+	}
+
+	// Получение IP адреса клиента и удаление порта
+	addr := p.Addr.String()
+	var err error
+	if strings.Contains(addr, ":") {
+		addr, _, err = net.SplitHostPort(addr)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, "unable to get client IP")
+		}
+	}
+
+	// Проверка подсети
+	if i.TrustedSubnet != nil {
+		if !i.TrustedSubnet.Contains(net.ParseIP(addr)) {
+			return status.Error(codes.PermissionDenied, "access denied")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// HashInterceptor проверяет целостность запросов по HMAC SHA256 с общим ключом.
+// HMAC унарного запроса передается в метаданных validation.HashMetadataKey,
+// HMAC сообщения потока - в поле сообщения validation.HashField.
+// HMAC проверяется по байтам, полученным от клиента (см. validation.SignedPayload), поэтому
+// сервер должен быть создан с параметрами ServerOptions, сохраняющими полученные байты.
+// Клиент может подписывать запросы интерцепторами validation.UnaryClientInterceptor
+// и validation.StreamClientInterceptor.
+type HashInterceptor struct {
+	// Key - ключ HMAC
+	Key []byte
+
+	// Байты сообщений, полученные кодеком, до их передачи в контекст запроса
+	payloads sync.Map
+}
+
+var (
+	_ Interceptor    = &HashInterceptor{}
+	_ ServerOptioner = &HashInterceptor{}
+)
+
+// ServerOptions возвращает параметры сервера, сохраняющие байты полученных сообщений
+// в контексте запроса для проверки HMAC.
+func (i *HashInterceptor) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ForceServerCodec(&payloadCodec{interceptor: i}),
+		grpc.StatsHandler(&payloadHandler{interceptor: i}),
+	}
+}
+
+// Unary отклоняет запрос без HMAC или с HMAC, не совпадающим с посчитанным сервером.
+func (i *HashInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var hash string
+		if values := metadata.ValueFromIncomingContext(ctx, validation.HashMetadataKey); len(values) > 0 {
+			hash = values[0]
+		}
+
+		data, err := receivedPayload(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err = i.verify(data, hash); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream проверяет HMAC каждого полученного сообщения потока.
+// При ошибке проверки поток закрывается с ошибкой, возвращенной обработчику из RecvMsg.
+func (i *HashInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &hashServerStream{ServerStream: stream, interceptor: i})
+	}
+}
+
+// Хелпер метод проверки HMAC подписанных байт сообщения data.
+func (i *HashInterceptor) verify(data []byte, hash string) error {
+	if hash == "" {
+		return status.Error(codes.Unauthenticated, "hash required")
+	}
+	sample, err := hex.DecodeString(hash)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "can't decode hash")
+	}
+
+	expected := validation.CalculateHMACSHA256(data, i.Key)
+
+	// Сравнение хешей за постоянное время
+	if !hmac.Equal(expected, sample) {
+		return status.Error(codes.Unauthenticated, "invalid hash")
+	}
+
+	return nil
+}
+
+// hashServerStream выступает оберткой над grpc.ServerStream.
+// Проверяет HMAC каждого полученного сообщения.
+type hashServerStream struct {
+	grpc.ServerStream
+	interceptor *HashInterceptor
+}
+
+// RecvMsg переопределение функции grpc.ServerStream.RecvMsg(any).
+func (s *hashServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	pm, ok := m.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, "unable to calculate hash")
+	}
+	hash, ok := validation.MessageHMAC(pm)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "stream message can't carry hash")
+	}
+
+	data, err := receivedPayload(s.Context())
+	if err != nil {
+		return err
+	}
+	if data, err = validation.SignedPayload(pm, data); err != nil {
+		return status.Error(codes.InvalidArgument, "can't parse message")
+	}
+
+	return s.interceptor.verify(data, hash)
+}
+
+// Ключ контекста запроса, по которому хранятся байты последнего полученного сообщения.
+type payloadKey struct{}
+
+// Байты последнего полученного сообщения запроса.
+// Сохраняются обработчиком статистики и читаются интерцептором в той же горутине.
+type payload struct {
+	data     []byte
+	received bool
+}
+
+// Хелпер функция получения байт последнего полученного сообщения запроса.
+// Байты возвращаются один раз, чтобы не проверить новое сообщение по байтам предыдущего.
+func receivedPayload(ctx context.Context) ([]byte, error) {
+	p, ok := ctx.Value(payloadKey{}).(*payload)
+	if !ok || !p.received {
+		return nil, status.Error(codes.Internal, "unable to calculate hash")
+	}
+	p.received = false
+	return p.data, nil
+}
+
+// payloadCodec - кодек protobuf, запоминающий байты полученных сообщений.
+// Байты передаются в контекст запроса обработчиком статистики payloadHandler,
+// который вызывается сразу после десериализации сообщения.
+type payloadCodec struct {
+	interceptor *HashInterceptor
+}
+
+// Marshal реализация encoding.Codec.
+func (c *payloadCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal реализация encoding.Codec.
+// Буфер data переиспользуется после вызова, поэтому сохраняется его копия.
+func (c *payloadCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	c.interceptor.payloads.Store(v, bytes.Clone(data))
+	return nil
+}
+
+// Name реализация encoding.Codec.
+func (c *payloadCodec) Name() string {
+	return "proto"
+}
+
+// payloadHandler - обработчик статистики, переносящий байты полученного сообщения,
+// сохраненные кодеком payloadCodec, в контекст запроса.
+type payloadHandler struct {
+	interceptor *HashInterceptor
+}
+
+// TagRPC реализация stats.Handler. Добавляет в контекст запроса хранилище байт сообщения.
+func (h *payloadHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, payloadKey{}, &payload{})
+}
+
+// HandleRPC реализация stats.Handler.
+func (h *payloadHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	in, ok := s.(*stats.InPayload)
+	if !ok {
+		return
+	}
+	data, ok := h.interceptor.payloads.LoadAndDelete(in.Payload)
+	if !ok {
+		return
+	}
+	if p, ok := ctx.Value(payloadKey{}).(*payload); ok {
+		p.data, _ = data.([]byte)
+		p.received = true
+	}
+}
+
+// TagConn реализация stats.Handler.
+func (h *payloadHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn реализация stats.Handler.
+func (h *payloadHandler) HandleConn(context.Context, stats.ConnStats) {}
@@ -0,0 +1,118 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Хелпер параметров подключения клиента, подписывающего запросы ключом key.
+func signOptions(key string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(validation.UnaryClientInterceptor([]byte(key))),
+		grpc.WithStreamInterceptor(validation.StreamClientInterceptor([]byte(key))),
+	}
+}
+
+func TestHashInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []grpc.DialOption
+		wantCode codes.Code
+	}{
+		{name: "signed", opts: signOptions("secret"), wantCode: codes.OK},
+		{name: "wrong key", opts: signOptions("wrong"), wantCode: codes.Unauthenticated},
+		{name: "unsigned", opts: nil, wantCode: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, &HashInterceptor{Key: []byte("secret")}, "10.1.2.3", tt.opts...)
+
+			_, err := client.Ping(context.Background(), &pb.PingRequest{})
+			assert.Equal(t, tt.wantCode, status.Code(err), "unary")
+
+			err = streamBatches(t, client)
+			assert.Equal(t, tt.wantCode, status.Code(err), "stream")
+		})
+	}
+}
+
+func TestHashInterceptor_InvalidHash(t *testing.T) {
+	client := newTestClient(t, &HashInterceptor{Key: []byte("secret")}, "10.1.2.3")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), validation.HashMetadataKey, "not hex")
+	_, err := client.Ping(ctx, &pb.PingRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Хеш потока не передается в метаданных: сообщение без подписи отклоняется
+	stream, err := client.StreamMetrics(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.StreamMetricsRequest{Seq: 1}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// Кодек клиента, отправляющий заранее подготовленные байты сообщения.
+type rawCodec struct {
+	data []byte
+}
+
+func (c *rawCodec) Marshal(any) ([]byte, error) {
+	return c.data, nil
+}
+
+func (c *rawCodec) Unmarshal(data []byte, v any) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+func (c *rawCodec) Name() string {
+	return "proto"
+}
+
+func TestHashInterceptor_WireBytes(t *testing.T) {
+	client := newTestClient(t, &HashInterceptor{Key: []byte("secret")}, "10.1.2.3")
+
+	// Поля сообщения записаны не в порядке номеров и не совпадают с повторной сериализацией сервера
+	payload := protowire.AppendTag(nil, 2, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, nil)
+	payload = protowire.AppendTag(payload, 1, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 1)
+	hash := hex.EncodeToString(validation.CalculateHMACSHA256(payload, []byte("secret")))
+
+	// Поле хеша записано в начало сообщения, HMAC считается без него
+	data := protowire.AppendTag(nil, 3, protowire.BytesType)
+	data = protowire.AppendString(data, hash)
+	data = append(data, payload...)
+
+	stream, err := client.StreamMetrics(context.Background(), grpc.ForceCodec(&rawCodec{data: data}))
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.StreamMetricsRequest{}))
+	ack, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), ack.GetSeq())
+}
+
+func TestHashInterceptor_ClientMessageUnchanged(t *testing.T) {
+	client := newTestClient(t, &HashInterceptor{Key: []byte("secret")}, "10.1.2.3", signOptions("secret")...)
+
+	stream, err := client.StreamMetrics(context.Background())
+	require.NoError(t, err)
+
+	// Хеш дописывается к сериализованному сообщению, сообщение клиента не изменяется
+	req := &pb.StreamMetricsRequest{Seq: 1}
+	require.NoError(t, stream.Send(req))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Empty(t, req.GetHash())
+}
@@ -8,3 +8,9 @@ type Interceptor interface {
 	Unary() grpc.UnaryServerInterceptor
 	Stream() grpc.StreamServerInterceptor
 }
+
+// ServerOptioner - интерцептор, которому для работы нужны параметры gRPC сервера.
+// Параметры применяются при создании сервера вместе с интерцепторами.
+type ServerOptioner interface {
+	ServerOptions() []grpc.ServerOption
+}
@@ -0,0 +1,203 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Тестовый сервис: отвечает на Ping и подтверждает каждый пакет потока StreamMetrics.
+type testService struct {
+	pb.UnimplementedMetricsServiceServer
+}
+
+func (s *testService) Ping(_ context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	return &pb.PingResponse{}, nil
+}
+
+func (s *testService) StreamMetrics(stream pb.MetricsService_StreamMetricsServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(&pb.StreamMetricsResponse{Seq: req.GetSeq()}); err != nil {
+			return err
+		}
+	}
+}
+
+// remoteAddrConn выступает оберткой над net.Conn с подменой адреса клиента.
+type remoteAddrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *remoteAddrConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// remoteAddrListener выступает оберткой над bufconn.Listener,
+// соединения которой сообщают серверу адрес клиента addr.
+type remoteAddrListener struct {
+	*bufconn.Listener
+	addr net.Addr
+}
+
+func (l *remoteAddrListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &remoteAddrConn{Conn: conn, addr: l.addr}, nil
+}
+
+// syncBuffer - буфер лога, безопасный для записи из горутин сервера.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Хелпер для запуска тестового сервиса с интерцептором на соединении в памяти.
+// Сервер видит клиента с адресом clientIP. Параметры opts дополняют параметры подключения клиента.
+func newTestClient(
+	t *testing.T,
+	interceptor Interceptor,
+	clientIP string,
+	opts ...grpc.DialOption,
+) pb.MetricsServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	var srvOpts []grpc.ServerOption
+	if optioner, ok := interceptor.(ServerOptioner); ok {
+		srvOpts = optioner.ServerOptions()
+	}
+	srv := grpc.NewServer(append(srvOpts,
+		grpc.ChainUnaryInterceptor(interceptor.Unary()),
+		grpc.ChainStreamInterceptor(interceptor.Stream()),
+	)...)
+	pb.RegisterMetricsServiceServer(srv, &testService{})
+	go func() {
+		_ = srv.Serve(&remoteAddrListener{
+			Listener: listener,
+			addr:     &net.TCPAddr{IP: net.ParseIP(clientIP), Port: 50000},
+		})
+	}()
+	t.Cleanup(srv.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufconn", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewMetricsServiceClient(conn)
+}
+
+// Хелпер для отправки двух пакетов в поток StreamMetrics.
+// Возвращает ошибку, с которой сервер закрыл поток, или nil.
+func streamBatches(t *testing.T, client pb.MetricsServiceClient) error {
+	t.Helper()
+
+	stream, err := client.StreamMetrics(context.Background())
+	require.NoError(t, err)
+
+	for seq := range uint64(2) {
+		if err = stream.Send(&pb.StreamMetricsRequest{Seq: seq + 1}); err != nil {
+			break
+		}
+	}
+	require.NoError(t, stream.CloseSend())
+
+	for {
+		_, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestAccessFilterInterceptor(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		clientIP string
+		wantCode codes.Code
+	}{
+		{name: "trusted client", clientIP: "10.1.2.3", wantCode: codes.OK},
+		{name: "untrusted client", clientIP: "192.168.1.1", wantCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, &AccessFilterInterceptor{TrustedSubnet: subnet}, tt.clientIP)
+
+			_, err = client.Ping(context.Background(), &pb.PingRequest{})
+			assert.Equal(t, tt.wantCode, status.Code(err), "unary")
+
+			err = streamBatches(t, client)
+			assert.Equal(t, tt.wantCode, status.Code(err), "stream")
+		})
+	}
+}
+
+func TestLoggerInterceptor_Stream(t *testing.T) {
+	var buf syncBuffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	client := newTestClient(t, &LoggerInterceptor{}, "10.1.2.3")
+	require.NoError(t, streamBatches(t, client))
+
+	// Лог потока записывается сервером после ответа клиенту
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "incoming stream gRPC request")
+	}, time.Second, 10*time.Millisecond)
+
+	out := buf.String()
+	assert.Contains(t, out, "method=/metrics.MetricsService/StreamMetrics")
+	assert.Contains(t, out, "from=10.1.2.3:50000")
+	assert.Contains(t, out, "received=2")
+	assert.Contains(t, out, "sent=2")
+}
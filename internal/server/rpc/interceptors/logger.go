@@ -24,20 +24,11 @@ func (l *LoggerInterceptor) Unary() grpc.UnaryServerInterceptor {
 		// Подсчет времени обработки запроса
 		duration := time.Since(startTime)
 
-		addr := "unknown"
-
-		p, ok := peer.FromContext(ctx)
-		if !ok {
-			slog.Error("unable to get client IP")
-		} else {
-			addr = p.Addr.String()
-		}
-
 		// Запись лога
 		slog.Info(
 			"incoming unary gRPC request",
 			slog.String("method", info.FullMethod),
-			slog.String("from", addr),
+			slog.String("from", peerAddr(ctx)),
 			slog.Duration("duration", duration),
 			slog.Any("error", err),
 		)
@@ -46,8 +37,68 @@ func (l *LoggerInterceptor) Unary() grpc.UnaryServerInterceptor {
 	}
 }
 
+// Stream записывает лог после закрытия потока.
+// Помимо времени работы потока в лог попадает кол-во полученных и отправленных сообщений.
 func (l *LoggerInterceptor) Stream() grpc.StreamServerInterceptor {
-	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		return handler(srv, stream)
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+
+		// Вызов обработчика потока с подсчетом сообщений
+		counter := &countingStream{ServerStream: stream}
+		err := handler(srv, counter)
+
+		// Подсчет времени работы потока
+		duration := time.Since(startTime)
+
+		// Запись лога
+		slog.Info(
+			"incoming stream gRPC request",
+			slog.String("method", info.FullMethod),
+			slog.String("from", peerAddr(stream.Context())),
+			slog.Duration("duration", duration),
+			slog.Int("received", counter.received),
+			slog.Int("sent", counter.sent),
+			slog.Any("error", err),
+		)
+
+		return err
+	}
+}
+
+// countingStream выступает оберткой над grpc.ServerStream.
+// Сохраняет кол-во успешно полученных и отправленных сообщений.
+// gRPC запрещает одновременный вызов одного метода потока из нескольких горутин,
+// поэтому каждый счетчик изменяется не более чем одной горутиной одновременно.
+type countingStream struct {
+	grpc.ServerStream
+	received int
+	sent     int
+}
+
+// RecvMsg переопределение функции grpc.ServerStream.RecvMsg(any).
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
+
+// SendMsg переопределение функции grpc.ServerStream.SendMsg(any).
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
+// Хелпер функция получения адреса клиента из контекста запроса.
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		slog.Error("unable to get client IP")
+		return "unknown"
 	}
+	return p.Addr.String()
 }
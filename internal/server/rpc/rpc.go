@@ -2,9 +2,13 @@ package rpc
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"net"
+	"time"
 
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc/interceptors"
 	"github.com/FlutterDizaster/ya-metrics/internal/view"
 	pb "github.com/FlutterDizaster/ya-metrics/proto"
@@ -15,11 +19,17 @@ import (
 )
 
 type MetricsStorage interface {
-	AddMetrics(metrics ...view.Metric) ([]view.Metric, error)
+	AddMetrics(ctx context.Context, metrics ...view.Metric) ([]view.Metric, error)
+	GetMetric(ctx context.Context, kind string, name string, labels view.Labels) (view.Metric, error)
+	ListMetrics(ctx context.Context, query view.ListQuery) (view.MetricsPage, error)
+	DeleteMetrics(ctx context.Context, metrics ...view.Metric) error
+	ResetCounter(ctx context.Context, name string, labels view.Labels) (view.Metric, error)
+	Ping(ctx context.Context) error
 }
 
 type Settings struct {
 	Storage      MetricsStorage
+	Broker       *broker.Broker // Шина обновлений метрик. nil - поток WatchMetrics недоступен
 	Addr         string
 	Interceptors []interceptors.Interceptor
 }
@@ -29,8 +39,10 @@ type Settings struct {
 type MetricsService struct {
 	pb.UnimplementedMetricsServiceServer
 	storage      MetricsStorage
+	broker       *broker.Broker
 	addr         string
 	interceptors []interceptors.Interceptor
+	sessions     streamSessions
 }
 
 // New - создание экземпляра MetricsService.
@@ -39,6 +51,7 @@ type MetricsService struct {
 func New(settings Settings) *MetricsService {
 	return &MetricsService{
 		storage:      settings.Storage,
+		broker:       settings.Broker,
 		addr:         settings.Addr,
 		interceptors: settings.Interceptors,
 	}
@@ -56,14 +69,7 @@ func (s *MetricsService) Start(ctx context.Context) error {
 		return err
 	}
 
-	interceptors := make([]grpc.UnaryServerInterceptor, 0, len(s.interceptors))
-	for i := range s.interceptors {
-		interceptors = append(interceptors, s.interceptors[i].Unary())
-	}
-
-	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
-
-	pb.RegisterMetricsServiceServer(srv, s)
+	srv := s.newServer()
 
 	eg := errgroup.Group{}
 
@@ -78,17 +84,43 @@ func (s *MetricsService) Start(ctx context.Context) error {
 	return eg.Wait()
 }
 
+// Хелпер метод создания gRPC сервера с зарегистрированным сервисом.
+// Интерцепторы сервиса применяются как к унарным запросам, так и к потокам, в порядке их перечисления.
+// Параметры сервера, необходимые интерцепторам, применяются при создании сервера.
+func (s *MetricsService) newServer() *grpc.Server {
+	unary := make([]grpc.UnaryServerInterceptor, 0, len(s.interceptors))
+	stream := make([]grpc.StreamServerInterceptor, 0, len(s.interceptors))
+	var opts []grpc.ServerOption
+	for i := range s.interceptors {
+		unary = append(unary, s.interceptors[i].Unary())
+		stream = append(stream, s.interceptors[i].Stream())
+		if optioner, ok := s.interceptors[i].(interceptors.ServerOptioner); ok {
+			opts = append(opts, optioner.ServerOptions()...)
+		}
+	}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	srv := grpc.NewServer(opts...)
+
+	pb.RegisterMetricsServiceServer(srv, s)
+
+	return srv
+}
+
 // AddMetrics - gRPC обработчик добавления метрик в хранилище.
 // Метод принимает слайс метрик для послежующего добавления их в репозиторий и возвращает слайс обновленных метрик.
 func (s *MetricsService) AddMetrics(
-	_ context.Context,
+	ctx context.Context,
 	req *pb.AddMetricsRequest,
 ) (*pb.AddMetricsResponse, error) {
 	metrics := view.UnmarshalGRPCMetrics(req.GetMetrics())
 
-	resutl, err := s.storage.AddMetrics(metrics...)
+	resutl, err := s.storage.AddMetrics(ctx, metrics...)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to add metrics: %v", err)
+		return nil, status.Errorf(storageErrorCode(err), "failed to add metrics: %v", err)
 	}
 
 	resp := &pb.AddMetricsResponse{
@@ -97,3 +129,198 @@ func (s *MetricsService) AddMetrics(
 
 	return resp, nil
 }
+
+// DeleteMetrics - gRPC обработчик удаления метрик из хранилища.
+// Метрики идентифицируются типом, ID и набором меток, значения метрик не учитываются.
+// Если хотя бы одна метрика не найдена, не удаляется ни одна и возвращается код NotFound.
+func (s *MetricsService) DeleteMetrics(
+	ctx context.Context,
+	req *pb.DeleteMetricsRequest,
+) (*pb.DeleteMetricsResponse, error) {
+	metrics := view.UnmarshalGRPCMetrics(req.GetMetrics())
+
+	if err := s.storage.DeleteMetrics(ctx, metrics...); err != nil {
+		return nil, status.Errorf(storageErrorCode(err), "failed to delete metrics: %v", err)
+	}
+
+	return &pb.DeleteMetricsResponse{}, nil
+}
+
+// ResetCounter - gRPC обработчик сброса значения счетчика в ноль.
+// Возвращает обновленный счетчик.
+func (s *MetricsService) ResetCounter(
+	ctx context.Context,
+	req *pb.ResetCounterRequest,
+) (*pb.ResetCounterResponse, error) {
+	var labels view.Labels
+	if len(req.GetLabels()) > 0 {
+		labels = req.GetLabels()
+	}
+
+	metric, err := s.storage.ResetCounter(ctx, req.GetId(), labels)
+	if err != nil {
+		return nil, status.Errorf(storageErrorCode(err), "failed to reset counter: %v", err)
+	}
+
+	resp := &pb.ResetCounterResponse{
+		Metric: view.MarshalGRPCMetrics([]view.Metric{metric})[0],
+	}
+
+	return resp, nil
+}
+
+// GetMetric - gRPC обработчик получения метрики по её типу, имени и набору меток.
+// Если метрика не найдена, возвращает код NotFound.
+func (s *MetricsService) GetMetric(
+	ctx context.Context,
+	req *pb.GetMetricRequest,
+) (*pb.GetMetricResponse, error) {
+	var labels view.Labels
+	if len(req.GetLabels()) > 0 {
+		labels = req.GetLabels()
+	}
+
+	metric, err := s.storage.GetMetric(ctx, req.GetKind(), req.GetId(), labels)
+	if err != nil {
+		return nil, status.Errorf(storageErrorCode(err), "failed to get metric: %v", err)
+	}
+
+	resp := &pb.GetMetricResponse{
+		Metric: view.MarshalGRPCMetrics([]view.Metric{metric})[0],
+	}
+
+	return resp, nil
+}
+
+// ListMetrics - gRPC обработчик получения страницы списка метрик.
+// Параметры фильтрации, сортировки и пагинации совпадают с HTTP обработчиком списка метрик.
+// Если параметры запроса некорректны, возвращает код InvalidArgument.
+func (s *MetricsService) ListMetrics(
+	ctx context.Context,
+	req *pb.ListMetricsRequest,
+) (*pb.ListMetricsResponse, error) {
+	page, err := s.storage.ListMetrics(ctx, view.UnmarshalGRPCListQuery(req))
+	if err != nil {
+		return nil, status.Errorf(storageErrorCode(err), "failed to list metrics: %v", err)
+	}
+
+	resp := &pb.ListMetricsResponse{
+		Metrics:    view.MarshalGRPCMetrics(page.Metrics),
+		NextCursor: page.NextCursor,
+	}
+
+	return resp, nil
+}
+
+// Ping - gRPC обработчик проверки доступности хранилища.
+// Если хранилище недоступно, возвращает код Unavailable.
+func (s *MetricsService) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.storage.Ping(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "storage unavailable: %v", err)
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// StreamMetrics - gRPC обработчик потока пакетов метрик.
+// Пакеты записываются в хранилище по очереди, после записи каждого пакета клиенту
+// отправляется подтверждение с номером пакета, поэтому клиент может ограничивать
+// кол-во неподтвержденных пакетов. Ошибка записи пакета передается в подтверждении.
+// Временная ошибка хранилища (Unavailable, DeadlineExceeded) после подтверждения закрывает поток,
+// чтобы следующие пакеты не были записаны раньше пакета, который клиент отправит повторно.
+// Если клиент передает идентификатор, пакет с номером не больше последнего обработанного
+// для этого клиента считается повторной отправкой после обрыва потока
+// и подтверждается без записи в хранилище.
+func (s *MetricsService) StreamMetrics(stream pb.MetricsService_StreamMetricsServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		apply := func() error {
+			metrics := view.UnmarshalGRPCMetrics(req.GetMetrics())
+			_, addErr := s.storage.AddMetrics(stream.Context(), metrics...)
+			return addErr
+		}
+
+		var applyErr error
+		if clientID := req.GetClientId(); clientID == "" {
+			applyErr = apply()
+		} else {
+			now := time.Now()
+			var applied bool
+			applied, applyErr = s.sessions.get(clientID, now).process(req.GetSeq(), now, apply)
+			if !applied {
+				slog.Debug("stream batch duplicate", slog.String("client", clientID), slog.Uint64("seq", req.GetSeq()))
+			}
+		}
+
+		ack := &pb.StreamMetricsResponse{Seq: req.GetSeq()}
+		if applyErr != nil {
+			slog.Error("stream batch error", slog.Uint64("seq", req.GetSeq()), slog.String("error", applyErr.Error()))
+			ack.Code = uint32(storageErrorCode(applyErr))
+			ack.Error = applyErr.Error()
+		}
+
+		if err = stream.Send(ack); err != nil {
+			return err
+		}
+		if code := codes.Code(ack.GetCode()); retryableCode(code) {
+			return status.Error(code, ack.GetError())
+		}
+	}
+}
+
+// WatchMetrics - gRPC обработчик потока обновлений метрик.
+// После каждой записи в хранилище клиенту отправляется пакет обновленных метрик,
+// удовлетворяющих фильтру запроса. Если параметры фильтра некорректны, возвращает код InvalidArgument.
+// Если клиент не успевает читать обновления, поток закрывается с кодом ResourceExhausted.
+func (s *MetricsService) WatchMetrics(
+	req *pb.WatchMetricsRequest,
+	stream pb.MetricsService_WatchMetricsServer,
+) error {
+	if s.broker == nil {
+		return status.Error(codes.Unimplemented, "metrics watching is disabled")
+	}
+
+	query := view.UnmarshalGRPCWatchQuery(req)
+	if err := query.Normalize(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sub := s.broker.Subscribe(query.Matcher())
+	defer s.broker.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Updates():
+			if !ok {
+				return watchErrorStatus(sub.Err())
+			}
+			resp := &pb.WatchMetricsResponse{
+				Metrics: view.MarshalGRPCMetrics(event.Metrics),
+				Deleted: event.Deleted,
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Хелпер функция для получения статуса закрытия потока обновлений по причине закрытия подписки.
+func watchErrorStatus(err error) error {
+	switch {
+	case errors.Is(err, broker.ErrSlowSubscriber):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, broker.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return nil
+	}
+}
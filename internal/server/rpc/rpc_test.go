@@ -0,0 +1,348 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc/interceptors"
+	"github.com/FlutterDizaster/ya-metrics/internal/view"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockStorage struct {
+	metrics view.Metrics
+	query   view.ListQuery
+	pingErr error
+}
+
+func (m *mockStorage) AddMetrics(_ context.Context, metrics ...view.Metric) ([]view.Metric, error) {
+	for _, metric := range metrics {
+		if metric.MType == "" {
+			return nil, repository.ErrWrongType
+		}
+	}
+	m.metrics = append(m.metrics, metrics...)
+	return metrics, nil
+}
+
+func (m *mockStorage) GetMetric(_ context.Context, kind string, name string, labels view.Labels) (view.Metric, error) {
+	for _, metric := range m.metrics {
+		if metric.MType == kind && metric.Key() == name+labels.String() {
+			return metric, nil
+		}
+	}
+	return view.Metric{}, repository.ErrNotFound
+}
+
+func (m *mockStorage) ListMetrics(_ context.Context, query view.ListQuery) (view.MetricsPage, error) {
+	m.query = query
+	return view.ListMetrics(m.metrics, query)
+}
+
+func (m *mockStorage) DeleteMetrics(_ context.Context, _ ...view.Metric) error {
+	return nil
+}
+
+func (m *mockStorage) ResetCounter(_ context.Context, _ string, _ view.Labels) (view.Metric, error) {
+	return view.Metric{}, repository.ErrNotFound
+}
+
+func (m *mockStorage) Ping(_ context.Context) error {
+	return m.pingErr
+}
+
+// Хелпер для запуска сервиса на соединении в памяти.
+func newTestClient(t *testing.T, settings Settings) pb.MetricsServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	srv := New(settings).newServer()
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return pb.NewMetricsServiceClient(conn)
+}
+
+func TestMetricsService_GetMetric(t *testing.T) {
+	value := 1.5
+	storage := &mockStorage{metrics: view.Metrics{
+		{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "a"}, Value: &value},
+	}}
+	client := newTestClient(t, Settings{Storage: storage})
+	ctx := context.Background()
+
+	resp, err := client.GetMetric(ctx, &pb.GetMetricRequest{
+		Id:     "cpu",
+		Kind:   view.KindGauge,
+		Labels: map[string]string{"host": "a"},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, value, resp.GetMetric().GetValue(), 0)
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "cpu", Kind: view.KindGauge})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetricsService_ListMetrics(t *testing.T) {
+	storage := &mockStorage{metrics: view.Metrics{
+		{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "a"}},
+		{ID: "cpu", MType: view.KindGauge, Labels: view.Labels{"host": "b"}},
+		{ID: "requests", MType: view.KindCounter, Labels: view.Labels{"host": "a"}},
+	}}
+	client := newTestClient(t, Settings{Storage: storage})
+	ctx := context.Background()
+
+	resp, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{
+		Labels: map[string]string{"host": "a"},
+		Desc:   true,
+		Limit:  1,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetMetrics(), 1)
+	assert.Equal(t, "requests", resp.GetMetrics()[0].GetId())
+	assert.NotEmpty(t, resp.GetNextCursor())
+	assert.Equal(t, view.ListQuery{Labels: view.Labels{"host": "a"}, Desc: true, Limit: 1}, storage.query)
+
+	resp, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{
+		Labels: map[string]string{"host": "a"},
+		Desc:   true,
+		Limit:  1,
+		Cursor: resp.GetNextCursor(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetMetrics(), 1)
+	assert.Equal(t, "cpu", resp.GetMetrics()[0].GetId())
+	assert.Empty(t, resp.GetNextCursor())
+
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{Sort: "value"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetricsService_Ping(t *testing.T) {
+	storage := &mockStorage{}
+	client := newTestClient(t, Settings{Storage: storage})
+	ctx := context.Background()
+
+	_, err := client.Ping(ctx, &pb.PingRequest{})
+	require.NoError(t, err)
+
+	storage.pingErr = errors.New("connection refused")
+	_, err = client.Ping(ctx, &pb.PingRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestMetricsService_StreamMetrics(t *testing.T) {
+	storage := &mockStorage{}
+	client := newTestClient(t, Settings{Storage: storage})
+
+	stream, err := client.StreamMetrics(context.Background())
+	require.NoError(t, err)
+
+	batches := []*pb.StreamMetricsRequest{
+		{Seq: 1, Metrics: []*pb.Metric{{Id: "cpu", Kind: view.KindGauge, Value: 1}}},
+		{Seq: 2, Metrics: []*pb.Metric{{Id: "broken"}}},
+		{Seq: 3, Metrics: []*pb.Metric{{Id: "requests", Kind: view.KindCounter, Delta: 1}}},
+	}
+	for _, batch := range batches {
+		require.NoError(t, stream.Send(batch))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	// Ошибка записи пакета передается в подтверждении и не закрывает поток
+	wantCodes := []codes.Code{codes.OK, codes.InvalidArgument, codes.OK}
+	for i, want := range wantCodes {
+		ack, recvErr := stream.Recv()
+		require.NoError(t, recvErr)
+		assert.Equal(t, batches[i].GetSeq(), ack.GetSeq())
+		assert.Equal(t, want, codes.Code(ack.GetCode()))
+	}
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+
+	assert.Len(t, storage.metrics, 2)
+}
+
+func TestMetricsService_StreamMetricsDuplicates(t *testing.T) {
+	storage := &mockStorage{}
+	client := newTestClient(t, Settings{Storage: storage})
+
+	// Хелпер отправки пакетов в новый поток с ожиданием подтверждений
+	sendBatches := func(batches ...*pb.StreamMetricsRequest) {
+		stream, err := client.StreamMetrics(context.Background())
+		require.NoError(t, err)
+		for _, batch := range batches {
+			require.NoError(t, stream.Send(batch))
+		}
+		require.NoError(t, stream.CloseSend())
+		for _, batch := range batches {
+			ack, err := stream.Recv()
+			require.NoError(t, err)
+			assert.Equal(t, batch.GetSeq(), ack.GetSeq())
+			assert.Equal(t, codes.OK, codes.Code(ack.GetCode()))
+		}
+	}
+	batch := func(clientID string, seq uint64, id string) *pb.StreamMetricsRequest {
+		return &pb.StreamMetricsRequest{
+			Seq:      seq,
+			ClientId: clientID,
+			Metrics:  []*pb.Metric{{Id: id, Kind: view.KindCounter, Delta: 1}},
+		}
+	}
+
+	sendBatches(batch("agent1", 1, "a"), batch("agent1", 2, "b"))
+	// Повторная отправка после переподключения подтверждается без записи
+	sendBatches(batch("agent1", 2, "b"), batch("agent1", 3, "c"))
+	// Номера пакетов других клиентов и клиентов без идентификатора не учитываются
+	sendBatches(batch("agent2", 1, "d"), batch("", 1, "e"), batch("", 1, "f"))
+
+	ids := make([]string, 0, len(storage.metrics))
+	for _, metric := range storage.metrics {
+		ids = append(ids, metric.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, ids)
+}
+
+func TestMetricsService_WatchMetrics(t *testing.T) {
+	b := broker.New(broker.Settings{})
+	client := newTestClient(t, Settings{Storage: &mockStorage{}, Broker: b})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchMetrics(ctx, &pb.WatchMetricsRequest{Prefix: "cpu"})
+	require.NoError(t, err)
+
+	// Обновления публикуются до получения, так как подписка создается сервером асинхронно
+	cpu, mem := 1.5, 2.5
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.Publish([]view.Metric{
+					{ID: "mem", MType: view.KindGauge, Value: &mem},
+					{ID: "cpu", MType: view.KindGauge, Value: &cpu},
+				})
+			}
+		}
+	}()
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Len(t, resp.GetMetrics(), 1)
+	assert.Equal(t, "cpu", resp.GetMetrics()[0].GetId())
+	assert.InDelta(t, cpu, resp.GetMetrics()[0].GetValue(), 0)
+
+	// Удаление метрик передается пакетом с признаком deleted
+	b.PublishDeleted([]view.Metric{{ID: "cpu", MType: view.KindGauge}})
+	for !resp.GetDeleted() {
+		resp, err = stream.Recv()
+		require.NoError(t, err)
+	}
+	require.Len(t, resp.GetMetrics(), 1)
+	assert.Equal(t, "cpu", resp.GetMetrics()[0].GetId())
+}
+
+func TestMetricsService_WatchMetricsErrors(t *testing.T) {
+	closed := broker.New(broker.Settings{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, closed.Start(ctx))
+
+	tests := []struct {
+		name     string
+		broker   *broker.Broker
+		req      *pb.WatchMetricsRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "watching disabled",
+			broker:   nil,
+			req:      &pb.WatchMetricsRequest{},
+			wantCode: codes.Unimplemented,
+		},
+		{
+			name:     "invalid regex",
+			broker:   broker.New(broker.Settings{}),
+			req:      &pb.WatchMetricsRequest{Regex: "("},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "broker closed",
+			broker:   closed,
+			req:      &pb.WatchMetricsRequest{},
+			wantCode: codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, Settings{Storage: &mockStorage{}, Broker: tt.broker})
+
+			stream, err := client.WatchMetrics(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestMetricsService_Interceptors(t *testing.T) {
+	// Адрес соединения в памяти не входит в доверенную подсеть
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	b := broker.New(broker.Settings{})
+	client := newTestClient(t, Settings{
+		Storage: &mockStorage{},
+		Broker:  b,
+		Interceptors: []interceptors.Interceptor{
+			&interceptors.LoggerInterceptor{},
+			&interceptors.AccessFilterInterceptor{TrustedSubnet: subnet},
+		},
+	})
+	ctx := context.Background()
+
+	_, err = client.Ping(ctx, &pb.PingRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.StreamMetrics(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	watch, err := client.WatchMetrics(ctx, &pb.WatchMetricsRequest{})
+	require.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+)
+
+// Время хранения номера последнего пакета клиента потока StreamMetrics
+// после обработки его последнего пакета.
+const streamSessionTTL = time.Hour
+
+// Хелпер структура для хранения номеров последних обработанных пакетов клиентов потока StreamMetrics.
+// Позволяет отбрасывать пакеты, повторно отправленные клиентом после обрыва потока,
+// в том числе если старый поток клиента еще не закрыт сервером.
+// Пакеты клиента, который не отправлял пакеты дольше streamSessionTTL, не отбрасываются.
+type streamSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*streamSession
+}
+
+// Сессия клиента потока StreamMetrics.
+// Блокировка сессии удерживается на время обработки пакета,
+// поэтому пакеты клиента из разных потоков обрабатываются по очереди.
+type streamSession struct {
+	mu      sync.Mutex
+	lastSeq uint64
+	seen    time.Time
+}
+
+// Метод получения сессии клиента clientID.
+// Создает сессию, если ее нет, и удаляет сессии, устаревшие на момент now.
+func (s *streamSessions) get(clientID string, now time.Time) *streamSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[clientID]; ok {
+		return session
+	}
+
+	if s.sessions == nil {
+		s.sessions = make(map[string]*streamSession)
+	}
+	for id, session := range s.sessions {
+		if session.expired(now) {
+			delete(s.sessions, id)
+		}
+	}
+
+	session := &streamSession{seen: now}
+	s.sessions[clientID] = session
+	return session
+}
+
+// Метод обработки пакета seq функцией apply.
+// Если пакет уже был обработан, apply не вызывается и метод возвращает false.
+// Пакет считается обработанным только при успешном выполнении apply,
+// поэтому пакет с ошибкой записи может быть отправлен клиентом повторно.
+func (s *streamSession) process(seq uint64, now time.Time, apply func() error) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen = now
+	if seq <= s.lastSeq {
+		return false, nil
+	}
+	if err := apply(); err != nil {
+		return true, err
+	}
+	s.lastSeq = seq
+	return true, nil
+}
+
+// Метод проверки устаревания сессии.
+func (s *streamSession) expired(now time.Time) bool {
+	if !s.mu.TryLock() {
+		// Сессия используется потоком
+		return false
+	}
+	defer s.mu.Unlock()
+	return now.Sub(s.seen) > streamSessionTTL
+}
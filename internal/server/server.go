@@ -4,12 +4,17 @@ import (
 	"context"
 	"log/slog"
 	"net"
+	"time"
 
 	"github.com/FlutterDizaster/ya-metrics/internal/application"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/api"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/api/middleware"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/broker"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/janitor"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/boltdb"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/memory"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/postgres"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/sqlite"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc"
 	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc/interceptors"
 	pemreader "github.com/FlutterDizaster/ya-metrics/pkg/pem-reader"
@@ -21,11 +26,13 @@ type IService interface {
 	Start(ctx context.Context) error
 }
 
-// Интерфейс IStorageService объединяет интерфейсы IService и api.MetricsStorage.
+// Интерфейс IStorageService объединяет интерфейсы IService, api.MetricsStorage,
+// rpc.MetricsStorage и janitor.Storage.
 type IStorageService interface {
 	IService
 	api.MetricsStorage
 	rpc.MetricsStorage
+	janitor.Storage
 }
 
 // Settings хранит параметры необходимые для создания экземпляра Server.
@@ -47,8 +54,93 @@ type Settings struct {
 	// Флаг восстановления данных. Если true, то данные будут восстановлены из бекапа
 	Restore bool `name:"restore" short:"r" default:"false" env:"RESTORE" usage:"Restore metrics from backup"`
 
-	// Строка подключения к базе данных
-	PGConnString string `name:"dbconn" short:"d" default:"" env:"DATABASE_DSN" usage:"Postgres connection string"`
+	// Строка подключения к базе данных. Строка вида sqlite://<путь> подключает хранилище SQLite
+	//nolint:lll // tags too long. idk how to fix that
+	PGConnString string `name:"dbconn" short:"d" default:"" env:"DATABASE_DSN" usage:"Postgres connection string or sqlite://<path> for SQLite storage"`
+
+	// Путь к файлу встраиваемого хранилища. Используется, если не указана строка подключения к базе данных
+	//nolint:lll // tags too long. idk how to fix that
+	BoltFilePath string `name:"bolt-file" default:"" env:"BOLT_FILE_PATH" usage:"Embedded storage file path. Used when database connection string is not set"`
+
+	// Время хранения истории значений метрик в секундах. 0 - история хранится бессрочно
+	//nolint:lll // tags too long. idk how to fix that
+	HistoryRetention int `name:"history-retention" default:"86400" env:"HISTORY_RETENTION" usage:"Metrics history retention in seconds"`
+
+	// Кол-во хранимых предыдущих бекапов хранилища в памяти. 0 - хранится только текущий
+	//nolint:lll // tags too long. idk how to fix that
+	BackupKeep int `name:"backup-keep" default:"3" env:"BACKUP_KEEP" usage:"Number of previous backups kept for rollback"`
+
+	// Формат метрик в бекапе хранилища в памяти: json или proto
+	//nolint:lll // tags too long. idk how to fix that
+	BackupFormat string `name:"backup-format" default:"json" env:"BACKUP_FORMAT" usage:"In-memory storage backup format: json or proto"`
+
+	// Алгоритм сжатия бекапа хранилища в памяти: none, gzip или zstd
+	//nolint:lll // tags too long. idk how to fix that
+	BackupCompression string `name:"backup-compression" default:"none" env:"BACKUP_COMPRESSION" usage:"In-memory storage backup compression: none, gzip or zstd"`
+
+	// Кол-во сегментов хранилища в памяти с отдельными блокировками
+	//nolint:lll // tags too long. idk how to fix that
+	MemoryShards int `name:"memory-shards" default:"32" env:"MEMORY_SHARDS" usage:"Number of lock-striped shards of in-memory storage"`
+
+	// Кол-во хранимых значений каждой метрики в хранилищах в памяти, bbolt и SQLite. 0 - история не хранится
+	//nolint:lll // tags too long. idk how to fix that
+	HistoryDepth int `name:"history-depth" default:"1000" env:"HISTORY_DEPTH" usage:"Metrics history depth for in-memory, bbolt and SQLite storages"`
+
+	// Политика синхронизации журнала упреждающей записи хранилища в памяти: always, interval, never или off
+	//nolint:lll // tags too long. idk how to fix that
+	WALSync string `name:"wal-sync" default:"interval" env:"WAL_SYNC" usage:"In-memory storage WAL fsync policy: always, interval, never or off"`
+
+	// Интервал синхронизации журнала упреждающей записи в секундах
+	//nolint:lll // tags too long. idk how to fix that
+	WALSyncInterval int `name:"wal-sync-interval" default:"1" env:"WAL_SYNC_INTERVAL" usage:"In-memory storage WAL fsync interval in seconds"`
+
+	// Время выполнения чтения метрик из хранилища в секундах. 0 - без ограничения
+	//nolint:lll // tags too long. idk how to fix that
+	StorageReadTimeout int `name:"storage-read-timeout" default:"1" env:"STORAGE_READ_TIMEOUT" usage:"Storage read timeout in seconds"`
+
+	// Время выполнения записи метрик в хранилище в секундах. 0 - без ограничения
+	//nolint:lll // tags too long. idk how to fix that
+	StorageWriteTimeout int `name:"storage-write-timeout" default:"3" env:"STORAGE_WRITE_TIMEOUT" usage:"Storage write timeout in seconds"`
+
+	// Время выполнения чтения истории метрик из хранилища в секундах. 0 - без ограничения
+	//nolint:lll // tags too long. idk how to fix that
+	StorageHistoryTimeout int `name:"storage-history-timeout" default:"3" env:"STORAGE_HISTORY_TIMEOUT" usage:"Storage history read timeout in seconds"`
+
+	// Время выполнения проверки подключения к хранилищу в секундах. 0 - без ограничения
+	//nolint:lll // tags too long. idk how to fix that
+	StoragePingTimeout int `name:"storage-ping-timeout" default:"1" env:"STORAGE_PING_TIMEOUT" usage:"Storage ping timeout in seconds"`
+
+	// Количество повторов операции с БД при временных ошибках
+	//nolint:lll // tags too long. idk how to fix that
+	StorageRetryCount int `name:"storage-retry-count" default:"3" env:"STORAGE_RETRY_COUNT" usage:"Storage operation retry count on transient errors"`
+
+	// Интервал перед первым повтором операции с БД в миллисекундах
+	//nolint:lll // tags too long. idk how to fix that
+	StorageRetryInterval int `name:"storage-retry-interval" default:"100" env:"STORAGE_RETRY_INTERVAL" usage:"Storage retry interval in milliseconds"`
+
+	// Максимальный интервал между повторами операции с БД в миллисекундах
+	//nolint:lll // tags too long. idk how to fix that
+	StorageRetryMaxWaitTime int `name:"storage-retry-max-wait" default:"1000" env:"STORAGE_RETRY_MAX_WAIT" usage:"Storage max retry interval in milliseconds"`
+
+	// Кол-во временных ошибок БД подряд, после которого обращения к БД приостанавливаются
+	//nolint:lll // tags too long. idk how to fix that
+	StorageBreakerThreshold int `name:"storage-breaker-threshold" default:"5" env:"STORAGE_BREAKER_THRESHOLD" usage:"Storage circuit breaker failure threshold"`
+
+	// Время приостановки обращений к БД в секундах
+	//nolint:lll // tags too long. idk how to fix that
+	StorageBreakerTimeout int `name:"storage-breaker-timeout" default:"10" env:"STORAGE_BREAKER_TIMEOUT" usage:"Storage circuit breaker open timeout in seconds"`
+
+	// Время в секундах, после которого не обновлявшаяся метрика удаляется вместе с историей. 0 - метрики не удаляются
+	//nolint:lll // tags too long. idk how to fix that
+	MetricTTL int `name:"metric-ttl" default:"0" env:"METRIC_TTL" usage:"Time in seconds after which a metric without updates is deleted. 0 disables expiry"`
+
+	// Интервал проверки метрик на устаревание в секундах
+	//nolint:lll // tags too long. idk how to fix that
+	JanitorInterval int `name:"janitor-interval" default:"60" env:"JANITOR_INTERVAL" usage:"Interval in seconds between stale metrics checks"`
+
+	// Кол-во пакетов обновлений в буфере подписчика потока обновлений метрик
+	//nolint:lll // tags too long. idk how to fix that
+	WatchBuffer int `name:"watch-buffer" default:"64" env:"WATCH_BUFFER" usage:"Number of pending update batches per metrics watcher before it is disconnected"`
 
 	// Ключ хеширования данных
 	Key string `name:"key" short:"k" default:"" env:"KEY" usage:"Hash key"`
@@ -81,19 +173,26 @@ func New(settings Settings) (*Server, error) {
 	}
 
 	// Создание хранилища метрик
-	storage, err := setupStorage(settings)
+	rawStorage, err := setupStorage(settings)
 	if err != nil {
 		return nil, err
 	}
 
+	// Создание шины обновлений метрик и публикация в неё изменений хранилища
+	metricsBroker := broker.New(broker.Settings{BufferSize: settings.WatchBuffer})
+	storage := &publishingStorage{
+		IStorageService: rawStorage,
+		broker:          metricsBroker,
+	}
+
 	// Создание API сервера
-	apiServer, err := setupHTTPServer(settings, storage)
+	apiServer, err := setupHTTPServer(settings, storage, metricsBroker)
 	if err != nil {
 		return nil, err
 	}
 
 	// Создание gRPC сервера
-	grpcServer := setupGRPCServer(settings, storage)
+	grpcServer := setupGRPCServer(settings, storage, metricsBroker)
 
 	// Создание экземпляра Server
 	server := &Server{}
@@ -103,6 +202,10 @@ func New(settings Settings) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = server.RegisterService(metricsBroker)
+	if err != nil {
+		return nil, err
+	}
 	err = server.RegisterService(apiServer)
 	if err != nil {
 		return nil, err
@@ -112,6 +215,18 @@ func New(settings Settings) (*Server, error) {
 		return nil, err
 	}
 
+	// Регистрация сервиса удаления устаревших метрик
+	if settings.MetricTTL > 0 {
+		err = server.RegisterService(janitor.New(janitor.Settings{
+			Storage:  storage,
+			TTL:      time.Duration(settings.MetricTTL) * time.Second,
+			Interval: time.Duration(settings.JanitorInterval) * time.Second,
+		}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	slog.Debug("Application instance created")
 	return server, nil
 }
@@ -164,7 +279,11 @@ func setupMiddlewares(settings Settings) ([]middleware.Middleware, error) {
 	return middlewares, nil
 }
 
-func setupHTTPServer(settings Settings, storage api.MetricsStorage) (*api.API, error) {
+func setupHTTPServer(
+	settings Settings,
+	storage api.MetricsStorage,
+	metricsBroker *broker.Broker,
+) (*api.API, error) {
 	// configure middlewares
 	middlewares, err := setupMiddlewares(settings)
 	if err != nil {
@@ -175,8 +294,19 @@ func setupHTTPServer(settings Settings, storage api.MetricsStorage) (*api.API, e
 	routerSettings := &api.Settings{
 		Addr:        settings.URL,
 		Storage:     storage,
+		Broker:      metricsBroker,
 		Middlewares: middlewares,
 	}
+	// Запросы без тела, изменяющие метрики, подписываются по методу и пути
+	if settings.Key != "" {
+		routerSettings.URIValidator = &middleware.URIValidator{
+			Key: []byte(settings.Key),
+		}
+		routerSettings.BodyValidator = &middleware.Validator{
+			Key:    []byte(settings.Key),
+			Strict: true,
+		}
+	}
 	// Создание api сервера
 	apiServer := api.New(routerSettings)
 
@@ -197,13 +327,24 @@ func setupInterceptors(settings Settings) []interceptors.Interceptor {
 		}
 	}
 
+	if settings.Key != "" {
+		intrcpts = append(intrcpts, &interceptors.HashInterceptor{
+			Key: []byte(settings.Key),
+		})
+	}
+
 	return intrcpts
 }
 
-func setupGRPCServer(settings Settings, storage rpc.MetricsStorage) *rpc.MetricsService {
+func setupGRPCServer(
+	settings Settings,
+	storage rpc.MetricsStorage,
+	metricsBroker *broker.Broker,
+) *rpc.MetricsService {
 	rpcSettings := rpc.Settings{
 		Addr:         settings.RPC,
 		Storage:      storage,
+		Broker:       metricsBroker,
 		Interceptors: setupInterceptors(settings),
 	}
 
@@ -214,18 +355,60 @@ func setupStorage(settings Settings) (IStorageService, error) {
 	// Создание экземпляра StorageService
 	var storage IStorageService
 	var err error
-	// Если строка для поключения к бд не указана
-	if settings.PGConnString == "" {
+	switch {
+	// Если указана строка подключения к SQLite
+	case sqlite.IsDSN(settings.PGConnString):
+		storageSettings := sqlite.Settings{
+			DSN:              settings.PGConnString,
+			HistoryDepth:     settings.HistoryDepth,
+			HistoryRetention: time.Duration(settings.HistoryRetention) * time.Second,
+			ReadTimeout:      time.Duration(settings.StorageReadTimeout) * time.Second,
+			WriteTimeout:     time.Duration(settings.StorageWriteTimeout) * time.Second,
+			HistoryTimeout:   time.Duration(settings.StorageHistoryTimeout) * time.Second,
+			PingTimeout:      time.Duration(settings.StoragePingTimeout) * time.Second,
+		}
+		storage, err = sqlite.New(&storageSettings)
+	// Если указана строка для поключения к бд
+	case settings.PGConnString != "":
+		// Создание хранилища с подключением к базе
+		storageSettings := postgres.Settings{
+			ConnString:       settings.PGConnString,
+			HistoryRetention: time.Duration(settings.HistoryRetention) * time.Second,
+			ReadTimeout:      time.Duration(settings.StorageReadTimeout) * time.Second,
+			WriteTimeout:     time.Duration(settings.StorageWriteTimeout) * time.Second,
+			HistoryTimeout:   time.Duration(settings.StorageHistoryTimeout) * time.Second,
+			PingTimeout:      time.Duration(settings.StoragePingTimeout) * time.Second,
+			RetryCount:       settings.StorageRetryCount,
+			RetryInterval:    time.Duration(settings.StorageRetryInterval) * time.Millisecond,
+			RetryMaxWaitTime: time.Duration(settings.StorageRetryMaxWaitTime) * time.Millisecond,
+			BreakerThreshold: settings.StorageBreakerThreshold,
+			BreakerTimeout:   time.Duration(settings.StorageBreakerTimeout) * time.Second,
+		}
+		storage, err = postgres.New(&storageSettings)
+	// Если указан файл встраиваемого хранилища
+	case settings.BoltFilePath != "":
+		storageSettings := boltdb.Settings{
+			Path:             settings.BoltFilePath,
+			HistoryDepth:     settings.HistoryDepth,
+			HistoryRetention: time.Duration(settings.HistoryRetention) * time.Second,
+		}
+		storage, err = boltdb.New(&storageSettings)
+	default:
 		// Создание локального хранилища метрик
 		storageSettings := memory.Settings{
-			StoreInterval:   settings.StoreInterval,
-			FileStoragePath: settings.FileStoragePath,
-			Restore:         settings.Restore,
+			StoreInterval:       settings.StoreInterval,
+			FileStoragePath:     settings.FileStoragePath,
+			Restore:             settings.Restore,
+			BackupKeep:          settings.BackupKeep,
+			SnapshotFormat:      settings.BackupFormat,
+			SnapshotCompression: settings.BackupCompression,
+			HistoryDepth:        settings.HistoryDepth,
+			HistoryDuration:     settings.HistoryRetention,
+			WALSync:             settings.WALSync,
+			WALSyncInterval:     settings.WALSyncInterval,
+			Shards:              settings.MemoryShards,
 		}
 		storage, err = memory.New(&storageSettings)
-	} else {
-		// Создание хранилища с подключением к базе
-		storage, err = postgres.New(settings.PGConnString)
 	}
 	if err != nil {
 		slog.Error("error creating storage. forcing exit.", slog.String("error", err.Error()))
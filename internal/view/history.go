@@ -0,0 +1,120 @@
+package view
+
+import (
+	"errors"
+	"time"
+)
+
+// Функции агрегации значений при прореживании истории метрики.
+const (
+	AggregationAvg  = "avg"  // Среднее значение за интервал
+	AggregationMin  = "min"  // Минимальное значение за интервал
+	AggregationMax  = "max"  // Максимальное значение за интервал
+	AggregationLast = "last" // Последнее значение за интервал
+)
+
+// Ошибка возвращается при запросе истории с неподдерживаемой функцией агрегации.
+var ErrUnknownAggregation = errors.New("unknown aggregation")
+
+// Alias к срезу сэмплов.
+//
+//easyjson:json
+type Samples []Sample
+
+// Sample - значение метрики в момент времени.
+// Для метрик типа gauge хранит значение метрики, для метрик типа counter - накопленное значение счетчика.
+//
+// @swagger:model
+//
+//go:generate easyjson history.go
+//easyjson:json
+type Sample struct {
+	// Sample time
+	// Required: true
+	Timestamp time.Time `json:"timestamp"`
+	// Sample value
+	// Required: true
+	Value float64 `json:"value"`
+}
+
+// HistoryQuery - параметры запроса истории значений метрики.
+// Если Step больше нуля, то значения прореживаются: на каждый интервал Step
+// возвращается одно значение, вычисленное функцией Aggregation.
+type HistoryQuery struct {
+	Kind        string        // Тип метрики
+	Name        string        // Имя метрики
+	Labels      Labels        // Метки метрики
+	From        time.Time     // Начало интервала (включительно)
+	To          time.Time     // Конец интервала (включительно)
+	Step        time.Duration // Шаг прореживания
+	Aggregation string        // Функция агрегации значений внутри шага
+}
+
+// SampleValue возвращает значение метрики для записи в историю.
+// Второе возвращаемое значение false, если метрика не записывается в историю.
+// История хранится только для метрик типа gauge и counter.
+func (m *Metric) SampleValue() (float64, bool) {
+	switch m.MType {
+	case KindGauge:
+		if m.Value == nil {
+			return 0, false
+		}
+		return *m.Value, true
+	case KindCounter:
+		if m.Delta == nil {
+			return 0, false
+		}
+		return float64(*m.Delta), true
+	}
+	return 0, false
+}
+
+// Downsample прореживает сэмплы, упорядоченные по времени.
+// Время делится на интервалы длиной step, выровненные относительно unix-эпохи.
+// Для каждого интервала, содержащего хотя бы один сэмпл, возвращается один сэмпл
+// с временем начала интервала и значением, вычисленным функцией aggregation.
+// Возвращает ErrUnknownAggregation если функция агрегации не поддерживается.
+func Downsample(samples Samples, step time.Duration, aggregation string) (Samples, error) {
+	switch aggregation {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast:
+	default:
+		return nil, ErrUnknownAggregation
+	}
+
+	result := make(Samples, 0)
+	var (
+		bucket time.Time
+		count  int
+	)
+	for _, sample := range samples {
+		start := time.Unix(0, sample.Timestamp.UnixNano()/int64(step)*int64(step))
+
+		// Начало нового интервала
+		if count == 0 || !start.Equal(bucket) {
+			if count > 0 && aggregation == AggregationAvg {
+				result[len(result)-1].Value /= float64(count)
+			}
+			bucket, count = start, 1
+			result = append(result, Sample{Timestamp: start, Value: sample.Value})
+			continue
+		}
+
+		count++
+		last := &result[len(result)-1]
+		switch aggregation {
+		case AggregationAvg:
+			last.Value += sample.Value
+		case AggregationMin:
+			last.Value = min(last.Value, sample.Value)
+		case AggregationMax:
+			last.Value = max(last.Value, sample.Value)
+		case AggregationLast:
+			last.Value = sample.Value
+		}
+	}
+	if count > 0 && aggregation == AggregationAvg {
+		result[len(result)-1].Value /= float64(count)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,161 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package view
+
+import (
+	json "encoding/json"
+
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView(in *jlexer.Lexer, out *Samples) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(Samples, 0, 2)
+			} else {
+				*out = Samples{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v1 Sample
+			(v1).UnmarshalEasyJSON(in)
+			*out = append(*out, v1)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView(out *jwriter.Writer, in Samples) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			(v3).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Samples) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Samples) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Samples) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Samples) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView(l, v)
+}
+func easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView1(in *jlexer.Lexer, out *Sample) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "timestamp":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Timestamp).UnmarshalJSON(data))
+			}
+		case "value":
+			out.Value = float64(in.Float64())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView1(out *jwriter.Writer, in Sample) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"timestamp\":"
+		out.RawString(prefix[1:])
+		out.Raw((in.Timestamp).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"value\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Value))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Sample) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Sample) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson40eb0d12EncodeGithubComFlutterDizasterYaMetricsInternalView1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Sample) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Sample) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson40eb0d12DecodeGithubComFlutterDizasterYaMetricsInternalView1(l, v)
+}
@@ -0,0 +1,318 @@
+package view
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+)
+
+// Поля сортировки списка метрик.
+const (
+	SortByID          = "id"           // Сортировка по имени метрики
+	SortByKind        = "type"         // Сортировка по типу, затем по имени метрики
+	SortByLastUpdated = "last_updated" // Сортировка по времени последнего обновления метрики
+)
+
+// Ограничения размера страницы списка метрик.
+const (
+	DefaultListLimit = 100  // Размер страницы, если он не указан
+	MaxListLimit     = 1000 // Максимальный размер страницы
+)
+
+// Ошибка возвращается при запросе списка метрик с некорректными параметрами.
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// MetricsPage - страница списка метрик.
+// NextCursor передается в следующий запрос для получения следующей страницы
+// и пуст, если страница последняя.
+//
+// @swagger:model
+//
+//go:generate easyjson list.go
+//easyjson:json
+type MetricsPage struct {
+	// Metrics of the page
+	// Required: true
+	Metrics Metrics `json:"metrics"`
+	// Cursor of the next page. Empty for the last page
+	// Required: false
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListQuery - параметры запроса списка метрик.
+// Все заданные фильтры применяются одновременно.
+// Порядок метрик с одинаковым значением поля сортировки определяется именем и метками метрики.
+type ListQuery struct {
+	Kind   string // Тип метрики. Пустая строка - все типы
+	Prefix string // Префикс имени метрики
+	Glob   string // Шаблон имени метрики: * - любая строка, ? - любой символ, [...] - класс символов
+	Regex  string // Регулярное выражение, которому должно соответствовать имя метрики
+	Labels Labels // Метки, которые должны быть у метрики. Остальные метки метрики не учитываются
+	Sort   string // Поле сортировки. Пустая строка - SortByID
+	Desc   bool   // Сортировка по убыванию
+	Limit  int    // Размер страницы. 0 - DefaultListLimit
+	Cursor string // Курсор страницы, полученный в MetricsPage.NextCursor
+}
+
+// ListCursor - позиция в списке метрик, после которой начинается следующая страница.
+// Хранит значения полей сортировки последней метрики предыдущей страницы.
+type ListCursor struct {
+	Kind        string `json:"k"`
+	ID          string `json:"i"`
+	Labels      Labels `json:"l,omitempty"`
+	LastUpdated int64  `json:"t,omitempty"` // Время в наносекундах Unix
+}
+
+// Normalize проверяет параметры запроса и заполняет значения по умолчанию.
+// Возвращает ошибку, оборачивающую ErrInvalidListQuery, если параметры некорректны.
+func (q *ListQuery) Normalize() error {
+	switch q.Sort {
+	case "":
+		q.Sort = SortByID
+	case SortByID, SortByKind, SortByLastUpdated:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidListQuery, q.Sort)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultListLimit
+	case q.Limit < 0 || q.Limit > MaxListLimit:
+		return fmt.Errorf("%w: limit must be in range 1..%d", ErrInvalidListQuery, MaxListLimit)
+	}
+
+	if q.Regex != "" {
+		if _, err := regexp.Compile(q.Regex); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidListQuery, err)
+		}
+	}
+	if q.Glob != "" {
+		if _, err := regexp.Compile(GlobRegexp(q.Glob)); err != nil {
+			return fmt.Errorf("%w: invalid glob %q", ErrInvalidListQuery, q.Glob)
+		}
+	}
+	if err := q.Labels.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidListQuery, err)
+	}
+
+	_, err := q.ParseCursor()
+	return err
+}
+
+// ParseCursor возвращает позицию, с которой начинается страница.
+// Для первой страницы возвращает nil.
+func (q *ListQuery) ParseCursor() (*ListCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidListQuery)
+	}
+	var cursor ListCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidListQuery)
+	}
+	return &cursor, nil
+}
+
+// NewListCursor возвращает курсор страницы, следующей за метрикой metric.
+func NewListCursor(metric Metric) string {
+	// Маршалинг структуры из строк и чисел не возвращает ошибок
+	data, _ := json.Marshal(cursorOf(metric))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Matcher возвращает функцию проверки, что метрика удовлетворяет фильтрам запроса.
+// Запрос должен быть предварительно проверен методом Normalize.
+func (q *ListQuery) Matcher() func(metric Metric) bool {
+	patterns := make([]*regexp.Regexp, 0, 2)
+	if q.Glob != "" {
+		patterns = append(patterns, regexp.MustCompile(GlobRegexp(q.Glob)))
+	}
+	if q.Regex != "" {
+		patterns = append(patterns, regexp.MustCompile(q.Regex))
+	}
+
+	return func(metric Metric) bool {
+		if q.Kind != "" && metric.MType != q.Kind {
+			return false
+		}
+		if !strings.HasPrefix(metric.ID, q.Prefix) {
+			return false
+		}
+		for _, pattern := range patterns {
+			if !pattern.MatchString(metric.ID) {
+				return false
+			}
+		}
+		for key, value := range q.Labels {
+			if got, ok := metric.Labels[key]; !ok || got != value {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Page возвращает страницу из метрик, отсортированных в порядке запроса и следующих за курсором.
+// Для определения наличия следующей страницы метрик должно быть больше q.Limit,
+// поэтому хранилища запрашивают на одну метрику больше размера страницы.
+func (q *ListQuery) Page(metrics []Metric) MetricsPage {
+	page := MetricsPage{Metrics: metrics}
+	if len(metrics) > q.Limit {
+		page.Metrics = metrics[:q.Limit]
+		page.NextCursor = NewListCursor(page.Metrics[q.Limit-1])
+	}
+	return page
+}
+
+// ListMetrics возвращает страницу списка метрик, выбранную из metrics по параметрам запроса.
+// Используется хранилищами, которые не могут выполнить выборку на своей стороне.
+// Возвращает ошибку, оборачивающую ErrInvalidListQuery, если параметры некорректны.
+func ListMetrics(metrics []Metric, query ListQuery) (MetricsPage, error) {
+	if err := query.Normalize(); err != nil {
+		return MetricsPage{}, err
+	}
+
+	match := query.Matcher()
+	selected := make([]Metric, 0)
+	for _, metric := range metrics {
+		if match(metric) {
+			selected = append(selected, metric)
+		}
+	}
+	return query.SortPage(selected)
+}
+
+// SortPage возвращает страницу списка из метрик, уже отобранных функцией, возвращаемой методом Matcher.
+// Используется хранилищами, которые отбирают метрики при чтении, чтобы не проверять фильтры повторно.
+// Запрос должен быть предварительно проверен методом Normalize.
+// Возвращает ошибку, оборачивающую ErrInvalidListQuery, если курсор некорректен.
+func (q *ListQuery) SortPage(metrics []Metric) (MetricsPage, error) {
+	cursor, err := q.ParseCursor()
+	if err != nil {
+		return MetricsPage{}, err
+	}
+
+	selected := make([]Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if cursor != nil && compareCursor(cursorOf(metric), *cursor, q.Sort, q.Desc) <= 0 {
+			continue
+		}
+		selected = append(selected, metric)
+	}
+
+	slices.SortFunc(selected, func(a, b Metric) int {
+		return compareCursor(cursorOf(a), cursorOf(b), q.Sort, q.Desc)
+	})
+
+	return q.Page(selected), nil
+}
+
+// Хелпер функция для преобразования запроса списка метрик из пакета proto в запрос пакета view.
+func UnmarshalGRPCListQuery(req *pb.ListMetricsRequest) ListQuery {
+	query := ListQuery{
+		Kind:   req.GetKind(),
+		Prefix: req.GetPrefix(),
+		Glob:   req.GetGlob(),
+		Regex:  req.GetRegex(),
+		Sort:   req.GetSort(),
+		Desc:   req.GetDesc(),
+		Limit:  int(req.GetLimit()),
+		Cursor: req.GetCursor(),
+	}
+	if len(req.GetLabels()) > 0 {
+		query.Labels = req.GetLabels()
+	}
+	return query
+}
+
+// Хелпер функция для преобразования фильтра потока обновлений метрик из пакета proto в запрос пакета view.
+// Используются только параметры фильтрации запроса.
+func UnmarshalGRPCWatchQuery(req *pb.WatchMetricsRequest) ListQuery {
+	query := ListQuery{
+		Kind:   req.GetKind(),
+		Prefix: req.GetPrefix(),
+		Glob:   req.GetGlob(),
+		Regex:  req.GetRegex(),
+	}
+	if len(req.GetLabels()) > 0 {
+		query.Labels = req.GetLabels()
+	}
+	return query
+}
+
+// GlobRegexp преобразует шаблон имени в регулярное выражение, соответствующее всему имени.
+// Символ * соответствует любой строке, ? - любому символу,
+// [...] - классу символов, [^...] - отрицанию класса символов.
+func GlobRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for glob != "" {
+		switch glob[0] {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteByte('.')
+		case '[':
+			// Класс символов переносится без изменений, кроме экранирования обратной косой черты
+			end := strings.IndexByte(glob, ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				break
+			}
+			sb.WriteString(strings.ReplaceAll(glob[:end], `\`, `\\`))
+			glob = glob[end:]
+			sb.WriteByte(']')
+		default:
+			// Остальные символы экранируются целиком, включая многобайтовые
+			_, size := utf8.DecodeRuneInString(glob)
+			sb.WriteString(regexp.QuoteMeta(glob[:size]))
+			glob = glob[size-1:]
+		}
+		glob = glob[1:]
+	}
+	sb.WriteByte('$')
+	return sb.String()
+}
+
+// Хелпер функция для получения значений полей сортировки метрики.
+func cursorOf(metric Metric) ListCursor {
+	cursor := ListCursor{Kind: metric.MType, ID: metric.ID, Labels: metric.Labels}
+	if metric.LastUpdated != nil {
+		cursor.LastUpdated = metric.LastUpdated.UnixNano()
+	}
+	return cursor
+}
+
+// Хелпер функция для сравнения позиций метрик в списке, отсортированном по полю sort.
+// Метрики с одинаковым значением поля сортировки упорядочиваются по имени и меткам.
+func compareCursor(a, b ListCursor, sort string, desc bool) int {
+	var c int
+	switch sort {
+	case SortByKind:
+		c = cmp.Compare(a.Kind, b.Kind)
+	case SortByLastUpdated:
+		c = cmp.Compare(a.LastUpdated, b.LastUpdated)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.Labels.String(), b.Labels.String())
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package view
+
+import (
+	json "encoding/json"
+
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonEf7cfe30DecodeGithubComFlutterDizasterYaMetricsInternalView(in *jlexer.Lexer, out *MetricsPage) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "metrics":
+			(out.Metrics).UnmarshalEasyJSON(in)
+		case "next_cursor":
+			out.NextCursor = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonEf7cfe30EncodeGithubComFlutterDizasterYaMetricsInternalView(out *jwriter.Writer, in MetricsPage) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"metrics\":"
+		out.RawString(prefix[1:])
+		(in.Metrics).MarshalEasyJSON(out)
+	}
+	if in.NextCursor != "" {
+		const prefix string = ",\"next_cursor\":"
+		out.RawString(prefix)
+		out.String(string(in.NextCursor))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v MetricsPage) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonEf7cfe30EncodeGithubComFlutterDizasterYaMetricsInternalView(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v MetricsPage) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonEf7cfe30EncodeGithubComFlutterDizasterYaMetricsInternalView(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *MetricsPage) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonEf7cfe30DecodeGithubComFlutterDizasterYaMetricsInternalView(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *MetricsPage) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonEf7cfe30DecodeGithubComFlutterDizasterYaMetricsInternalView(l, v)
+}
@@ -2,23 +2,118 @@ package view
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	pb "github.com/FlutterDizaster/ya-metrics/proto"
 )
 
 const (
-	KindGauge   = "gauge"   // Тип метрики gauge, значение метрики - float64
-	KindCounter = "counter" // Тип метрики counter, значение метрики - int64
+	KindGauge     = "gauge"     // Тип метрики gauge, значение метрики - float64
+	KindCounter   = "counter"   // Тип метрики counter, значение метрики - int64
+	KindHistogram = "histogram" // Тип метрики histogram, значение метрики - Histogram
 )
 
+var (
+	// Ошибка возвращается, если имя метки не соответствует формату [a-zA-Z_][a-zA-Z0-9_]*.
+	ErrInvalidLabelName = errors.New("invalid label name")
+	// Ошибка возвращается при попытке объединить гистограммы с разными границами корзин.
+	ErrHistogramBounds = errors.New("histogram bounds mismatch")
+	// Ошибка возвращается если гистограмма имеет некорректную структуру.
+	ErrInvalidHistogram = errors.New("invalid histogram")
+)
+
+// Границы корзин гистограммы по умолчанию.
+// Используются при создании гистограммы через NewMetric.
+//
+//nolint:gochecknoglobals // default histogram layout
+var DefaultBounds = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
 // Alias к срезу метрик.
 //
 //easyjson:json
 type Metrics []Metric
 
+// Labels - набор меток метрики.
+// Метки являются частью идентичности метрики наравне с её именем.
+type Labels map[string]string
+
+// Символы, экранируемые в имени метрики и именах меток при построении ключа метрики.
+//
+//nolint:gochecknoglobals // key escaper
+var keyEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`,`, `\,`,
+	`=`, `\=`,
+	`"`, `\"`,
+)
+
+// ValidLabelName проверяет, что имя метки соответствует формату [a-zA-Z_][a-zA-Z0-9_]*.
+func ValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Validate проверяет имена меток.
+// Возвращает ошибку, оборачивающую ErrInvalidLabelName, для первого некорректного имени.
+func (l Labels) Validate() error {
+	for name := range l {
+		if !ValidLabelName(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidLabelName, name)
+		}
+	}
+	return nil
+}
+
+// String возвращает каноническое представление меток в виде {k1="v1",k2="v2"}.
+// Метки сортируются по ключу. В именах меток экранируются символы \ { } , = ",
+// значения заключаются в кавычки с экранированием, поэтому представление однозначно.
+// Для пустого набора меток возвращает пустую строку.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(keyEscaper.Replace(k))
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[k]))
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
 // Metric - структура описывающая метрику.
-// Может иметь тип gauge или counter.
+// Может иметь тип gauge, counter или histogram.
+// Идентичность метрики определяется именем и набором меток.
 //
 // @swagger:model
 //
@@ -28,22 +123,123 @@ type Metric struct {
 	// Required: true
 	ID string `json:"id"`
 	// Metric Type
-	// Possible values: gauge, counter
+	// Possible values: gauge, counter, histogram
 	// Required: true
 	MType string `json:"type"`
+	// Metric labels
+	// Required: false
+	Labels Labels `json:"labels,omitempty"`
 	// Counter value
 	// Required: false
 	Delta *int64 `json:"delta,omitempty"`
 	// Gauge value
 	// Required: false
 	Value *float64 `json:"value,omitempty"`
+	// Histogram value
+	// Required: false
+	Histogram *Histogram `json:"histogram,omitempty"`
+	// Time of the last metric update. Set by the server
+	// Required: false
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+}
+
+// Histogram - структура описывающая распределение значений метрики типа histogram.
+// Bounds хранит верхние границы корзин в порядке возрастания.
+// Buckets хранит кол-во наблюдений в каждой корзине (не накопительно).
+// Последняя корзина не имеет верхней границы (+Inf), поэтому len(Buckets) == len(Bounds)+1.
+//
+// @swagger:model
+type Histogram struct {
+	// Upper bounds of buckets
+	// Required: true
+	Bounds []float64 `json:"bounds"`
+	// Observations count per bucket
+	// Required: true
+	Buckets []uint64 `json:"buckets"`
+	// Total observations count
+	// Required: true
+	Count uint64 `json:"count"`
+	// Sum of observed values
+	// Required: true
+	Sum float64 `json:"sum"`
+}
+
+// Функция для создания пустой гистограммы с заданными границами корзин.
+// Границы должны быть указаны в порядке возрастания, иначе возвращает ошибку.
+func NewHistogram(bounds []float64) (*Histogram, error) {
+	h := &Histogram{
+		Bounds:  slices.Clone(bounds),
+		Buckets: make([]uint64, len(bounds)+1),
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Observe добавляет наблюдение value в гистограмму.
+func (h *Histogram) Observe(value float64) {
+	idx, _ := slices.BinarySearch(h.Bounds, value)
+	h.Buckets[idx]++
+	h.Count++
+	h.Sum += value
+}
+
+// Validate проверяет структуру гистограммы.
+// Возвращает ErrInvalidHistogram если границы не упорядочены,
+// кол-во корзин не соответствует кол-ву границ или сумма корзин не равна Count.
+func (h *Histogram) Validate() error {
+	if len(h.Buckets) != len(h.Bounds)+1 {
+		return ErrInvalidHistogram
+	}
+	for i := 1; i < len(h.Bounds); i++ {
+		if h.Bounds[i-1] >= h.Bounds[i] {
+			return ErrInvalidHistogram
+		}
+	}
+	var count uint64
+	for _, bucket := range h.Buckets {
+		count += bucket
+	}
+	if count != h.Count {
+		return ErrInvalidHistogram
+	}
+	return nil
+}
+
+// Merge возвращает новую гистограмму, являющуюся суммой h и other.
+// Исходные гистограммы не изменяются.
+// Возвращает ErrInvalidHistogram если одна из гистограмм отсутствует
+// или кол-во её корзин не соответствует кол-ву границ.
+// Возвращает ErrHistogramBounds если границы корзин гистограмм различаются.
+func (h *Histogram) Merge(other *Histogram) (*Histogram, error) {
+	if h == nil || other == nil {
+		return nil, ErrInvalidHistogram
+	}
+	if len(h.Buckets) != len(h.Bounds)+1 || len(other.Buckets) != len(other.Bounds)+1 {
+		return nil, ErrInvalidHistogram
+	}
+	if !slices.Equal(h.Bounds, other.Bounds) {
+		return nil, ErrHistogramBounds
+	}
+	result := &Histogram{
+		Bounds:  slices.Clone(h.Bounds),
+		Buckets: make([]uint64, len(h.Buckets)),
+		Count:   h.Count + other.Count,
+		Sum:     h.Sum + other.Sum,
+	}
+	for i := range result.Buckets {
+		result.Buckets[i] = h.Buckets[i] + other.Buckets[i]
+	}
+	return result, nil
 }
 
 // Функция для создания метрики.
-// kind - тип метрики KindGauge или KindCounter.
+// kind - тип метрики KindGauge, KindCounter или KindHistogram.
 // name - имя метрики. Может быть любым.
 // value - значение метрики. Должно быть текстовой репрезентацией целочисленного типа для метрик KindCounter или
-// дробной для метрик KindGauge.
+// дробной для метрик KindGauge и KindHistogram.
+// Для метрик KindHistogram создается гистограмма с границами DefaultBounds и одним наблюдением value.
 // При передаче некорректных значений возвращает ошибку.
 func NewMetric(kind string, name string, value string) (*Metric, error) {
 	metric := &Metric{
@@ -63,12 +259,36 @@ func NewMetric(kind string, name string, value string) (*Metric, error) {
 			return nil, err
 		}
 		metric.Delta = &delta
+	case KindHistogram:
+		fvalue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		histogram, err := NewHistogram(DefaultBounds)
+		if err != nil {
+			return nil, err
+		}
+		histogram.Observe(fvalue)
+		metric.Histogram = histogram
 	default:
 		return nil, errors.New("wrong metric type")
 	}
 	return metric, nil
 }
 
+// Key возвращает ключ идентичности метрики, состоящий из имени и канонического представления меток.
+func (m *Metric) Key() string {
+	return MetricKey(m.ID, m.Labels)
+}
+
+// MetricKey возвращает ключ идентичности метрики с именем name и метками labels.
+// Символы \ { } , = " в имени экранируются, поэтому первая неэкранированная { начинает метки,
+// и ключ однозначно разбирается на имя и метки.
+// Для метрик без меток и специальных символов в имени ключ совпадает с именем.
+func MetricKey(name string, labels Labels) string {
+	return keyEscaper.Replace(name) + labels.String()
+}
+
 // StringValue возвращает строковое представление значения метрики.
 func (m *Metric) StringValue() string {
 	switch m.MType {
@@ -76,6 +296,12 @@ func (m *Metric) StringValue() string {
 		return strconv.FormatFloat(*m.Value, 'f', -1, 64)
 	case "counter":
 		return strconv.FormatInt(*m.Delta, 10)
+	case "histogram":
+		return fmt.Sprintf(
+			"count=%d sum=%s",
+			m.Histogram.Count,
+			strconv.FormatFloat(m.Histogram.Sum, 'f', -1, 64),
+		)
 	}
 	return ""
 }
@@ -88,6 +314,14 @@ func UnmarshalGRPCMetrics(metrics []*pb.Metric) []Metric {
 		metric := Metric{}
 		metric.ID = metrics[i].GetId()
 		metric.MType = metrics[i].GetKind()
+		if len(metrics[i].GetLabels()) > 0 {
+			metric.Labels = metrics[i].GetLabels()
+		}
+
+		if ts := metrics[i].GetLastUpdated(); ts != 0 {
+			lastUpdated := time.Unix(0, ts)
+			metric.LastUpdated = &lastUpdated
+		}
 
 		switch metrics[i].GetKind() {
 		case KindGauge:
@@ -96,6 +330,8 @@ func UnmarshalGRPCMetrics(metrics []*pb.Metric) []Metric {
 		case KindCounter:
 			delta := metrics[i].GetDelta()
 			metric.Delta = &delta
+		case KindHistogram:
+			metric.Histogram = unmarshalGRPCHistogram(metrics[i].GetHistogram())
 		}
 
 		resutl = append(resutl, metric)
@@ -109,18 +345,49 @@ func MarshalGRPCMetrics(metrics []Metric) []*pb.Metric {
 	resutl := make([]*pb.Metric, 0, len(metrics))
 	for i := range metrics {
 		metric := &pb.Metric{
-			Id:   metrics[i].ID,
-			Kind: metrics[i].MType,
+			Id:     metrics[i].ID,
+			Kind:   metrics[i].MType,
+			Labels: metrics[i].Labels,
 		}
-		switch metrics[i].MType {
-		case KindGauge:
-			value := metrics[i].Value
-			metric.Value = *value
-		case KindCounter:
-			delta := metrics[i].Delta
-			metric.Delta = *delta
+		if metrics[i].LastUpdated != nil {
+			metric.LastUpdated = metrics[i].LastUpdated.UnixNano()
+		}
+		// Метрики без значения, например при удалении, передаются только с идентичностью
+		switch {
+		case metrics[i].MType == KindGauge && metrics[i].Value != nil:
+			metric.Value = *metrics[i].Value
+		case metrics[i].MType == KindCounter && metrics[i].Delta != nil:
+			metric.Delta = *metrics[i].Delta
+		case metrics[i].MType == KindHistogram:
+			metric.Histogram = marshalGRPCHistogram(metrics[i].Histogram)
 		}
 		resutl = append(resutl, metric)
 	}
 	return resutl
 }
+
+// Хелпер функция для маршаллинга гистограммы из пакета proto в гистограмму пакета view.
+func unmarshalGRPCHistogram(histogram *pb.Histogram) *Histogram {
+	if histogram == nil {
+		return nil
+	}
+	return &Histogram{
+		Bounds:  histogram.GetBounds(),
+		Buckets: histogram.GetBuckets(),
+		Count:   histogram.GetCount(),
+		Sum:     histogram.GetSum(),
+	}
+}
+
+// Хелпер функция для маршаллинга гистограммы из пакета view в гистограмму пакета proto.
+func marshalGRPCHistogram(histogram *Histogram) *pb.Histogram {
+	if histogram == nil {
+		return nil
+	}
+	return &pb.Histogram{
+		Bounds:  histogram.Bounds,
+		Buckets: histogram.Buckets,
+		Count:   histogram.Count,
+		Sum:     histogram.Sum,
+	}
+}
@@ -4,6 +4,7 @@ package view
 
 import (
 	json "encoding/json"
+	time "time"
 
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
@@ -27,7 +28,7 @@ func easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView(in *jle
 		in.Delim('[')
 		if *out == nil {
 			if !in.IsDelim(']') {
-				*out = make(Metrics, 0, 1)
+				*out = make(Metrics, 0, 0)
 			} else {
 				*out = Metrics{}
 			}
@@ -107,6 +108,26 @@ func easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView1(in *jl
 			out.ID = string(in.String())
 		case "type":
 			out.MType = string(in.String())
+		case "labels":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Labels = make(Labels)
+				} else {
+					out.Labels = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v4 string
+					v4 = string(in.String())
+					(out.Labels)[key] = v4
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
 		case "delta":
 			if in.IsNull() {
 				in.Skip()
@@ -127,6 +148,28 @@ func easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView1(in *jl
 				}
 				*out.Value = float64(in.Float64())
 			}
+		case "histogram":
+			if in.IsNull() {
+				in.Skip()
+				out.Histogram = nil
+			} else {
+				if out.Histogram == nil {
+					out.Histogram = new(Histogram)
+				}
+				(*out.Histogram).UnmarshalEasyJSON(in)
+			}
+		case "last_updated":
+			if in.IsNull() {
+				in.Skip()
+				out.LastUpdated = nil
+			} else {
+				if out.LastUpdated == nil {
+					out.LastUpdated = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.LastUpdated).UnmarshalJSON(data))
+				}
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -151,6 +194,25 @@ func easyjson9478868cEncodeGithubComFlutterDizasterYaMetricsInternalView1(out *j
 		out.RawString(prefix)
 		out.String(string(in.MType))
 	}
+	if len(in.Labels) != 0 {
+		const prefix string = ",\"labels\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v5First := true
+			for v5Name, v5Value := range in.Labels {
+				if v5First {
+					v5First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v5Name))
+				out.RawByte(':')
+				out.String(string(v5Value))
+			}
+			out.RawByte('}')
+		}
+	}
 	if in.Delta != nil {
 		const prefix string = ",\"delta\":"
 		out.RawString(prefix)
@@ -161,6 +223,16 @@ func easyjson9478868cEncodeGithubComFlutterDizasterYaMetricsInternalView1(out *j
 		out.RawString(prefix)
 		out.Float64(float64(*in.Value))
 	}
+	if in.Histogram != nil {
+		const prefix string = ",\"histogram\":"
+		out.RawString(prefix)
+		(*in.Histogram).MarshalEasyJSON(out)
+	}
+	if in.LastUpdated != nil {
+		const prefix string = ",\"last_updated\":"
+		out.RawString(prefix)
+		out.Raw((*in.LastUpdated).MarshalJSON())
+	}
 	out.RawByte('}')
 }
 
@@ -187,3 +259,154 @@ func (v *Metric) UnmarshalJSON(data []byte) error {
 func (v *Metric) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView1(l, v)
 }
+func easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView2(in *jlexer.Lexer, out *Histogram) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "bounds":
+			if in.IsNull() {
+				in.Skip()
+				out.Bounds = nil
+			} else {
+				in.Delim('[')
+				if out.Bounds == nil {
+					if !in.IsDelim(']') {
+						out.Bounds = make([]float64, 0, 8)
+					} else {
+						out.Bounds = []float64{}
+					}
+				} else {
+					out.Bounds = (out.Bounds)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v6 float64
+					v6 = float64(in.Float64())
+					out.Bounds = append(out.Bounds, v6)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "buckets":
+			if in.IsNull() {
+				in.Skip()
+				out.Buckets = nil
+			} else {
+				in.Delim('[')
+				if out.Buckets == nil {
+					if !in.IsDelim(']') {
+						out.Buckets = make([]uint64, 0, 8)
+					} else {
+						out.Buckets = []uint64{}
+					}
+				} else {
+					out.Buckets = (out.Buckets)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v7 uint64
+					v7 = uint64(in.Uint64())
+					out.Buckets = append(out.Buckets, v7)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "count":
+			out.Count = uint64(in.Uint64())
+		case "sum":
+			out.Sum = float64(in.Float64())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson9478868cEncodeGithubComFlutterDizasterYaMetricsInternalView2(out *jwriter.Writer, in Histogram) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"bounds\":"
+		out.RawString(prefix[1:])
+		if in.Bounds == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v8, v9 := range in.Bounds {
+				if v8 > 0 {
+					out.RawByte(',')
+				}
+				out.Float64(float64(v9))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"buckets\":"
+		out.RawString(prefix)
+		if in.Buckets == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v10, v11 := range in.Buckets {
+				if v10 > 0 {
+					out.RawByte(',')
+				}
+				out.Uint64(uint64(v11))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"count\":"
+		out.RawString(prefix)
+		out.Uint64(uint64(in.Count))
+	}
+	{
+		const prefix string = ",\"sum\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Sum))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Histogram) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson9478868cEncodeGithubComFlutterDizasterYaMetricsInternalView2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Histogram) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson9478868cEncodeGithubComFlutterDizasterYaMetricsInternalView2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Histogram) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Histogram) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson9478868cDecodeGithubComFlutterDizasterYaMetricsInternalView2(l, v)
+}
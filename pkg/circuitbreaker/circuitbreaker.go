@@ -0,0 +1,138 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen - ошибка, возвращаемая при вызове через разомкнутый CircuitBreaker.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State - состояние CircuitBreaker.
+type State int
+
+const (
+	// StateClosed - вызовы выполняются, ошибки подсчитываются.
+	StateClosed State = iota
+	// StateOpen - вызовы отклоняются с ошибкой ErrOpen.
+	StateOpen
+	// StateHalfOpen - выполняется пробный вызов, остальные вызовы отклоняются.
+	StateHalfOpen
+)
+
+// String - текстовое представление состояния.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Settings - настройки CircuitBreaker.
+type Settings struct {
+	// Кол-во ошибок подряд, после которого CircuitBreaker размыкается.
+	// Значение меньше 1 приравнивается к 1.
+	FailureThreshold int
+	// Время, через которое разомкнутый CircuitBreaker пропускает пробный вызов.
+	OpenTimeout time.Duration
+	// Функция, определяющая является ли ошибка сбоем.
+	// Ошибки, не являющиеся сбоем, не влияют на состояние CircuitBreaker.
+	// Если не задана, сбоем считается любая ошибка.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreaker - автомат, прекращающий вызовы к сбоящему ресурсу.
+// После FailureThreshold сбоев подряд CircuitBreaker размыкается и отклоняет вызовы.
+// Через OpenTimeout пропускается один пробный вызов: при успехе CircuitBreaker замыкается,
+// при сбое снова размыкается.
+// Должен создаваться через New().
+type CircuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	isFailure   func(err error) bool
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// New - создание нового объекта CircuitBreaker в замкнутом состоянии.
+func New(settings Settings) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		threshold:   max(settings.FailureThreshold, 1),
+		openTimeout: settings.OpenTimeout,
+		isFailure:   settings.IsFailure,
+		now:         time.Now,
+	}
+	if cb.isFailure == nil {
+		cb.isFailure = func(err error) bool { return err != nil }
+	}
+	return cb
+}
+
+// Execute - выполнение fn через CircuitBreaker.
+// Если CircuitBreaker разомкнут, fn не выполняется и возвращается ErrOpen.
+// Иначе возвращает ошибку, возвращенную fn.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	cb.report(err)
+	return err
+}
+
+// State - текущее состояние CircuitBreaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// allow проверяет возможность выполнения вызова.
+// Переводит CircuitBreaker в полуоткрытое состояние по истечении OpenTimeout.
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case StateOpen:
+		if cb.now().Sub(cb.openedAt) < cb.openTimeout {
+			return ErrOpen
+		}
+		cb.state = StateHalfOpen
+		return nil
+	case StateHalfOpen:
+		// Пробный вызов уже выполняется
+		return ErrOpen
+	default:
+		return nil
+	}
+}
+
+// report учитывает результат вызова.
+func (cb *CircuitBreaker) report(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err == nil || !cb.isFailure(err) {
+		cb.state = StateClosed
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.state == StateHalfOpen || cb.failures >= cb.threshold {
+		cb.state = StateOpen
+		cb.openedAt = cb.now()
+	}
+}
@@ -0,0 +1,77 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errFailure = errors.New("failure")
+	errIgnored = errors.New("ignored")
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	current := time.Unix(0, 0)
+	cb := New(Settings{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Second,
+		IsFailure:        func(err error) bool { return errors.Is(err, errFailure) },
+	})
+	cb.now = func() time.Time { return current }
+
+	fail := func() error { return errFailure }
+	ignored := func() error { return errIgnored }
+	succeed := func() error { return nil }
+
+	// Ошибки, не являющиеся сбоем, не размыкают CircuitBreaker
+	assert.ErrorIs(t, cb.Execute(ignored), errIgnored)
+	assert.ErrorIs(t, cb.Execute(ignored), errIgnored)
+	assert.Equal(t, StateClosed, cb.State())
+
+	// Размыкание после FailureThreshold сбоев подряд
+	assert.ErrorIs(t, cb.Execute(fail), errFailure)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.ErrorIs(t, cb.Execute(fail), errFailure)
+	assert.Equal(t, StateOpen, cb.State())
+
+	// Вызовы отклоняются до истечения OpenTimeout
+	called := false
+	err := cb.Execute(func() error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called)
+
+	// Неудачный пробный вызов снова размыкает CircuitBreaker
+	current = current.Add(time.Second)
+	assert.ErrorIs(t, cb.Execute(fail), errFailure)
+	assert.Equal(t, StateOpen, cb.State())
+	assert.ErrorIs(t, cb.Execute(succeed), ErrOpen)
+
+	// Успешный пробный вызов замыкает CircuitBreaker
+	current = current.Add(time.Second)
+	assert.NoError(t, cb.Execute(succeed))
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreaker_HalfOpenSingleTrial(t *testing.T) {
+	current := time.Unix(0, 0)
+	cb := New(Settings{FailureThreshold: 1, OpenTimeout: time.Second})
+	cb.now = func() time.Time { return current }
+
+	assert.ErrorIs(t, cb.Execute(func() error { return errFailure }), errFailure)
+	current = current.Add(time.Second)
+
+	// Во время пробного вызова остальные вызовы отклоняются
+	err := cb.Execute(func() error {
+		assert.Equal(t, StateHalfOpen, cb.State())
+		assert.ErrorIs(t, cb.Execute(func() error { return nil }), ErrOpen)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, StateClosed, cb.State())
+}
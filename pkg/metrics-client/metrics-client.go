@@ -0,0 +1,129 @@
+// Пакет metricsclient содержит клиент gRPC сервиса метрик.
+// Клиент принимает и возвращает сообщения пакета proto, поэтому не зависит от внутренних пакетов сервера.
+// Ошибки сервера возвращаются как ошибки gRPC статуса, код которых можно получить через status.Code.
+package metricsclient
+
+import (
+	"context"
+	"slices"
+
+	"github.com/FlutterDizaster/ya-metrics/pkg/validation"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Типы метрик.
+const (
+	KindGauge     = "gauge"
+	KindCounter   = "counter"
+	KindHistogram = "histogram"
+)
+
+// Settings - настройки клиента.
+type Settings struct {
+	// Адрес gRPC сервера метрик.
+	Addr string
+	// Дополнительные параметры подключения.
+	// Если не заданы, используется подключение без шифрования.
+	DialOptions []grpc.DialOption
+	// Ключ HMAC запросов. Должен совпадать с ключом сервера.
+	// Пустая строка - запросы не подписываются.
+	HashKey string
+}
+
+// Client - клиент gRPC сервиса метрик.
+// Должен создаваться через New() и закрываться методом Close().
+type Client struct {
+	conn   *grpc.ClientConn
+	client pb.MetricsServiceClient
+}
+
+// New - создание клиента сервиса метрик.
+// Подключение к серверу устанавливается при первом вызове.
+func New(settings Settings) (*Client, error) {
+	opts := slices.Clip(settings.DialOptions)
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	if settings.HashKey != "" {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(validation.UnaryClientInterceptor([]byte(settings.HashKey))),
+			grpc.WithChainStreamInterceptor(validation.StreamClientInterceptor([]byte(settings.HashKey))),
+		)
+	}
+
+	conn, err := grpc.NewClient(settings.Addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn:   conn,
+		client: pb.NewMetricsServiceClient(conn),
+	}, nil
+}
+
+// Close - закрытие подключения к серверу.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// AddMetrics - добавление метрик.
+// Возвращает обновленные значения метрик.
+func (c *Client) AddMetrics(ctx context.Context, metrics ...*pb.Metric) ([]*pb.Metric, error) {
+	resp, err := c.client.AddMetrics(ctx, &pb.AddMetricsRequest{
+		Metrics: metrics,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetMetrics(), nil
+}
+
+// GetMetric - получение метрики по типу, имени и набору меток.
+func (c *Client) GetMetric(ctx context.Context, kind string, name string, labels map[string]string) (*pb.Metric, error) {
+	resp, err := c.client.GetMetric(ctx, &pb.GetMetricRequest{
+		Id:     name,
+		Kind:   kind,
+		Labels: labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetMetric(), nil
+}
+
+// ListMetrics - получение страницы списка метрик.
+// Для получения следующей страницы NextCursor ответа передается в поле Cursor запроса.
+func (c *Client) ListMetrics(ctx context.Context, query *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	return c.client.ListMetrics(ctx, query)
+}
+
+// DeleteMetrics - удаление метрик вместе с их историей.
+// Если хотя бы одна метрика не найдена, не удаляется ни одна.
+func (c *Client) DeleteMetrics(ctx context.Context, metrics ...*pb.Metric) error {
+	_, err := c.client.DeleteMetrics(ctx, &pb.DeleteMetricsRequest{
+		Metrics: metrics,
+	})
+	return err
+}
+
+// ResetCounter - сброс значения счетчика в ноль.
+// Возвращает обновленный счетчик.
+func (c *Client) ResetCounter(ctx context.Context, name string, labels map[string]string) (*pb.Metric, error) {
+	resp, err := c.client.ResetCounter(ctx, &pb.ResetCounterRequest{
+		Id:     name,
+		Labels: labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetMetric(), nil
+}
+
+// Ping - проверка доступности сервера и его хранилища.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.client.Ping(ctx, &pb.PingRequest{})
+	return err
+}
@@ -0,0 +1,135 @@
+package metricsclient
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/FlutterDizaster/ya-metrics/internal/server/repository/memory"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc"
+	"github.com/FlutterDizaster/ya-metrics/internal/server/rpc/interceptors"
+	pb "github.com/FlutterDizaster/ya-metrics/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Хелпер для создания клиента, подключенного к серверу на соединении в памяти.
+// Если serverKey не пуст, сервер проверяет HMAC запросов с этим ключом.
+func newTestClient(t *testing.T, serverKey, clientKey string) *Client {
+	t.Helper()
+
+	storage, err := memory.New(&memory.Settings{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+	})
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	var opts []grpc.ServerOption
+	if serverKey != "" {
+		hash := &interceptors.HashInterceptor{Key: []byte(serverKey)}
+		opts = append(hash.ServerOptions(),
+			grpc.ChainUnaryInterceptor(hash.Unary()),
+			grpc.ChainStreamInterceptor(hash.Stream()),
+		)
+	}
+	srv := grpc.NewServer(opts...)
+	pb.RegisterMetricsServiceServer(srv, rpc.New(rpc.Settings{Storage: storage}))
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	client, err := New(Settings{
+		Addr: "passthrough:///bufconn",
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		},
+		HashKey: clientKey,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	client := newTestClient(t, "", "")
+	ctx := context.Background()
+
+	require.NoError(t, client.Ping(ctx))
+
+	result, err := client.AddMetrics(
+		ctx,
+		&pb.Metric{Id: "cpu", Kind: KindGauge, Labels: map[string]string{"host": "a"}, Value: 1.5},
+		&pb.Metric{Id: "requests", Kind: KindCounter, Delta: 3},
+	)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.NotZero(t, result[0].GetLastUpdated())
+
+	metric, err := client.GetMetric(ctx, KindGauge, "cpu", map[string]string{"host": "a"})
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, metric.GetValue(), 0)
+	assert.Equal(t, map[string]string{"host": "a"}, metric.GetLabels())
+
+	_, err = client.GetMetric(ctx, KindGauge, "cpu", nil)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	page, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.GetMetrics(), 1)
+	assert.Equal(t, "cpu", page.GetMetrics()[0].GetId())
+
+	page, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{Limit: 1, Cursor: page.GetNextCursor()})
+	require.NoError(t, err)
+	require.Len(t, page.GetMetrics(), 1)
+	assert.Equal(t, "requests", page.GetMetrics()[0].GetId())
+	assert.Empty(t, page.GetNextCursor())
+
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{Regex: "("})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	metric, err = client.ResetCounter(ctx, "requests", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), metric.GetDelta())
+
+	require.NoError(t, client.DeleteMetrics(ctx, &pb.Metric{Id: "requests", Kind: KindCounter}))
+	_, err = client.GetMetric(ctx, KindCounter, "requests", nil)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClient_HashKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientKey string
+		wantCode  codes.Code
+	}{
+		{name: "valid key", clientKey: "secret", wantCode: codes.OK},
+		{name: "wrong key", clientKey: "wrong", wantCode: codes.Unauthenticated},
+		{name: "unsigned", clientKey: "", wantCode: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, "secret", tt.clientKey)
+
+			_, err := client.AddMetrics(context.Background(), &pb.Metric{
+				Id:     "cpu",
+				Kind:   KindGauge,
+				Value:  1.5,
+				Labels: map[string]string{"host": "web1", "dc": "eu"},
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package validation
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// HashMetadataKey - ключ метаданных gRPC, в котором передается HMAC унарного запроса.
+const HashMetadataKey = "hashsha256"
+
+// HashField - имя поля сообщения потока gRPC, в котором передается HMAC сообщения.
+// Метаданные передаются один раз при открытии потока, поэтому каждое сообщение потока
+// содержит собственный хеш.
+const HashField = "hash"
+
+// SignedPayload возвращает байты сериализованного сообщения потока data, по которым считается его HMAC.
+// HMAC считается по байтам, переданным по сети, а не по повторной сериализации сообщения,
+// поэтому не зависит от реализации protobuf клиента и версии схемы.
+// Клиент сериализует сообщение без поля HashField, считает HMAC полученных байт
+// и дописывает поле с хешем в конец сообщения, поэтому все вхождения поля удаляются из data.
+// HMAC унарного запроса считается по всем байтам запроса.
+// Сообщение msg используется только для определения номера поля.
+func SignedPayload(msg proto.Message, data []byte) ([]byte, error) {
+	fd := hashField(msg.ProtoReflect())
+	if fd == nil {
+		return data, nil
+	}
+
+	payload := make([]byte, 0, len(data))
+	for rest := data; len(rest) > 0; {
+		num, typ, n := protowire.ConsumeTag(rest)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, rest[n:])
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		if num != fd.Number() {
+			payload = append(payload, rest[:n+m]...)
+		}
+		rest = rest[n+m:]
+	}
+	return payload, nil
+}
+
+// MessageHMAC возвращает HMAC, переданный в поле HashField сообщения потока.
+// Второе значение равно false, если у сообщения нет такого поля.
+func MessageHMAC(msg proto.Message) (string, bool) {
+	m := msg.ProtoReflect()
+	fd := hashField(m)
+	if fd == nil {
+		return "", false
+	}
+	return m.Get(fd).String(), true
+}
+
+// UnaryClientInterceptor возвращает клиентский интерцептор,
+// передающий HMAC каждого унарного запроса в метаданных HashMetadataKey.
+// Запрос сериализуется интерцептором, и по сети передаются те же байты, по которым посчитан HMAC.
+func UnaryClientInterceptor(key []byte) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if msg, ok := req.(proto.Message); ok {
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			hash := CalculateHMACSHA256(data, key)
+			ctx = metadata.AppendToOutgoingContext(ctx, HashMetadataKey, hex.EncodeToString(hash))
+			opts = append(opts, grpc.ForceCodec(&preparedCodec{msg: msg, data: data}))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor возвращает клиентский интерцептор,
+// передающий HMAC каждого отправляемого сообщения потока в его поле HashField.
+// Отправляемые сообщения не изменяются: поле с хешем дописывается к сериализованному сообщению.
+func StreamClientInterceptor(key []byte) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		opts = append(opts, grpc.ForceCodec(&signingCodec{key: key}))
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// preparedCodec - кодек унарного запроса, сериализованного до вызова.
+// Для запроса msg возвращает уже сериализованные байты data.
+type preparedCodec struct {
+	msg  proto.Message
+	data []byte
+}
+
+// Marshal реализация encoding.Codec.
+func (c *preparedCodec) Marshal(v any) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok && msg == c.msg {
+		return c.data, nil
+	}
+	return marshal(v)
+}
+
+// Unmarshal реализация encoding.Codec.
+func (c *preparedCodec) Unmarshal(data []byte, v any) error {
+	return unmarshal(data, v)
+}
+
+// Name реализация encoding.Codec.
+func (c *preparedCodec) Name() string {
+	return codecName
+}
+
+// signingCodec - кодек сообщений потока, дописывающий HMAC сообщения в поле HashField.
+type signingCodec struct {
+	key []byte
+}
+
+// Marshal реализация encoding.Codec.
+// Значение поля HashField сообщения не учитывается и не передается.
+func (c *signingCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return marshal(v)
+	}
+	fd := hashField(msg.ProtoReflect())
+	if fd == nil {
+		return marshal(v)
+	}
+
+	if msg.ProtoReflect().Has(fd) {
+		clone := proto.Clone(msg)
+		clone.ProtoReflect().Clear(fd)
+		msg = clone
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := CalculateHMACSHA256(data, c.key)
+	data = protowire.AppendTag(data, fd.Number(), protowire.BytesType)
+	return protowire.AppendString(data, hex.EncodeToString(hash)), nil
+}
+
+// Unmarshal реализация encoding.Codec.
+func (c *signingCodec) Unmarshal(data []byte, v any) error {
+	return unmarshal(data, v)
+}
+
+// Name реализация encoding.Codec.
+func (c *signingCodec) Name() string {
+	return codecName
+}
+
+// Имя кодека protobuf. Кодеки подписи не меняют формат сообщений.
+const codecName = "proto"
+
+// Хелпер функция сериализации сообщения protobuf.
+func marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+// Хелпер функция десериализации сообщения protobuf.
+func unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// Хелпер функция получения строкового поля HashField сообщения.
+func hashField(m protoreflect.Message) protoreflect.FieldDescriptor {
+	fd := m.Descriptor().Fields().ByName(HashField)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.Cardinality() == protoreflect.Repeated {
+		return nil
+	}
+	return fd
+}
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 )
 
@@ -10,3 +11,16 @@ func CalculateHashSHA256(content, key []byte) []byte {
 	hash := sha256.Sum256(content)
 	return hash[:]
 }
+
+// CalculateHMACSHA256 подсчет HMAC SHA256 с ключом key.
+func CalculateHMACSHA256(content, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// CalculateRequestHMAC подсчет HMAC SHA256 запроса без тела.
+// Подписывается строка "<метод> <путь с query параметрами>", например "DELETE /value/gauge/cpu?host=web1".
+func CalculateRequestHMAC(method, requestURI string, key []byte) []byte {
+	return CalculateHMACSHA256([]byte(method+" "+requestURI), key)
+}
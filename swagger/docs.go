@@ -47,6 +47,247 @@ const docTemplate = `{
                 }
             }
         },
+        "/delete/": {
+            "post": {
+                "description": "Delete metrics and their history. Metric values are ignored",
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Delete metrics",
+                "parameters": [
+                    {
+                        "description": "Metrics",
+                        "name": "metrics",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/view.Metric"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC SHA256 of the request body. Required when the server key is set",
+                        "name": "HashSHA256",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Metric not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/history/{kind}/{name}": {
+            "get": {
+                "description": "Get metric values history for time range with optional downsampling.\nMetric labels are passed as additional query parameters (e.g. ?host=web1)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Get metric history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric kind",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC3339 or unix seconds. Default: to - 1h",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC3339 or unix seconds. Default: now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Downsampling step, e.g. 30s, 5m",
+                        "name": "step",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Downsampling aggregation: avg, min, max, last. Default: avg",
+                        "name": "agg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/view.Sample"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Metric not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/list": {
+            "get": {
+                "description": "List metrics filtered by kind, name and labels with cursor-based pagination.\nMetric labels are passed as additional query parameters (e.g. ?host=web1)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "List metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric kind: gauge, counter or histogram",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name glob: * - any string, ? - any character, [...] - character class",
+                        "name": "glob",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name regular expression",
+                        "name": "regex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: id, type or last_updated. Default: id",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc. Default: asc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, up to 1000. Default: 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Page cursor from next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/view.MetricsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Get all metrics in Prometheus text exposition format.\nOpenMetrics format is used if client accepts application/openmetrics-text",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Get all metrics in Prometheus format",
+                "responses": {
+                    "200": {
+                        "description": "Metrics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/ping": {
             "get": {
                 "description": "Ping DB donnection",
@@ -64,6 +305,65 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "DB temporarily unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reset/{name}": {
+            "post": {
+                "description": "Reset counter value to zero",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Reset counter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Counter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC SHA256 of 'POST \u003crequest URI\u003e'. Required when the server key is set",
+                        "name": "HashSHA256",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/view.Metric"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or invalid hash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Counter not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Error",
                         "schema": {
@@ -118,7 +418,7 @@ const docTemplate = `{
         },
         "/update/{kind}/{name}/{value}": {
             "post": {
-                "description": "Update metric in DB",
+                "description": "Update metric in DB. Metric labels are passed as query parameters (e.g. ?host=web1)",
                 "produces": [
                     "text/plain"
                 ],
@@ -254,7 +554,7 @@ const docTemplate = `{
         },
         "/value/{kind}/{name}": {
             "get": {
-                "description": "Get metric",
+                "description": "Get metric. Metric labels are passed as query parameters (e.g. ?host=web1)",
                 "produces": [
                     "text/plain"
                 ],
@@ -298,10 +598,156 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "delete": {
+                "description": "Delete metric and its history",
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Delete metric",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric kind",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC SHA256 of 'DELETE \u003crequest URI\u003e'. Required when the server key is set",
+                        "name": "HashSHA256",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request or invalid hash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Metric not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/watch": {
+            "get": {
+                "description": "Stream updated metrics as Server-Sent Events. Each \"metrics\" event carries a JSON array of metrics.\nEach \"deleted\" event carries a JSON array of deleted metrics without values.\nAn \"error\" event is sent before the stream is closed by the server.\nMetric labels are passed as additional query parameters (e.g. ?host=web1)",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Watch metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Metric kind: gauge, counter or histogram",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name glob: * - any string, ? - any character, [...] - character class",
+                        "name": "glob",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric name regular expression",
+                        "name": "regex",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/view.Metric"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "501": {
+                        "description": "Watching disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
             }
         }
     },
     "definitions": {
+        "view.Histogram": {
+            "type": "object",
+            "properties": {
+                "bounds": {
+                    "description": "Upper bounds of buckets\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "buckets": {
+                    "description": "Observations count per bucket\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "count": {
+                    "description": "Total observations count\nRequired: true",
+                    "type": "integer"
+                },
+                "sum": {
+                    "description": "Sum of observed values\nRequired: true",
+                    "type": "number"
+                }
+            }
+        },
+        "view.Labels": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "string"
+            }
+        },
         "view.Metric": {
             "type": "object",
             "properties": {
@@ -309,12 +755,32 @@ const docTemplate = `{
                     "description": "Counter value\nRequired: false",
                     "type": "integer"
                 },
+                "histogram": {
+                    "description": "Histogram value\nRequired: false",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/view.Histogram"
+                        }
+                    ]
+                },
                 "id": {
                     "description": "Metric ID\nRequired: true",
                     "type": "string"
                 },
+                "labels": {
+                    "description": "Metric labels\nRequired: false",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/view.Labels"
+                        }
+                    ]
+                },
+                "last_updated": {
+                    "description": "Time of the last metric update. Set by the server\nRequired: false",
+                    "type": "string"
+                },
                 "type": {
-                    "description": "Metric Type\nPossible values: gauge, counter\nRequired: true",
+                    "description": "Metric Type\nPossible values: gauge, counter, histogram\nRequired: true",
                     "type": "string"
                 },
                 "value": {
@@ -322,6 +788,35 @@ const docTemplate = `{
                     "type": "number"
                 }
             }
+        },
+        "view.MetricsPage": {
+            "type": "object",
+            "properties": {
+                "metrics": {
+                    "description": "Metrics of the page\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/view.Metric"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Cursor of the next page. Empty for the last page\nRequired: false",
+                    "type": "string"
+                }
+            }
+        },
+        "view.Sample": {
+            "type": "object",
+            "properties": {
+                "timestamp": {
+                    "description": "Sample time\nRequired: true",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "Sample value\nRequired: true",
+                    "type": "number"
+                }
+            }
         }
     }
 }`